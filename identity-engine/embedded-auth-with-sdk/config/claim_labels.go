@@ -0,0 +1,63 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultClaimLabels are the friendly names the profile page shows for the
+// standard OIDC claims.
+var DefaultClaimLabels = map[string]string{
+	"email":              "Email",
+	"email_verified":     "Email Verified",
+	"family_name":        "Last Name",
+	"given_name":         "First Name",
+	"locale":             "Locale",
+	"name":               "Name",
+	"preferred_username": "Username",
+	"sub":                "Subject",
+	"updated_at":         "Updated At",
+	"zoneinfo":           "Time Zone",
+}
+
+// ParseClaimLabels reads claim labels written as comma separated key=label
+// pairs, e.g. "email=E-mail,given_name=Given Name", on top of
+// DefaultClaimLabels. An empty label shows the raw claim key instead.
+func ParseClaimLabels(s string) (map[string]string, error) {
+	labels := map[string]string{}
+	for k, v := range DefaultClaimLabels {
+		labels[k] = v
+	}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" {
+			return nil, fmt.Errorf("invalid claim label %q, expected key=label", pair)
+		}
+		if label := strings.TrimSpace(parts[1]); label != "" {
+			labels[key] = label
+		} else {
+			delete(labels, key)
+		}
+	}
+	return labels, nil
+}
@@ -0,0 +1,42 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import "testing"
+
+func TestParseClaimLabels(t *testing.T) {
+	labels, err := ParseClaimLabels("email=E-mail, department=Department,sub=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels["email"] != "E-mail" || labels["department"] != "Department" {
+		t.Errorf("expected the configured labels, got %v", labels)
+	}
+	if labels["given_name"] != "First Name" {
+		t.Errorf("expected the default labels to remain, got %v", labels)
+	}
+	if _, ok := labels["sub"]; ok {
+		t.Error("expected an empty label to remove the default")
+	}
+	if DefaultClaimLabels["email"] != "Email" {
+		t.Error("expected the defaults not to be modified")
+	}
+
+	if _, err := ParseClaimLabels("email"); err == nil {
+		t.Error("expected a pair without a label to be invalid")
+	}
+}
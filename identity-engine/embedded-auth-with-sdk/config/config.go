@@ -16,9 +16,43 @@
 
 package config
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type Config struct {
 	Testing    bool
 	HttpClient *http.Client
+	// ClaimLabels maps claim keys to the friendly names shown in the profile
+	// table, e.g. "Email" for email. Nil uses DefaultClaimLabels; claims
+	// without a label show their raw key.
+	ClaimLabels map[string]string
+	// MaxCodeAttempts is how many wrong verification codes a sign in allows
+	// before it has to start over. Zero uses the server's default.
+	MaxCodeAttempts int
+	// AutoSelectAuthenticator skips the list of authenticators when Okta
+	// offers only one and no way to skip it, going straight to its challenge.
+	AutoSelectAuthenticator bool
+	// ShowFullEmail shows the whole address a code is sent to when enrolling
+	// email. Off, it's masked like Okta's own pages do, e.g. m***y@example.com.
+	ShowFullEmail bool
+	// PhoneCountries are the ISO 3166 alpha-2 codes of the countries offered
+	// when enrolling a phone, in the order they're listed. The first one is
+	// selected when the browser's language doesn't name one of them. Empty
+	// offers every country the sample knows.
+	PhoneCountries []string
+	// IDXTimeout bounds each call to Okta, retries included. Zero uses the
+	// server's default.
+	IDXTimeout time.Duration
+	// IDXRetries is how many times a call to Okta is retried when it failed
+	// before Okta could act on it, e.g. with a 503.
+	IDXRetries int
+	// GroupsClaim is the ID token claim the profile page lists the user's
+	// groups from. Empty uses "groups".
+	GroupsClaim string
+	// WelcomeClaim is the userinfo claim the home page greets a signed in
+	// user by, e.g. given_name. Empty uses DefaultWelcomeClaim. The test
+	// harness reads the same WELCOME_CLAIM to know the greeting to expect.
+	WelcomeClaim string
 }
@@ -0,0 +1,57 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParsePhoneCountries reads the countries offered when enrolling a phone,
+// written as comma separated ISO 3166 alpha-2 codes in the order they're
+// listed, e.g. "GB,FR,DE". Codes are upper cased; an empty list is nil.
+func ParsePhoneCountries(s string) ([]string, error) {
+	var codes []string
+	seen := map[string]bool{}
+	for _, code := range strings.Split(s, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" {
+			continue
+		}
+		if !isAlpha2(code) {
+			return nil, fmt.Errorf("invalid country code %q, expected an ISO 3166 alpha-2 code, e.g. US", code)
+		}
+		if seen[code] {
+			return nil, fmt.Errorf("country code %s is listed twice", code)
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func isAlpha2(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePhoneCountries(t *testing.T) {
+	codes, err := ParsePhoneCountries(" gb,IE , fr,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"GB", "IE", "FR"}; !reflect.DeepEqual(codes, expected) {
+		t.Errorf("expected %v, got %v", expected, codes)
+	}
+
+	if codes, err := ParsePhoneCountries(""); err != nil || codes != nil {
+		t.Errorf("expected no countries, got %v, %v", codes, err)
+	}
+	for _, invalid := range []string{"USA", "U1", "GB,gb", "É"} {
+		if _, err := ParsePhoneCountries(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
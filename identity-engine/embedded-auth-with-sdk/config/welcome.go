@@ -0,0 +1,39 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import "fmt"
+
+// DefaultWelcomeClaim is the userinfo claim the signed in greeting names the
+// user by.
+const DefaultWelcomeClaim = "name"
+
+// WelcomeClaimKey is the claim the signed in greeting names the user by,
+// WelcomeClaim or DefaultWelcomeClaim.
+func (c *Config) WelcomeClaimKey() string {
+	if c != nil && c.WelcomeClaim != "" {
+		return c.WelcomeClaim
+	}
+	return DefaultWelcomeClaim
+}
+
+// WelcomeText is the signed in greeting for the value of the welcome claim.
+// The home page renders it and the test harness expects it, so both must go
+// through here.
+func WelcomeText(value string) string {
+	return fmt.Sprintf("Welcome, %s.", value)
+}
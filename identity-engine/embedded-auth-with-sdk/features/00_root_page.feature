@@ -9,11 +9,19 @@ Feature: 0.1 Root page for Direct Auth Demo Application
     Given Mary navigates to the Root View
     Then the Root Page shows links to the Entry Points as defined in https://oktawiki.atlassian.net/l/c/Pw7DVm1t
 
+  @0.1.4
+  Scenario: 0.1.4 Mary follows each Entry Point from the Root View to its form
+    Given Mary navigates to the Root View
+    Then the "Sign In" link leads to the Login form
+    And the "Sign Up" link leads to the Registration form
+    And the "Password Recovery" link leads to the Password Recovery form
+
   @0.1.2
   Scenario: 0.1.2 Mary visits the Root View and WITH an authentcation session
     Given Mary navigates to the Root View
     Then Mary logs in to the Application
     And Mary sees a table with the claims from the /userinfo response
+    And Mary sees the claim "email" labeled "Email"
     And Mary sees a logout button
 
   @0.1.3
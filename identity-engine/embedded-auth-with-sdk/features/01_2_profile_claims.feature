@@ -0,0 +1,33 @@
+@1.2
+Feature: 1.2 Profile claims reflect changes to the user
+
+  Background:
+    Given there is a new sign up user named Mary Acme
+    And user is added to the org without phone number
+
+  @1.2.1
+  Scenario: 1.2.1 Mary's claims table shows her updated name after she signs in again
+    Given Mary navigates to the Basic Login View
+    When she fills in her correct username
+    And she fills in her password
+    And she submits the Login form
+    And she verifies her email if asked
+    Then she is redirected back to the Root View
+    And the cell for the value of "name" is shown and contains her first name and last name
+    When the user's last name is changed to Smith in the org
+    And she signs out and back in
+    Then she is redirected back to the Root View
+    And the cell for the value of "name" is shown and contains her first name and last name
+
+  # needs the groups scope in OKTA_IDX_SCOPES or a groups claim on the
+  # authorization server, and HARNESS_GROUPS_CLAIM=true
+  @1.2.2
+  Scenario: 1.2.2 Mary sees the groups she belongs to on her profile
+    Given the app's ID tokens carry a groups claim
+    And Mary navigates to the Basic Login View
+    When she fills in her correct username
+    And she fills in her password
+    And she submits the Login form
+    And she verifies her email if asked
+    Then she is redirected back to the Root View
+    And she sees the group "Everyone" on her profile
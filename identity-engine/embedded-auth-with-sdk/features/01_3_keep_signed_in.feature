@@ -0,0 +1,27 @@
+@1.3
+Feature: 1.3 Keep me signed in
+
+  Background:
+    Given there is a new sign up user named Mary Acme
+    And user is added to the org without phone number
+
+  @1.3.1
+  Scenario: 1.3.1 Mary asks to be kept signed in
+    Given Mary navigates to the Basic Login View
+    When she fills in her correct username
+    And she fills in her password
+    And she checks "Keep me signed in"
+    And she submits the Login form
+    And she verifies her email if asked
+    Then she is redirected back to the Root View
+    And her session cookie lasts 30 days
+
+  @1.3.2
+  Scenario: 1.3.2 Mary signs in without asking to be kept signed in
+    Given Mary navigates to the Basic Login View
+    When she fills in her correct username
+    And she fills in her password
+    And she submits the Login form
+    And she verifies her email if asked
+    Then she is redirected back to the Root View
+    And her session cookie ends with the browser session
@@ -7,6 +7,8 @@ Feature: 1.1 Basic Login with Password Factor
   @1.1.2
   Scenario: 1.1.2 Mary doesn't know her username
     Given Mary navigates to the Basic Login View
+    Then she sees the page title "Login"
+    And she sees the username field has focus
     When she fills in her incorrect username
     And she fills in her password
     And she submits the Login form
@@ -19,9 +21,28 @@ Feature: 1.1 Basic Login with Password Factor
     And she fills in her incorrect password
     And she submits the Login form
     Then she should see an error message "Authentication failed"
+    And she sees her username kept on the Login form
 
   @1.1.8
   Scenario: 1.1.8 Mary clicks on the "Forgot Password Link"
     Given Mary navigates to the Basic Login View
     When she clicks on the Forgot Password button
     Then she is redirected to the Self Service Password Reset View
+
+  @1.1.9
+  Scenario: 1.1.9 Mary signs in with an unknown username on an org that reveals accounts
+    Given the org doesn't protect against account enumeration
+    And Mary navigates to the Basic Login View
+    When she fills in her incorrect username
+    And she fills in her password
+    And she submits the Login form
+    Then she sees the error for an unknown username
+
+  @1.1.10
+  Scenario: 1.1.10 Mary signs in with an unknown username on an org that protects against account enumeration
+    Given the org does protect against account enumeration
+    And Mary navigates to the Basic Login View
+    When she fills in her incorrect username
+    And she fills in her password
+    And she submits the Login form
+    Then she sees the error for an unknown username
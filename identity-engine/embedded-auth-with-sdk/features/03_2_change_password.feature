@@ -0,0 +1,24 @@
+@3.2
+Feature: 3.2 Direct Auth Change Password
+
+  Background:
+    Given there is a new sign up user named Mary Acme
+    And user is added to the org without phone number
+    And Mary navigates to the Basic Login View
+    And she fills in her correct username
+    And she fills in her password
+    And she submits the Login form
+    And she verifies her email if asked
+
+  @3.2.1
+  Scenario: 3.2.1 Mary changes her password while signed in
+    When she changes her password
+    Then she is redirected back to the Root View
+    And she sees a message that her password was changed
+    When she signs out and back in
+    Then she is redirected back to the Root View
+
+  @3.2.2
+  Scenario: 3.2.2 Mary enters the wrong current password
+    When she changes her password with a wrong current password
+    Then she sees an error that her current password is incorrect
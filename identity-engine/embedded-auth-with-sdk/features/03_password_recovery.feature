@@ -11,16 +11,39 @@ Feature: 3.1 Direct Auth Password Recovery
     When she inputs correct Email
     And she submits the recovery form
     Then she sees a page to input the code
+    And she isn't told the code was sent only if her account exists
     When she fills in the correct code
     And she submits the code form
     Then she sees a page to set new password
     When she fills a password that fits within the password policy
     And she submits new password form
     Then she is redirected back to the Root View
+    And she sees a message that her password was reset
 
   @3.1.2
   Scenario: 3.1.2 Mary tries to reset a password with the wrong email
-    Given Mary navigates to the Password Recovery View
+    Given the org doesn't protect against account enumeration
+    And Mary navigates to the Password Recovery View
+    When she inputs incorrect Email
+    And she submits the recovery form
+    Then she sees the error for an email without an account
+
+  @3.1.4
+  Scenario: 3.1.4 Mary recovers an email without an account on an org that hides which accounts exist
+    Given the org does protect against account enumeration
+    And Mary navigates to the Password Recovery View
     When she inputs incorrect Email
     And she submits the recovery form
-    Then she sees a message "Reset password is not allowed at this time. Please contact support for assistance."
+    Then she sees a page to input the code
+    And she is told the code was sent only if her account exists
+
+  @3.1.3 @no-ci
+  Scenario: 3.1.3 Mary's recovery code expires before she uses it
+    Given Mary navigates to the Password Recovery View
+    When she inputs correct Email
+    And she submits the recovery form
+    Then she sees a page to input the code
+    When she waits for her recovery code to expire
+    And she fills in the correct code
+    And she submits the code form
+    Then she sees an error that her code has expired
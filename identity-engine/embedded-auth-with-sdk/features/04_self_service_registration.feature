@@ -7,6 +7,8 @@ Feature: 4.1 Self Service Registration with Email Activation and optional SMS
   @4.1.1
   Scenario: 4.1.1 Mary signs up for an account with Password, setups up required Email factor, then skips optional SMS
     Given Mary navigates to the Self Service Registration View
+    Then she sees the page title "Register"
+    And she sees the First Name field has focus
     When she fills out her First Name
     And she fills out her Last Name
     And she fills out her Email
@@ -20,12 +22,18 @@ Feature: 4.1 Self Service Registration with Email Activation and optional SMS
     And she confirms her Password
     And she submits the set new password form
     Then she sees a list of required factors to setup
+    And she doesn't see the option to skip
     When she selects Email
     Then she sees a page to input a code
+    And she sees the code is sent to her email
     When she inputs the correct code from her email
     Then she sees the list of optional factors (SMS)
+    And she sees the option to skip
+    And she sees 1 authenticator option
     When she selects "Skip" on SMS
     Then she is redirected to the Root View
+    And she sees the page title "Profile"
+    And her Email factor is enrolled in Okta
     And she sees a table with her profile info
     And the cell for the value of "email" is shown and contains her email
     And the cell for the value of "name" is shown and contains her first name and last name
@@ -49,8 +57,10 @@ Feature: 4.1 Self Service Registration with Email Activation and optional SMS
     Then she sees a page to input a code
     When she inputs the correct code from her email
     Then she sees the list of optional factors (SMS)
+    And she sees the option to skip
     When she selects Phone from the list
-    And she inputs a valid phone number
+    Then she sees her phone number's country selected
+    When she inputs a valid phone number
     And she selects "Receive a Code"
     Then the screen changes to receive an input for a code
     When she inputs the correct code from her SMS
@@ -83,7 +93,109 @@ Feature: 4.1 Self Service Registration with Email Activation and optional SMS
     Then she sees a page to input a code
     When she inputs the correct code from her email
     Then she sees the list of optional factors (SMS)
+    And she sees the option to skip
     When she selects Phone from the list
     And she inputs an invalid phone number
     And she selects "Receive a Code"
     Then she sees an error message "Unable to initiate factor enrollment: Invalid Phone Number."
+
+  @4.1.5
+  Scenario: 4.1.5 Mary signs up a second time with the same Email
+    Given Mary navigates to the Self Service Registration View
+    When she fills out her First Name
+    And she fills out her Last Name
+    And she fills out her Email
+    And she submits the registration form
+    Then she fills out her Password
+    Given Mary navigates to the Self Service Registration View
+    When she fills out her First Name
+    And she fills out her Last Name
+    And she fills out her Email
+    And she submits the registration form
+    Then she sees an error message that an account already exists
+    And she sees a link to sign in instead
+
+  @4.1.6
+  Scenario: 4.1.6 Mary submits the registration form without filling it out
+    Given Mary navigates to the Self Service Registration View
+    When she submits the registration form
+    Then she sees an error that her First Name is required
+    And she sees an error that her Last Name is required
+    And she sees an error that her Email is required
+
+  @4.1.7
+  Scenario: 4.1.7 Mary signs up and verifies her email only if the org's policy requires it
+    Given Mary navigates to the Self Service Registration View
+    When she fills out her First Name
+    And she fills out her Last Name
+    And she fills out her Email
+    And she submits the registration form
+    And she fills out her Password
+    And she confirms her Password
+    And she submits the set new password form
+    And she completes the email verification her org's policy requires
+    Then she is redirected to the Root View
+    And she sees a table with her profile info
+    And the cell for the value of "email" is shown and contains her email
+
+  @4.1.8
+  Scenario: 4.1.8 Mary signs up and sets up optional Voice with a phone extension
+    Given Mary navigates to the Self Service Registration View
+    When she fills out her First Name
+    And she fills out her Last Name
+    And she fills out her Email
+    And she submits the registration form
+    When she fills out her Password
+    And she confirms her Password
+    And she submits the set new password form
+    When she selects Email
+    Then she sees a page to input a code
+    When she inputs the correct code from her email
+    Then she sees the list of optional factors (SMS)
+    When she selects Phone from the list
+    And she inputs a valid phone number
+    And she selects the Voice method
+    And she fills in the phone extension "123"
+    And she selects "Receive a Call"
+    Then the screen changes to receive an input for a code
+    When she inputs the correct code from her voice call
+    And she selects "Verify"
+    Then she is redirected to the Root View
+    And she sees a table with her profile info
+    And her Voice factor is enrolled in Okta
+
+  @4.1.9
+  Scenario: 4.1.9 Mary's sign up session expires before she finishes
+    Given Mary navigates to the Self Service Registration View
+    When she fills out her First Name
+    And she fills out her Last Name
+    And she fills out her Email
+    And she submits the registration form
+    And her sign up session expires
+    And she fills out her Password
+    And she confirms her Password
+    And she submits the set new password form
+    Then she sees her session has expired
+    And she sees the page title "Session Expired"
+    When she clicks "Start over"
+    Then she is redirected to the Root View
+
+  @4.1.10
+  Scenario: 4.1.10 Mary can only pick from the countries the org allows for her phone
+    Given the app offers the phone countries "GB,FR,DE"
+    And Mary navigates to the Self Service Registration View
+    When she fills out her First Name
+    And she fills out her Last Name
+    And she fills out her Email
+    And she submits the registration form
+    When she fills out her Password
+    And she confirms her Password
+    And she submits the set new password form
+    When she selects Email
+    Then she sees a page to input a code
+    When she inputs the correct code from her email
+    Then she sees the list of optional factors (SMS)
+    When she selects Phone from the list
+    Then she sees the country options "GB,FR,DE"
+    When she selects "France (+33)" from the phoneCountry dropdown
+    Then she sees the phone country "+33" selected
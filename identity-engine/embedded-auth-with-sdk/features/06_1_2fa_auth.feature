@@ -13,6 +13,7 @@ Feature: 6.1 Multi-Factor Authentication with Password and Email
     And she fills in her password
     And she submits the Login form
     Then she sees a list of factors
+    And she sees "Email" preselected
     When she selects Email
     Then she sees a page to input the code
     When she fills in the correct code
@@ -34,3 +35,27 @@ Feature: 6.1 Multi-Factor Authentication with Password and Email
     When she fills in the incorrect code
     And she submits the code form
     Then she sees a message "Invalid code. Try again."
+
+  @6.1.4
+  Scenario: 6.1.4 Mary enters a wrong verification code too many times
+    Given Mary navigates to the Basic Login View
+    When she fills in her correct username
+    And she fills in her password
+    And she submits the Login form
+    Then she sees a list of factors
+    When she selects Email
+    Then she sees a page to input the code
+    When she enters a wrong code 5 times
+    Then she sees an error that there were too many attempts
+
+  @6.1.5
+  Scenario: 6.1.5 Mary goes straight to the Email challenge when it is her only factor
+    Given the app auto-selects the only authenticator offered
+    And Mary navigates to the Basic Login View
+    When she fills in her correct username
+    And she fills in her password
+    And she submits the Login form
+    Then she is taken straight to the Email challenge
+    When she fills in the correct code
+    And she submits the code form
+    Then she is redirected back to the Root View
@@ -37,6 +37,7 @@ Feature: 6.2 Multi-Factor Authentication with Password and SMS
     And she fills in her password
     And she submits the Login form
     Then she sees a list of factors
+    And she sees the authenticators in order "Email, Phone"
     When she selects Phone
     Then she sees form with method
     When she inputs a method
@@ -85,3 +86,25 @@ Feature: 6.2 Multi-Factor Authentication with Password and SMS
     When fills in the incorrect code
     And she submits the code form
     Then she sees a message "Invalid code. Try again."
+
+  @6.2.5
+  Scenario: 6.2.5 2FA Mary switches from Email to SMS during the challenge
+    Given there is a new sign up user named Mary Acme
+    And user is added to the org with phone number
+    And user is assigned to the group MFA Required
+    Given Mary navigates to the Basic Login View
+    When she fills in her correct username
+    And she fills in her password
+    And she submits the Login form
+    Then she sees a list of factors
+    When she selects Email
+    Then she sees a page to input the code
+    When she switches to the "SMS" authenticator
+    Then she sees form with method
+    When she inputs a method
+    Then she sees a page to input the code
+    When she inputs the correct code from her SMS
+    And she submits the code form
+    Then she is redirected back to the Root View
+    And she sees a table with her profile info
+    And the cell for the value of "email" is shown and contains her email
@@ -10,9 +10,13 @@ require (
 	github.com/gorilla/sessions v1.2.1
 	github.com/howeyc/fsnotify v0.9.0
 	github.com/okta/okta-idx-golang v0.2.1
+	github.com/okta/okta-jwt-verifier-golang v1.1.1
 	github.com/okta/okta-sdk-golang/v2 v2.3.1-0.20210519105407-20ace51aad26
+	github.com/okta/samples-golang/identity-engine/internal v0.0.0-00010101000000-000000000000
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.1
 	github.com/tebeka/selenium v0.9.9
 )
+
+replace github.com/okta/samples-golang/identity-engine/internal => ../internal
@@ -0,0 +1,132 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// circuitBreaker fails A18N calls fast once A18N looks down, instead of every
+// scenario waiting out its own retries. It opens after threshold consecutive
+// failures and, once cooldown has passed, lets a single call through to see
+// whether A18N is back: the breaker closes if it succeeds and opens again if
+// it fails.
+type circuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{name: name, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns an error wrapping errCircuitOpen while the breaker is open.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.trial || b.now().Sub(b.openedAt) < b.cooldown {
+		return fmt.Errorf("%s failed %d times in a row, not calling it until %s: %w",
+			b.name, b.failures, b.openedAt.Add(b.cooldown).Format(time.RFC3339), errCircuitOpen)
+	}
+	b.trial = true
+	log.Printf("%s circuit breaker is half-open, trying one call", b.name)
+	return nil
+}
+
+// record counts the outcome of a call allow let through.
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	open := b.failures >= b.threshold
+	b.trial = false
+	if !failed {
+		if open {
+			log.Printf("%s circuit breaker is closed", b.name)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+		log.Printf("%s circuit breaker is open after %d consecutive failures, failing fast for %s", b.name, b.failures, b.cooldown)
+	}
+}
+
+// abandon forgets a call allow let through that ended without telling
+// whether A18N works, so a half-open breaker tries again.
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// a18nBreaker guards every call to A18N. HARNESS_A18N_BREAKER_THRESHOLD
+// defaults to 5 consecutive failures and HARNESS_A18N_BREAKER_COOLDOWN to 1
+// minute.
+var a18nBreaker = newCircuitBreaker("A18N", a18nBreakerThreshold(), a18nBreakerCooldown())
+
+func a18nBreakerThreshold() int {
+	threshold, err := strconv.Atoi(os.Getenv("HARNESS_A18N_BREAKER_THRESHOLD"))
+	if err != nil || threshold <= 0 {
+		return 5
+	}
+	return threshold
+}
+
+func a18nBreakerCooldown() time.Duration {
+	cooldown, err := time.ParseDuration(os.Getenv("HARNESS_A18N_BREAKER_COOLDOWN"))
+	if err != nil || cooldown <= 0 {
+		return time.Minute
+	}
+	return cooldown
+}
+
+// doA18N sends an A18N request through a18nBreaker. Network errors and 5xx
+// answers count as failures; a scenario giving up on its own, e.g. on its
+// timeout, doesn't.
+func (th *TestHarness) doA18N(req *http.Request) (*http.Response, error) {
+	if err := a18nBreaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := th.httpClient.Do(req)
+	if err != nil && req.Context().Err() != nil {
+		a18nBreaker.abandon()
+		return nil, err
+	}
+	a18nBreaker.record(err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
@@ -0,0 +1,102 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndCloses(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker("A18N", 3, time.Minute)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("expected call %d to be allowed, got %v", i+1, err)
+		}
+		b.record(true)
+	}
+	if err := b.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("expected the breaker to open after 3 failures, got %v", err)
+	}
+
+	// half-open after the cooldown: one trial call, which fails
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected a trial call after the cooldown, got %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("expected only one trial call at a time, got %v", err)
+	}
+	b.record(true)
+	if err := b.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("expected a failed trial to open the breaker again, got %v", err)
+	}
+
+	// a successful trial closes it
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected a trial call after the cooldown, got %v", err)
+	}
+	b.record(false)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected the breaker to close after a successful trial, got %v", err)
+	}
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	b := newCircuitBreaker("A18N", 2, time.Minute)
+	b.record(true)
+	b.record(false)
+	b.record(true)
+	if err := b.allow(); err != nil {
+		t.Errorf("expected failures that aren't consecutive to keep the breaker closed, got %v", err)
+	}
+}
+
+func TestDoA18NFailsFastWhenOpen(t *testing.T) {
+	calls := 0
+	a18n := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer a18n.Close()
+
+	saved := a18nBreaker
+	defer func() { a18nBreaker = saved }()
+	a18nBreaker = newCircuitBreaker("A18N", 2, time.Hour)
+
+	th := &TestHarness{httpClient: &http.Client{}}
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodGet, a18n.URL+"/v1/profile", nil)
+		resp, err := th.doA18N(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if i == 2 && !errors.Is(err, errCircuitOpen) {
+			t.Errorf("expected the third call to fail fast, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected A18N to be called twice, got %d", calls)
+	}
+}
@@ -0,0 +1,120 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a18nStub stands in for the A18N API when HARNESS_A18N_STUB_CODE is set, so
+// scenarios that read verification codes run without an A18N account. Every
+// profile's latest email or SMS carries the stub's code, sent just now.
+type a18nStub struct {
+	*httptest.Server
+	code string
+
+	mu       sync.Mutex
+	next     int
+	profiles map[string]A18NProfile
+}
+
+// a18nStubServer is the running stub, if any; a18nApiURL points at it.
+var a18nStubServer *a18nStub
+
+func newA18NStub(code string) *a18nStub {
+	stub := &a18nStub{code: code, profiles: map[string]A18NProfile{}}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.serveHTTP))
+	return stub
+}
+
+func (s *a18nStub) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// /v1/profile, /v1/profile/{id} or /v1/profile/{id}/{type}/latest
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/profile"), "/")
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		s.createProfile(w, r)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.listProfiles(w)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		s.mu.Lock()
+		delete(s.profiles, parts[1])
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 4 && parts[3] == "latest" && r.Method == http.MethodGet:
+		s.latestMessage(w, parts[1], parts[2])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *a18nStub) createProfile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DisplayName string `json:"displayName"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	s.mu.Lock()
+	s.next++
+	id := fmt.Sprintf("stub%d", s.next)
+	profile := A18NProfile{
+		ProfileID:    id,
+		PhoneNumber:  fmt.Sprintf("+1555555%04d", s.next%10000),
+		EmailAddress: fmt.Sprintf("%s-%d@a18n.help", id, time.Now().UnixNano()),
+		DisplayName:  req.DisplayName,
+		URL:          fmt.Sprintf("%s/v1/profile/%s", s.URL, id),
+		CreatedAt:    time.Now().UTC(),
+	}
+	s.profiles[id] = profile
+	s.mu.Unlock()
+
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (s *a18nStub) listProfiles(w http.ResponseWriter) {
+	s.mu.Lock()
+	profiles := A18NProfiles{}
+	for _, profile := range s.profiles {
+		profiles.Profiles = append(profiles.Profiles, profile)
+	}
+	profiles.Count = len(profiles.Profiles)
+	s.mu.Unlock()
+
+	json.NewEncoder(w).Encode(profiles)
+}
+
+func (s *a18nStub) latestMessage(w http.ResponseWriter, id, codeType string) {
+	s.mu.Lock()
+	_, found := s.profiles[id]
+	s.mu.Unlock()
+	if !found {
+		http.NotFound(w, nil)
+		return
+	}
+	json.NewEncoder(w).Encode(A18NContent{
+		MessageID: fmt.Sprintf("%s-%s", id, codeType),
+		ProfileID: id,
+		// sent just now, so it's within latestVerificationCode's window
+		CreatedAt: time.Now().UTC(),
+		Content:   fmt.Sprintf("Your verification code is %s", s.code),
+	})
+}
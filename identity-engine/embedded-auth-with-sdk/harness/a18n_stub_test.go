@@ -0,0 +1,57 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import "testing"
+
+func TestA18NStubServesCode(t *testing.T) {
+	a18nStubServer = newA18NStub("123456")
+	defer func() {
+		a18nStubServer.Close()
+		a18nStubServer = nil
+	}()
+	th := NewTestHarness()
+
+	profile, err := th.createProfile("Mary Acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.URL == "" || profile.EmailAddress == "" || profile.PhoneNumber == "" {
+		t.Fatalf("expected a complete stub profile, got %+v", profile)
+	}
+
+	for _, codeType := range []string{EMAIL_CODE_TYPE, SMS_CODE_TYPE} {
+		code, err := th.latestVerificationCode(profile.URL, codeType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != "123456" {
+			t.Errorf("expected the stub's %s code, got %q", codeType, code)
+		}
+	}
+
+	if err = th.deleteProfile(profile); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := th.profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profiles.Count != 0 {
+		t.Errorf("expected the profile to be deleted, got %d profiles", profiles.Count)
+	}
+}
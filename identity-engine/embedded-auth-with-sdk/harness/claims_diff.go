@@ -0,0 +1,100 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tebeka/selenium"
+)
+
+// diffClaims reports every claim that is missing from actual, extra in it, or
+// has a different value, rather than stopping at the first one.
+func diffClaims(expected, actual map[string]string) error {
+	var missing, extra, mismatched []string
+	for key, want := range expected {
+		got, ok := actual[key]
+		switch {
+		case !ok:
+			missing = append(missing, key)
+		case got != want:
+			mismatched = append(mismatched, fmt.Sprintf("%s: expected %q, got %q", key, want, got))
+		}
+	}
+	for key := range actual {
+		if _, ok := expected[key]; !ok {
+			extra = append(extra, key)
+		}
+	}
+	if len(missing)+len(extra)+len(mismatched) == 0 {
+		return nil
+	}
+
+	var report []string
+	for _, part := range []struct {
+		name  string
+		items []string
+	}{
+		{"missing", missing},
+		{"extra", extra},
+		{"mismatched", mismatched},
+	} {
+		if len(part.items) == 0 {
+			continue
+		}
+		sort.Strings(part.items)
+		report = append(report, fmt.Sprintf("%s claims [%s]", part.name, strings.Join(part.items, "; ")))
+	}
+	return fmt.Errorf("claims table differs from OKTA_IDX_CLAIMS: %s", strings.Join(report, ", "))
+}
+
+// renderedClaims collects every claim in the claims table by its value cell.
+func (th *TestHarness) renderedClaims() (map[string]string, error) {
+	if err := th.seesElement(`td[id$="-value"]`); err != nil {
+		return nil, err
+	}
+	cells, err := th.wd.FindElements(selenium.ByCSSSelector, `td[id$="-value"]`)
+	if err != nil {
+		return nil, err
+	}
+	rendered := map[string]string{}
+	for _, cell := range cells {
+		id, err := cell.GetAttribute("id")
+		if err != nil {
+			return nil, err
+		}
+		text, err := cell.Text()
+		if err != nil {
+			return nil, err
+		}
+		rendered[strings.TrimSuffix(id, "-value")] = strings.TrimSpace(text)
+	}
+	return rendered, nil
+}
+
+// seesExactClaims checks the claims table holds exactly the claims in
+// OKTA_IDX_CLAIMS, reporting all the differences at once. seesClaimsTable
+// stays the quicker check that the expected claims are there.
+func (th *TestHarness) seesExactClaims() error {
+	rendered, err := th.renderedClaims()
+	if err != nil {
+		return err
+	}
+	return diffClaims(claims(), rendered)
+}
@@ -0,0 +1,44 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffClaims(t *testing.T) {
+	expected := map[string]string{"email": "mary@acme.com", "name": "Mary Acme", "locale": "en_US"}
+
+	if err := diffClaims(expected, map[string]string{"email": "mary@acme.com", "name": "Mary Acme", "locale": "en_US"}); err != nil {
+		t.Errorf("expected no difference, got %v", err)
+	}
+
+	err := diffClaims(expected, map[string]string{"email": "mary@acme.com", "name": "Mary Smith", "zoneinfo": "UTC"})
+	if err == nil {
+		t.Fatal("expected the claims to differ")
+	}
+	for _, part := range []string{
+		"missing claims [locale]",
+		"extra claims [zoneinfo]",
+		`mismatched claims [name: expected "Mary Acme", got "Mary Smith"]`,
+	} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("expected %q in %q", part, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/cucumber/godog"
+)
+
+const (
+	// noAccountMessage starts Okta's error for an unknown username when the
+	// org reveals whether accounts exist
+	noAccountMessage = "There is no account with the Username"
+	// genericAuthFailedMessage is all an org protecting against account
+	// enumeration says about an unknown username, the same as about a wrong
+	// password
+	genericAuthFailedMessage = "Authentication failed"
+)
+
+// enumerationProtected reports whether HARNESS_ENUMERATION_PROTECTED says
+// the org hides whether an account exists, answering for unknown accounts
+// exactly as for known ones.
+func enumerationProtected() bool {
+	protected, _ := strconv.ParseBool(os.Getenv("HARNESS_ENUMERATION_PROTECTED"))
+	return protected
+}
+
+// noAccountError is the error expected when signing in with an unknown
+// username.
+func noAccountError() string {
+	if enumerationProtected() {
+		return genericAuthFailedMessage
+	}
+	return noAccountMessage
+}
+
+// orgEnumerationProtection leaves a scenario written for the other setting
+// pending rather than failing it against this org.
+func (th *TestHarness) orgEnumerationProtection(does string) error {
+	want := does == "does"
+	if enumerationProtected() != want {
+		return fmt.Errorf("%w: the scenario needs HARNESS_ENUMERATION_PROTECTED=%t", godog.ErrPending, want)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/cucumber/godog"
+)
+
+func TestNoAccountError(t *testing.T) {
+	defer os.Setenv("HARNESS_ENUMERATION_PROTECTED", os.Getenv("HARNESS_ENUMERATION_PROTECTED"))
+	os.Setenv("HARNESS_ENUMERATION_PROTECTED", "")
+	if got := noAccountError(); got != noAccountMessage {
+		t.Errorf("expected %q by default, got %q", noAccountMessage, got)
+	}
+
+	os.Setenv("HARNESS_ENUMERATION_PROTECTED", "true")
+	if got := noAccountError(); got != genericAuthFailedMessage {
+		t.Errorf("expected %q when protected, got %q", genericAuthFailedMessage, got)
+	}
+}
+
+func TestOrgEnumerationProtection(t *testing.T) {
+	th := &TestHarness{}
+	defer os.Setenv("HARNESS_ENUMERATION_PROTECTED", os.Getenv("HARNESS_ENUMERATION_PROTECTED"))
+	os.Setenv("HARNESS_ENUMERATION_PROTECTED", "true")
+	if err := th.orgEnumerationProtection("does"); err != nil {
+		t.Errorf("expected a protected org to match, got %v", err)
+	}
+	if err := th.orgEnumerationProtection("doesn't"); !errors.Is(err, godog.ErrPending) {
+		t.Errorf("expected the other mode to be pending, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// sessionExpiredBody is what IDX answers a remediation with once the
+// interaction has outlived its session.
+const sessionExpiredBody = `{"version":"1.0.0","messages":{"type":"array","value":[{"message":"The session has expired.","i18n":{"key":"idx.session.expired"},"class":"ERROR"}]}}`
+
+// sessionExpirer stands in for IDX once a scenario expires the flow in
+// progress: the next remediation gets the session expired error, as if the
+// user had waited out the interaction's lifetime. Every other call, and
+// every call after that one, goes to Okta.
+type sessionExpirer struct {
+	armed int32
+}
+
+// expireNext arms the expirer for the next remediation.
+func (e *sessionExpirer) expireNext() {
+	atomic.StoreInt32(&e.armed, 1)
+}
+
+func (e *sessionExpirer) reset() {
+	atomic.StoreInt32(&e.armed, 0)
+}
+
+// intercept answers req with the session expired error if the expirer is
+// armed and req is a remediation.
+func (e *sessionExpirer) intercept(req *http.Request) (*http.Response, bool) {
+	if req.Method != http.MethodPost || !strings.Contains(req.URL.Path, "/idp/idx/") {
+		return nil, false
+	}
+	if !atomic.CompareAndSwapInt32(&e.armed, 1, 0) {
+		return nil, false
+	}
+	return &http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Status:     "401 Unauthorized",
+		Header:     http.Header{"Content-Type": []string{"application/ion+json; okta-version=1.0.0"}},
+		Body:       ioutil.NopCloser(bytes.NewBufferString(sessionExpiredBody)),
+		Request:    req,
+	}, true
+}
+
+// expiresFlowSession makes the flow in progress expire at its next step.
+func (th *TestHarness) expiresFlowSession() error {
+	th.expirer.expireNext()
+	return nil
+}
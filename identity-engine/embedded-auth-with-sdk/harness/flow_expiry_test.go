@@ -0,0 +1,56 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+func TestSessionExpirerAnswersNextRemediationOnly(t *testing.T) {
+	var e sessionExpirer
+	remediation := httptest.NewRequest(http.MethodPost, "https://example.okta.com/idp/idx/challenge/answer", nil)
+	if _, ok := e.intercept(remediation); ok {
+		t.Fatal("expected no answer before the expirer is armed")
+	}
+
+	e.expireNext()
+	if _, ok := e.intercept(httptest.NewRequest(http.MethodPost, "https://example.okta.com/oauth2/default/v1/interact", nil)); ok {
+		t.Fatal("expected interact to go to Okta")
+	}
+	resp, ok := e.intercept(remediation)
+	if !ok {
+		t.Fatal("expected the remediation to be answered")
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	var idxErr idx.ErrorResponse
+	if err := json.Unmarshal(body, &idxErr); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || len(idxErr.Message.Values) != 1 || idxErr.Message.Values[0].I18N.Key != "idx.session.expired" {
+		t.Errorf("expected the session expired error, got %d %s", resp.StatusCode, body)
+	}
+
+	if _, ok := e.intercept(remediation); ok {
+		t.Error("expected only one remediation to be answered")
+	}
+}
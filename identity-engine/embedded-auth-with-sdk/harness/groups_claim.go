@@ -0,0 +1,42 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/cucumber/godog"
+)
+
+// groupsClaimIssued reports whether HARNESS_GROUPS_CLAIM says the org puts a
+// groups claim in the app's ID tokens, which needs the groups scope or a
+// claim set up on the authorization server.
+func groupsClaimIssued() bool {
+	issued, _ := strconv.ParseBool(os.Getenv("HARNESS_GROUPS_CLAIM"))
+	return issued
+}
+
+// appIssuesGroupsClaim leaves a scenario that lists groups pending rather
+// than failing it against an org that doesn't issue the claim.
+func (th *TestHarness) appIssuesGroupsClaim() error {
+	if !groupsClaimIssued() {
+		return fmt.Errorf("%w: the scenario needs HARNESS_GROUPS_CLAIM=true", godog.ErrPending)
+	}
+	return nil
+}
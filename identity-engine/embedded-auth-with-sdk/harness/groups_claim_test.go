@@ -0,0 +1,39 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/cucumber/godog"
+)
+
+func TestAppIssuesGroupsClaim(t *testing.T) {
+	th := &TestHarness{}
+	defer os.Setenv("HARNESS_GROUPS_CLAIM", os.Getenv("HARNESS_GROUPS_CLAIM"))
+	os.Setenv("HARNESS_GROUPS_CLAIM", "")
+	if err := th.appIssuesGroupsClaim(); !errors.Is(err, godog.ErrPending) {
+		t.Errorf("expected the scenario to be pending by default, got %v", err)
+	}
+
+	os.Setenv("HARNESS_GROUPS_CLAIM", "true")
+	if err := th.appIssuesGroupsClaim(); err != nil {
+		t.Errorf("expected an org issuing the claim to match, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+const (
+	passwordDigits  = "0123456789"
+	passwordLowers  = "abcdefghijklmnopqrstuvwxyz"
+	passwordUppers  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordSymbols = "!#$%&()*+,-.:;<=>?@^_~"
+	// minPasswordLength is the shortest password generated, whatever the
+	// policy allows
+	minPasswordLength = 12
+)
+
+// defaultPasswordComplexity is assumed when the org's password policies
+// can't be read: at least a lowercase letter, an uppercase letter and a
+// number.
+var defaultPasswordComplexity = &okta.PasswordPolicyPasswordSettingsComplexity{
+	MinLength:    8,
+	MinLowerCase: 1,
+	MinUpperCase: 1,
+	MinNumber:    1,
+}
+
+// generatePasswordForPolicy generates a random password long enough for the
+// policy and with at least as many characters of each class as it requires.
+// Symbols are only used when the policy requires them. A nil policy is
+// defaultPasswordComplexity.
+func generatePasswordForPolicy(c *okta.PasswordPolicyPasswordSettingsComplexity) string {
+	if c == nil {
+		c = defaultPasswordComplexity
+	}
+	var buf []byte
+	for _, class := range []struct {
+		chars string
+		min   int64
+	}{
+		{passwordDigits, c.MinNumber},
+		{passwordLowers, c.MinLowerCase},
+		{passwordUppers, c.MinUpperCase},
+		{passwordSymbols, c.MinSymbol},
+	} {
+		for i := int64(0); i < class.min; i++ {
+			buf = append(buf, class.chars[rand.Intn(len(class.chars))])
+		}
+	}
+
+	all := passwordLowers + passwordUppers + passwordDigits
+	if c.MinSymbol > 0 {
+		all += passwordSymbols
+	}
+	length := minPasswordLength
+	if int(c.MinLength) > length {
+		length = int(c.MinLength)
+	}
+	for len(buf) < length {
+		buf = append(buf, all[rand.Intn(len(all))])
+	}
+	rand.Shuffle(len(buf), func(i, j int) {
+		buf[i], buf[j] = buf[j], buf[i]
+	})
+	return string(buf)
+}
+
+// strictestComplexity combines the complexity of the active password
+// policies so a password generated for it passes whichever policy applies
+// to a user. It's nil when none of them set one.
+func strictestComplexity(policies []okta.PasswordPolicy) *okta.PasswordPolicyPasswordSettingsComplexity {
+	var strictest *okta.PasswordPolicyPasswordSettingsComplexity
+	for _, p := range policies {
+		if p.Status != "ACTIVE" || p.Settings == nil || p.Settings.Password == nil || p.Settings.Password.Complexity == nil {
+			continue
+		}
+		c := p.Settings.Password.Complexity
+		if strictest == nil {
+			strictest = &okta.PasswordPolicyPasswordSettingsComplexity{}
+		}
+		strictest.MinLength = max64(strictest.MinLength, c.MinLength)
+		strictest.MinLowerCase = max64(strictest.MinLowerCase, c.MinLowerCase)
+		strictest.MinUpperCase = max64(strictest.MinUpperCase, c.MinUpperCase)
+		strictest.MinNumber = max64(strictest.MinNumber, c.MinNumber)
+		strictest.MinSymbol = max64(strictest.MinSymbol, c.MinSymbol)
+	}
+	return strictest
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// orgPasswordComplexity reads the org's password policies.
+func (th *TestHarness) orgPasswordComplexity() (*okta.PasswordPolicyPasswordSettingsComplexity, error) {
+	req, err := th.oktaClient.GetRequestExecutor().NewRequest(http.MethodGet, "/api/v1/policies?type=PASSWORD", nil)
+	if err != nil {
+		return nil, err
+	}
+	var policies []okta.PasswordPolicy
+	if _, err = th.oktaClient.GetRequestExecutor().Do(context.Background(), req, &policies); err != nil {
+		return nil, fmt.Errorf("list password policies error: %w", err)
+	}
+	return strictestComplexity(policies), nil
+}
+
+// generatePassword generates a password the org's password policies accept.
+func (th *TestHarness) generatePassword() string {
+	return generatePasswordForPolicy(th.org.passwordComplexity)
+}
@@ -0,0 +1,87 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+func countOf(s, chars string) int64 {
+	var n int64
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGeneratePasswordForPolicy(t *testing.T) {
+	policies := map[string]*okta.PasswordPolicyPasswordSettingsComplexity{
+		"default":    nil,
+		"okta":       {MinLength: 8, MinLowerCase: 1, MinUpperCase: 1, MinNumber: 1},
+		"symbols":    {MinLength: 10, MinLowerCase: 1, MinUpperCase: 1, MinNumber: 2, MinSymbol: 3},
+		"long":       {MinLength: 32, MinLowerCase: 1},
+		"classes":    {MinLength: 4, MinLowerCase: 5, MinUpperCase: 5, MinNumber: 5, MinSymbol: 5},
+		"no classes": {MinLength: 15},
+	}
+	for name, c := range policies {
+		expected := c
+		if expected == nil {
+			expected = defaultPasswordComplexity
+		}
+		for i := 0; i < 50; i++ {
+			p := generatePasswordForPolicy(c)
+			if int64(len(p)) < expected.MinLength || len(p) < minPasswordLength {
+				t.Fatalf("%s: %q is too short", name, p)
+			}
+			if countOf(p, passwordLowers) < expected.MinLowerCase ||
+				countOf(p, passwordUppers) < expected.MinUpperCase ||
+				countOf(p, passwordDigits) < expected.MinNumber ||
+				countOf(p, passwordSymbols) < expected.MinSymbol {
+				t.Fatalf("%s: %q misses a required character class", name, p)
+			}
+			if expected.MinSymbol == 0 && countOf(p, passwordSymbols) != 0 {
+				t.Fatalf("%s: %q has symbols the policy doesn't ask for", name, p)
+			}
+		}
+	}
+}
+
+func TestStrictestComplexity(t *testing.T) {
+	policy := func(status string, c *okta.PasswordPolicyPasswordSettingsComplexity) okta.PasswordPolicy {
+		return okta.PasswordPolicy{
+			Status:   status,
+			Settings: &okta.PasswordPolicySettings{Password: &okta.PasswordPolicyPasswordSettings{Complexity: c}},
+		}
+	}
+	c := strictestComplexity([]okta.PasswordPolicy{
+		policy("ACTIVE", &okta.PasswordPolicyPasswordSettingsComplexity{MinLength: 8, MinNumber: 1}),
+		policy("ACTIVE", &okta.PasswordPolicyPasswordSettingsComplexity{MinLength: 14, MinSymbol: 1}),
+		policy("INACTIVE", &okta.PasswordPolicyPasswordSettingsComplexity{MinLength: 40}),
+		{Status: "ACTIVE"},
+	})
+	if c == nil || c.MinLength != 14 || c.MinNumber != 1 || c.MinSymbol != 1 {
+		t.Errorf("expected the strictest of the active policies, got %+v", c)
+	}
+	if strictestComplexity(nil) != nil {
+		t.Error("expected no complexity without policies")
+	}
+}
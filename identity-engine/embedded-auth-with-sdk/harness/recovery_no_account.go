@@ -0,0 +1,58 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import "os"
+
+const (
+	// recoveryNoAccountMessage is Okta's error for recovering an account
+	// that doesn't exist when the org reveals it
+	recoveryNoAccountMessage = "Reset password is not allowed at this time. Please contact support for assistance."
+	// recoveryGenericMessage is the sample's code page notice, which is all an
+	// org protecting against account enumeration shows for an unknown email
+	recoveryGenericMessage = "If an account exists for the email you entered, a code has been sent to it."
+)
+
+// recoveryNoAccountError is the error expected when recovering an unknown
+// email on an org that reveals whether accounts exist, which
+// HARNESS_RECOVERY_NO_ACCOUNT_MESSAGE overrides, e.g. for a customized org.
+func recoveryNoAccountError() string {
+	if message := os.Getenv("HARNESS_RECOVERY_NO_ACCOUNT_MESSAGE"); message != "" {
+		return message
+	}
+	return recoveryNoAccountMessage
+}
+
+func (th *TestHarness) seesRecoveryNoAccountError() error {
+	return th.seesErrorMessage(recoveryNoAccountError())
+}
+
+// seesRecoveryAccountNotice checks the code page says the code was only sent
+// if the account exists, which is all an org protecting against account
+// enumeration says about an unknown email.
+func (th *TestHarness) seesRecoveryAccountNotice() error {
+	return th.seesElementWithText(`#recovery-code-notice`, recoveryGenericMessage)
+}
+
+// doesntSeeRecoveryAccountNotice checks the code page doesn't hedge when Okta
+// recovers a known account.
+func (th *TestHarness) doesntSeeRecoveryAccountNotice() error {
+	if err := th.waitForEmailCodeForm(); err != nil {
+		return err
+	}
+	return th.doesNotSeeElement(`#recovery-code-notice`)
+}
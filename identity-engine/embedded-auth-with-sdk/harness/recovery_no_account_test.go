@@ -0,0 +1,35 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRecoveryNoAccountError(t *testing.T) {
+	defer os.Setenv("HARNESS_RECOVERY_NO_ACCOUNT_MESSAGE", os.Getenv("HARNESS_RECOVERY_NO_ACCOUNT_MESSAGE"))
+	os.Setenv("HARNESS_RECOVERY_NO_ACCOUNT_MESSAGE", "")
+	if got := recoveryNoAccountError(); got != recoveryNoAccountMessage {
+		t.Errorf("expected Okta's no account error by default, got %q", got)
+	}
+
+	os.Setenv("HARNESS_RECOVERY_NO_ACCOUNT_MESSAGE", "Check your email.")
+	if got := recoveryNoAccountError(); got != "Check your email." {
+		t.Errorf("expected the configured message, got %q", got)
+	}
+}
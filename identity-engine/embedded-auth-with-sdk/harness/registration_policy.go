@@ -0,0 +1,81 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// What the sample shows after a new user sets their password, depending on
+// the org's profile enrollment policy.
+const (
+	registrationSkipsOptionalFactors = `input[type="submit"][value="Skip"]`
+	registrationSignedIn             = `form[action="/logout"]`
+	registrationVerifiesEmail        = `form[action="/enrollFactor"] input[id="push_email"]`
+)
+
+// registrationEmailVerification is what the org's policy does after sign up,
+// from HARNESS_REGISTRATION_EMAIL_VERIFICATION: "required" for an email
+// verification step, "none" for direct activation, or empty to accept either.
+func registrationEmailVerification() string {
+	return os.Getenv("HARNESS_REGISTRATION_EMAIL_VERIFICATION")
+}
+
+// checkRegistrationOutcome fails when the sample asked for email verification,
+// or didn't, contrary to the configured policy.
+func checkRegistrationOutcome(policy string, verifiesEmail bool) error {
+	switch {
+	case policy == "required" && !verifiesEmail:
+		return errors.New("expected the policy to require email verification after sign up, but the user was activated directly")
+	case policy == "none" && verifiesEmail:
+		return errors.New("expected the user to be activated directly after sign up, but they were asked to verify their email")
+	}
+	return nil
+}
+
+// completesRegistrationForPolicy finishes a sign up whichever way the org's
+// policy goes: it verifies the email when asked to, then skips any optional
+// factors.
+func (th *TestHarness) completesRegistrationForPolicy() error {
+	// optional factors can list email too, so skip is looked for first
+	seen, err := th.waitForAnyOf(registrationSkipsOptionalFactors, registrationSignedIn, registrationVerifiesEmail)
+	if err != nil {
+		return fmt.Errorf("expected an email verification step or direct activation after sign up: %w", err)
+	}
+	verifiesEmail := seen == registrationVerifiesEmail
+	if err = checkRegistrationOutcome(registrationEmailVerification(), verifiesEmail); err != nil {
+		return err
+	}
+
+	if verifiesEmail {
+		if err = th.selectsEmail(); err != nil {
+			return err
+		}
+		if err = th.fillsInTheEnrollmentCode(); err != nil {
+			return err
+		}
+		if seen, err = th.waitForAnyOf(registrationSkipsOptionalFactors, registrationSignedIn); err != nil {
+			return err
+		}
+	}
+	if seen == registrationSkipsOptionalFactors {
+		return th.clicksSkip()
+	}
+	return nil
+}
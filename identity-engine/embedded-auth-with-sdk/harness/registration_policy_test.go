@@ -0,0 +1,40 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import "testing"
+
+func TestCheckRegistrationOutcome(t *testing.T) {
+	tests := []struct {
+		policy        string
+		verifiesEmail bool
+		fails         bool
+	}{
+		{"required", true, false},
+		{"required", false, true},
+		{"none", false, false},
+		{"none", true, true},
+		{"", true, false},
+		{"", false, false},
+	}
+	for _, test := range tests {
+		err := checkRegistrationOutcome(test.policy, test.verifiesEmail)
+		if (err != nil) != test.fails {
+			t.Errorf("policy %q with email verification %v: expected failure %v, got %v", test.policy, test.verifiesEmail, test.fails, err)
+		}
+	}
+}
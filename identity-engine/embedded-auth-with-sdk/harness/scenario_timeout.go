@@ -0,0 +1,115 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/tebeka/selenium"
+)
+
+// scenarioDeadline aborts a scenario that runs past its timeout, e.g. one
+// stuck on a hung WebDriver or an A18N code that never arrives.
+type scenarioDeadline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startScenarioDeadline calls onTimeout once timeout elapses unless stop is
+// called first.
+func startScenarioDeadline(timeout time.Duration, onTimeout func()) *scenarioDeadline {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	d := &scenarioDeadline{ctx: ctx, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(d.done)
+		<-ctx.Done()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			onTimeout()
+		}
+	}()
+	return d
+}
+
+// stop ends the deadline and waits for a timeout already in progress to
+// finish so teardown doesn't race it. A nil deadline, e.g. of a scenario
+// whose WebDriver session never started, is already stopped.
+func (d *scenarioDeadline) stop() {
+	if d == nil {
+		return
+	}
+	d.cancel()
+	<-d.done
+}
+
+func (d *scenarioDeadline) timedOut() bool {
+	return d != nil && errors.Is(d.ctx.Err(), context.DeadlineExceeded)
+}
+
+func scenarioTimeout() time.Duration {
+	// HARNESS_SCENARIO_TIMEOUT defaults to 10 minutes per scenario
+	timeout, err := time.ParseDuration(os.Getenv("HARNESS_SCENARIO_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		return 10 * time.Minute
+	}
+	return timeout
+}
+
+var artifactNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// artifactPath is where an artifact of the named scenario is written, in
+// HARNESS_ARTIFACT_DIR or the temp dir.
+func artifactPath(scenario, suffix string) string {
+	dir := os.Getenv("HARNESS_ARTIFACT_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, artifactNameUnsafe.ReplaceAllString(scenario, "_")+suffix)
+}
+
+// captureArtifacts saves a screenshot and the page source of the browser so
+// a scenario that timed out can be looked at afterwards.
+func captureArtifacts(wd selenium.WebDriver, scenario string) {
+	if png, err := wd.Screenshot(); err == nil {
+		path := artifactPath(scenario, "-timeout.png")
+		if err = ioutil.WriteFile(path, png, 0644); err == nil {
+			fmt.Printf("saved screenshot to %s\n", path)
+		}
+	}
+	if source, err := wd.PageSource(); err == nil {
+		path := artifactPath(scenario, "-timeout.html")
+		if err = ioutil.WriteFile(path, []byte(source), 0644); err == nil {
+			fmt.Printf("saved page source to %s\n", path)
+		}
+	}
+}
+
+// scenarioContext is cancelled when the running scenario times out. Teardown
+// must not use it since it runs after the deadline has passed.
+func (th *TestHarness) scenarioContext() context.Context {
+	if th.deadline == nil {
+		return context.Background()
+	}
+	return th.deadline.ctx
+}
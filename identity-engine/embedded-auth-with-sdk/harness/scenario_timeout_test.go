@@ -0,0 +1,72 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScenarioDeadlineFiresOnTimeout(t *testing.T) {
+	fired := make(chan struct{})
+	d := startScenarioDeadline(10*time.Millisecond, func() { close(fired) })
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the timeout to fire")
+	}
+	d.stop()
+	if !d.timedOut() {
+		t.Error("expected the deadline to report it timed out")
+	}
+	if d.ctx.Err() == nil {
+		t.Error("expected the scenario context to be done")
+	}
+}
+
+func TestScenarioDeadlineStoppedInTime(t *testing.T) {
+	d := startScenarioDeadline(time.Minute, func() { t.Error("expected the timeout not to fire") })
+	d.stop()
+	if d.timedOut() {
+		t.Error("expected a stopped deadline not to report a timeout")
+	}
+}
+
+func TestScenarioTimeout(t *testing.T) {
+	os.Setenv("HARNESS_SCENARIO_TIMEOUT", "90s")
+	defer os.Unsetenv("HARNESS_SCENARIO_TIMEOUT")
+	if got := scenarioTimeout(); got != 90*time.Second {
+		t.Errorf("expected 90s, got %s", got)
+	}
+
+	os.Setenv("HARNESS_SCENARIO_TIMEOUT", "soon")
+	if got := scenarioTimeout(); got != 10*time.Minute {
+		t.Errorf("expected the 10m default for an invalid timeout, got %s", got)
+	}
+}
+
+func TestArtifactPath(t *testing.T) {
+	os.Setenv("HARNESS_ARTIFACT_DIR", "/tmp/artifacts")
+	defer os.Unsetenv("HARNESS_ARTIFACT_DIR")
+	got := artifactPath("4.1.1 Mary signs up / skips SMS", "-timeout.png")
+	if want := filepath.Join("/tmp/artifacts", "4.1.1_Mary_signs_up_skips_SMS-timeout.png"); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -2,13 +2,13 @@ package harness
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/cucumber/godog"
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 )
@@ -65,6 +65,11 @@ func (th *TestHarness) fillInOrgInfo() {
 		th.org.mfaRuleID = v.ID
 		break
 	}
+
+	th.org.passwordComplexity, err = th.orgPasswordComplexity()
+	if err != nil {
+		log.Printf("generated passwords fall back to the default complexity: %+v", err)
+	}
 }
 
 type OktaAppSignOnPolicyRule struct {
@@ -187,25 +192,26 @@ func (th *TestHarness) resetAppSignOnPolicyRule() error {
 }
 
 func (th *TestHarness) addUser(condition string) error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
-	}
-	profile := okta.UserProfile{}
-	profile["firstName"] = th.currentProfile.GivenName
-	profile["lastName"] = th.currentProfile.FamilyName
-	profile["login"] = th.currentProfile.EmailAddress
-	profile["email"] = th.currentProfile.EmailAddress
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	userProfile := okta.UserProfile{}
+	userProfile["firstName"] = profile.GivenName
+	userProfile["lastName"] = profile.FamilyName
+	userProfile["login"] = profile.EmailAddress
+	userProfile["email"] = profile.EmailAddress
 	if condition == "with" {
-		profile["mobilePhone"] = th.currentProfile.PhoneNumber
-		profile["primaryPhone"] = th.currentProfile.PhoneNumber
+		userProfile["mobilePhone"] = profile.PhoneNumber
+		userProfile["primaryPhone"] = profile.PhoneNumber
 	}
 	b := okta.CreateUserRequest{
 		Credentials: &okta.UserCredentials{
 			Password: &okta.PasswordCredential{
-				Value: th.currentProfile.Password,
+				Value: profile.Password,
 			},
 		},
-		Profile: &profile,
+		Profile: &userProfile,
 	}
 	u, _, err := th.oktaClient.User.CreateUser(context.Background(), b, nil)
 	if err != nil {
@@ -217,18 +223,22 @@ func (th *TestHarness) addUser(condition string) error {
 			return err
 		}
 	}
-	th.currentProfile.UserID = u.Id
+	profile.UserID = u.Id
 	return nil
 }
 
 func (th *TestHarness) enrollSMSFactor(uID string) error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
 	factor := []byte(fmt.Sprintf(`{
 	  "factorType": "sms",
 	  "provider": "OKTA",
 	  "profile": {
 	    "phoneNumber": "%s"
 	  }
-	}`, th.currentProfile.PhoneNumber))
+	}`, profile.PhoneNumber))
 	req, err := th.oktaClient.GetRequestExecutor().
 		WithAccept("application/json").
 		WithContentType("application/json").
@@ -241,17 +251,18 @@ func (th *TestHarness) enrollSMSFactor(uID string) error {
 	if err != nil {
 		return err
 	}
-	code, err := th.verificationCode(th.currentProfile.URL, SMS_CODE_TYPE)
+	code, err := th.verificationCode(profile.URL, SMS_CODE_TYPE)
 	if err != nil {
-		return fmt.Errorf("faild to find latest verification code for user %s: %v", th.currentProfile.EmailAddress, err)
+		return fmt.Errorf("faild to find latest verification code for user %s: %v", profile.EmailAddress, err)
 	}
 	_, _, err = th.oktaClient.UserFactor.ActivateFactor(context.Background(), uID, uf.ID, okta.ActivateFactorRequest{PassCode: code}, nil)
 	return err
 }
 
 func (th *TestHarness) addUserToGroup(groupName string) error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
 	// user is auto assigned to this group
 	if groupName == "Everyone" {
@@ -265,7 +276,7 @@ func (th *TestHarness) addUserToGroup(groupName string) error {
 		if g.Profile.Name != groupName {
 			continue
 		}
-		_, err = th.oktaClient.Group.AddUserToGroup(context.Background(), g.Id, th.currentProfile.UserID)
+		_, err = th.oktaClient.Group.AddUserToGroup(context.Background(), g.Id, profile.UserID)
 		return err
 	}
 	return fmt.Errorf("group %s doesn't exist in the org", groupName)
@@ -302,3 +313,125 @@ func (th *TestHarness) singOnPolicyRuleGroup() error {
 	_, err = th.oktaClient.GetRequestExecutor().Do(context.Background(), req, nil)
 	return err
 }
+
+func (th *TestHarness) changesFamilyName(familyName string) error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	userProfile := okta.UserProfile{}
+	userProfile["lastName"] = familyName
+	user := okta.User{Profile: &userProfile}
+	_, _, err = th.oktaClient.User.PartialUpdateUser(context.Background(), profile.UserID, user, nil)
+	if err != nil {
+		return err
+	}
+	profile.FamilyName = familyName
+	profile.DisplayName = fmt.Sprintf("%s %s", profile.GivenName, familyName)
+	return nil
+}
+
+// resetsUserFactors removes every factor the current profile's Okta user has
+// enrolled so the scenario can go through enrollment again. A18N profiles are
+// reused and may still carry factors from an earlier run.
+func (th *TestHarness) hasAdminCredentials() bool {
+	creds := th.oktaClient.GetConfig().Okta.Client
+	return creds.Token != "" || creds.PrivateKey != ""
+}
+
+// currentOktaUserID is the Okta user id of the scenario's user.
+func (th *TestHarness) currentOktaUserID() (string, error) {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return "", err
+	}
+	if profile.UserID != "" {
+		return profile.UserID, nil
+	}
+	// users who signed up through the sample are looked up by login
+	u, _, err := th.oktaClient.User.GetUser(context.Background(), profile.EmailAddress)
+	if err != nil {
+		return "", fmt.Errorf("could not find the Okta user for %s: %w", profile.EmailAddress, err)
+	}
+	return u.Id, nil
+}
+
+func (th *TestHarness) resetsUserFactors() error {
+	if !th.hasAdminCredentials() {
+		return fmt.Errorf("resetting factors needs admin credentials, set OKTA_CLIENT_TOKEN or OKTA_CLIENT_PRIVATEKEY")
+	}
+
+	userID, err := th.currentOktaUserID()
+	if err != nil {
+		return err
+	}
+
+	factors, _, err := th.oktaClient.UserFactor.ListFactors(context.Background(), userID)
+	if err != nil {
+		return err
+	}
+	for _, f := range factors {
+		factor, ok := f.(*okta.UserFactor)
+		if !ok {
+			continue
+		}
+		resp, err := th.oktaClient.UserFactor.DeleteFactor(context.Background(), userID, factor.Id)
+		// suppress Not Found error
+		if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+			return fmt.Errorf("could not reset %s factor: %w", factor.FactorType, err)
+		}
+	}
+	return nil
+}
+
+// enrolledFactorTypes maps a factor named in a scenario to the Okta factor
+// types that count as it being enrolled.
+var enrolledFactorTypes = map[string][]string{
+	"email": {"email"},
+	"sms":   {"sms"},
+	"voice": {"call"},
+	"phone": {"sms", "call"},
+}
+
+// hasActiveFactor reports whether one of factors is an active factor of one
+// of the types.
+func hasActiveFactor(factors []okta.Factor, types []string) bool {
+	for _, f := range factors {
+		factor, ok := f.(*okta.UserFactor)
+		if !ok || factor.Status != "ACTIVE" {
+			continue
+		}
+		for _, t := range types {
+			if factor.FactorType == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// userHasEnrolledFactor checks with Okta that the scenario's user has the
+// factor active, so an enrollment is known to have taken effect and not just
+// to have looked like it did. Without admin credentials the step is pending.
+func (th *TestHarness) userHasEnrolledFactor(factorType string) error {
+	types, ok := enrolledFactorTypes[strings.ToLower(factorType)]
+	if !ok {
+		return fmt.Errorf("unknown factor type %q", factorType)
+	}
+	if !th.hasAdminCredentials() {
+		return fmt.Errorf("%w: checking enrolled factors needs admin credentials, set OKTA_CLIENT_TOKEN or OKTA_CLIENT_PRIVATEKEY", godog.ErrPending)
+	}
+
+	userID, err := th.currentOktaUserID()
+	if err != nil {
+		return err
+	}
+	factors, _, err := th.oktaClient.UserFactor.ListFactors(context.Background(), userID)
+	if err != nil {
+		return err
+	}
+	if !hasActiveFactor(factors, types) {
+		return fmt.Errorf("expected user %s to have an active %s factor", userID, factorType)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+func TestHasActiveFactor(t *testing.T) {
+	factors := []okta.Factor{
+		&okta.UserFactor{FactorType: "email", Status: "ACTIVE"},
+		&okta.UserFactor{FactorType: "sms", Status: "PENDING_ACTIVATION"},
+	}
+
+	tests := map[string]bool{
+		"email": true,
+		"sms":   false,
+		"voice": false,
+		"phone": false,
+	}
+	for name, expected := range tests {
+		if got := hasActiveFactor(factors, enrolledFactorTypes[name]); got != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+
+	factors = append(factors, &okta.UserFactor{FactorType: "call", Status: "ACTIVE"})
+	if !hasActiveFactor(factors, enrolledFactorTypes["phone"]) {
+		t.Error("expected an active voice factor to count as a phone factor")
+	}
+}
@@ -0,0 +1,134 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cucumber/godog"
+)
+
+// stepTimingsPath is where the step timing summary is written at the end of
+// the suite, from HARNESS_STEP_TIMINGS. Empty leaves steps untimed.
+func stepTimingsPath() string {
+	return os.Getenv("HARNESS_STEP_TIMINGS")
+}
+
+// quotedArg matches a step's quoted arguments, so e.g. each page title shares
+// the timings of the step that checks it.
+var quotedArg = regexp.MustCompile(`"[^"]*"`)
+
+// stepTimings records how long each step took, grouped by its text.
+type stepTimings struct {
+	mu        sync.Mutex
+	started   map[string]time.Time
+	durations map[string][]time.Duration
+}
+
+type stepTimingSummary struct {
+	Step  string  `json:"step"`
+	Count int     `json:"count"`
+	P50Ms float64 `json:"p50Ms"`
+	P95Ms float64 `json:"p95Ms"`
+	MaxMs float64 `json:"maxMs"`
+}
+
+func newStepTimings() *stepTimings {
+	return &stepTimings{
+		started:   map[string]time.Time{},
+		durations: map[string][]time.Duration{},
+	}
+}
+
+func (t *stepTimings) beforeStep(st *godog.Step) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started[st.Id] = time.Now()
+}
+
+func (t *stepTimings) afterStep(st *godog.Step, _ error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start, ok := t.started[st.Id]
+	if !ok {
+		return
+	}
+	delete(t.started, st.Id)
+	t.record(st.Text, time.Since(start))
+}
+
+func (t *stepTimings) record(text string, d time.Duration) {
+	key := quotedArg.ReplaceAllString(text, `"..."`)
+	t.durations[key] = append(t.durations[key], d)
+}
+
+// percentile is the nearest rank percentile p of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// summary lists each step's p50 and p95, slowest p95 first.
+func (t *stepTimings) summary() []stepTimingSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	summaries := make([]stepTimingSummary, 0, len(t.durations))
+	for step, durations := range t.durations {
+		sorted := append([]time.Duration(nil), durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		summaries = append(summaries, stepTimingSummary{
+			Step:  step,
+			Count: len(sorted),
+			P50Ms: milliseconds(percentile(sorted, 50)),
+			P95Ms: milliseconds(percentile(sorted, 95)),
+			MaxMs: milliseconds(sorted[len(sorted)-1]),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].P95Ms != summaries[j].P95Ms {
+			return summaries[i].P95Ms > summaries[j].P95Ms
+		}
+		return summaries[i].Step < summaries[j].Step
+	})
+	return summaries
+}
+
+// write saves the summary as JSON to path.
+func (t *stepTimings) write(path string) error {
+	b, err := json.MarshalIndent(t.summary(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0o644)
+}
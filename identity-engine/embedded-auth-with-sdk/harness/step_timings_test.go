@@ -0,0 +1,59 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStepTimingsSummary(t *testing.T) {
+	timings := newStepTimings()
+	for i := 1; i <= 20; i++ {
+		timings.record("she fills in the code from her email", time.Duration(i)*time.Second)
+	}
+	timings.record(`she sees the page title "Login"`, 10*time.Millisecond)
+	timings.record(`she sees the page title "Profile"`, 30*time.Millisecond)
+
+	summary := timings.summary()
+	if len(summary) != 2 {
+		t.Fatalf("expected quoted arguments to share a step, got %+v", summary)
+	}
+	slowest := summary[0]
+	if slowest.Step != "she fills in the code from her email" || slowest.Count != 20 || slowest.P50Ms != 10000 || slowest.P95Ms != 19000 || slowest.MaxMs != 20000 {
+		t.Errorf("unexpected summary of the slowest step %+v", slowest)
+	}
+	if title := summary[1]; title.Step != `she sees the page title "..."` || title.Count != 2 || title.P50Ms != 10 || title.P95Ms != 30 {
+		t.Errorf("unexpected summary of the title step %+v", title)
+	}
+
+	path := filepath.Join(t.TempDir(), "timings.json")
+	if err := timings.write(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []stepTimingSummary
+	if err = json.Unmarshal(b, &written); err != nil || len(written) != 2 {
+		t.Errorf("expected the summary as JSON, got %s: %v", b, err)
+	}
+}
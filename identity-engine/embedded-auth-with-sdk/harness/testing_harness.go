@@ -41,6 +41,7 @@ import (
 const (
 	EMAIL_CODE_TYPE = "email"
 	SMS_CODE_TYPE   = "sms"
+	VOICE_CODE_TYPE = "voice"
 )
 
 type A18NProfile struct {
@@ -53,7 +54,8 @@ type A18NProfile struct {
 	GivenName    string
 	FamilyName   string
 	UserID       string
-	ErrorDesc    string `json:"errorDescription"`
+	ErrorDesc    string    `json:"errorDescription"`
+	CreatedAt    time.Time `json:"createdAt"`
 }
 
 type A18NProfiles struct {
@@ -92,6 +94,13 @@ type TestHarness struct {
 	httpClient     *http.Client
 	oktaClient     *okta.Client
 	org            orgData
+	sessions       sessionLimiter
+	deadline       *scenarioDeadline
+	timings        *stepTimings
+	expirer        sessionExpirer
+	// suiteConfig holds the server config fields scenarios may change, as
+	// they were at suite start, so each scenario can put them back.
+	suiteConfig config.Config
 }
 
 type orgData struct {
@@ -99,22 +108,32 @@ type orgData struct {
 	mfaRuleID          string
 	mfaRequiredGroupID string
 	everyoneGroupID    string
+	passwordComplexity *okta.PasswordPolicyPasswordSettingsComplexity
 }
 
 func NewTestHarness() *TestHarness {
-	return &TestHarness{
-		httpClient: &http.Client{Timeout: time.Second * 30},
+	th := &TestHarness{
+		httpClient: &http.Client{
+			Timeout:   time.Second * 30,
+			Transport: &server.UserAgentTransport{},
+		},
+		sessions: newSessionLimiter(maxSessions()),
 	}
+	if stepTimingsPath() != "" {
+		th.timings = newStepTimings()
+	}
+	return th
 }
 
 func (th *TestHarness) InitializeTestSuite(ctx *godog.TestSuiteContext) {
 	rand.Seed(time.Now().UnixNano())
 	ctx.BeforeSuite(func() {
 		httpClient := &http.Client{Timeout: time.Second * 30}
-		httpClient.Transport = &testThrottledTransport{}
+		httpClient.Transport = &testThrottledTransport{expirer: &th.expirer}
 		cfg := &config.Config{
-			Testing:    true,
-			HttpClient: httpClient,
+			Testing:      true,
+			HttpClient:   httpClient,
+			WelcomeClaim: os.Getenv("WELCOME_CLAIM"),
 		}
 		_, client, err := okta.NewClient(
 			context.Background(),
@@ -125,21 +144,54 @@ func (th *TestHarness) InitializeTestSuite(ctx *godog.TestSuiteContext) {
 		}
 		srv := server.NewServer(cfg)
 		th.server = srv
+		th.suiteConfig = *cfg
 		th.oktaClient = client
 
 		th.depopulateMary()
 		th.fillInOrgInfo()
 
+		if code := os.Getenv("HARNESS_A18N_STUB_CODE"); code != "" {
+			a18nStubServer = newA18NStub(code)
+			log.Printf("serving A18N verification codes from a stub at %s", a18nStubServer.URL)
+		}
+
+		if olderThan := os.Getenv("A18N_CLEANUP_OLDER_THAN"); olderThan != "" {
+			d, err := time.ParseDuration(olderThan)
+			if err != nil {
+				log.Fatalf("invalid A18N_CLEANUP_OLDER_THAN %q: %+v", olderThan, err)
+			}
+			reclaimed, err := th.cleanupStaleProfiles(d)
+			if err != nil {
+				log.Printf("cleanup stale A18N profiles error: %+v", err)
+			}
+			log.Printf("reclaimed %d stale A18N profiles", reclaimed)
+		}
+
 		srv.Run()
 	})
 
 	ctx.AfterSuite(func() {
+		if a18nStubServer != nil {
+			a18nStubServer.Close()
+		}
+		if th.timings != nil {
+			if err := th.timings.write(stepTimingsPath()); err != nil {
+				log.Printf("write step timings error: %+v", err)
+			} else {
+				log.Printf("step timings written to %s", stepTimingsPath())
+			}
+		}
 	})
 }
 
-type testThrottledTransport struct{}
+type testThrottledTransport struct {
+	expirer *sessionExpirer
+}
 
 func (t *testThrottledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if resp, ok := t.expirer.intercept(req); ok {
+		return resp, nil
+	}
 	// Rapid concurrent connections that can be exhibited in an automated test
 	// harness can get rate limited.
 	// https://developer.okta.com/docs/reference/rl-additional-limits/
@@ -198,16 +250,51 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 
 	th.capabilities = capabilities
 
+	if th.timings != nil {
+		ctx.BeforeStep(th.timings.beforeStep)
+		ctx.AfterStep(th.timings.afterStep)
+	}
+
 	ctx.BeforeScenario(func(sc *messages.Pickle) {
 		th.capabilities["name"] = fmt.Sprintf("Golang (%s / %s) Sample App - %q", os.Getenv("TRAVIS_GO_VERSION"), os.Getenv("TRAVIS_REPO_SLUG"), sc.Name)
 		var err error
+		th.sessions.acquire()
+		// AfterScenario releases the slot of a started session; one that
+		// fails to start gives it back here, before the panic unwinds
+		started := false
+		defer func() {
+			if !started {
+				th.sessions.release()
+			}
+		}()
 		th.wd, err = selenium.NewRemote(th.capabilities, seleniumUrl)
 		if err != nil {
 			log.Panic(err)
 		}
+		if seleniumVerbose() {
+			th.wd = newVerboseWebDriver(th.wd)
+		}
+		if err = th.setsDefaultViewport(); err != nil {
+			_ = th.wd.Quit()
+			log.Panic(err)
+		}
+		started = true
+
+		wd := th.wd
+		th.deadline = startScenarioDeadline(scenarioTimeout(), func() {
+			fmt.Printf("scenario %q exceeded its %s timeout, aborting\n", sc.Name, scenarioTimeout())
+			captureArtifacts(wd, sc.Name)
+			// ending the WebDriver session fails the step waiting on it
+			_ = wd.Quit()
+		})
 	})
 
 	ctx.AfterScenario(func(sc *messages.Pickle, err error) {
+		th.deadline.stop()
+		timedOut := th.deadline.timedOut()
+		if timedOut {
+			err = fmt.Errorf("scenario %q timed out after %s: %v", sc.Name, scenarioTimeout(), err)
+		}
 		if err != nil {
 			fmt.Printf("AfterScenario error: %+v\n", err)
 		}
@@ -218,6 +305,12 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 			fmt.Printf("AfterScenario error destroying profile: %+v\n", err)
 		}
 
+		th.server.UpdateConfig(func(c *config.Config) {
+			c.AutoSelectAuthenticator = th.suiteConfig.AutoSelectAuthenticator
+			c.PhoneCountries = th.suiteConfig.PhoneCountries
+		})
+		th.expirer.reset()
+
 		err = th.resetAppSignOnPolicyRule()
 		if err != nil {
 			fmt.Printf("AfterScenario error reseting Sign On Policy (next tests might fail): %+v\n", err)
@@ -225,20 +318,34 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 
 		// always force a logout
 		logoutXHR := fmt.Sprintf("var xhr = new XMLHttpRequest(); xhr.open(\"POST\", \"/logout\", false); xhr.send(\"\");")
-		_, _ = th.wd.ExecuteScript(logoutXHR, nil)
-		err = th.wd.Quit()
-		if err != nil {
-			fmt.Printf("AfterScenario error quiting web driver: %+v\n", err)
+		if !timedOut {
+			_, _ = th.wd.ExecuteScript(logoutXHR, nil)
+			err = th.wd.Quit()
+			if err != nil {
+				fmt.Printf("AfterScenario error quiting web driver: %+v\n", err)
+			}
 		}
+		th.deadline = nil
+		th.sessions.release()
 	})
 
 	ctx.Step(`there is an existing user`, th.existingUser)
 	ctx.Step(`sleep ([^" ]+)`, th.debugSleep)
+	ctx.Step(`browser window is (\d+)x(\d+)`, th.setsViewport)
+	ctx.Step(`browser window is maximized`, th.maximizesViewport)
+	ctx.Step(`selects "([^"]*)" from the ([^ ]+) dropdown`, th.selectsFromDropdown)
 
 	ctx.Step(`navigates to the Root View`, th.navigateToTheRootView)
 	ctx.Step(`Root Page shows links to the Entry Points`, th.checkEntryPoints)
+	ctx.Step(`the "Sign In" link leads to the Login form`, th.signInLinkLeadsToLoginForm)
+	ctx.Step(`the "Sign Up" link leads to the Registration form`, th.signUpLinkLeadsToRegistrationForm)
+	ctx.Step(`the "Password Recovery" link leads to the Password Recovery form`, th.passwordRecoveryLinkLeadsToRecoveryForm)
 	ctx.Step(`logs in to the Application`, th.loginToApplication)
+	ctx.Step(`sees a table with exactly the claims`, th.seesExactClaims)
 	ctx.Step(`sees a table with the claims`, th.seesClaimsTable)
+	ctx.Step(`sees the claim "([^"]*)" labeled "([^"]*)"`, th.seesClaimLabel)
+	ctx.Step(`sees the group "([^"]*)" on (?:her|his|their) profile`, th.seesGroup)
+	ctx.Step(`the app's ID tokens carry a groups claim`, th.appIssuesGroupsClaim)
 	ctx.Step(`doesn't see a table with the claims`, th.doesntSeeClaimsTable)
 	ctx.Step(`sees a logout button`, th.seesLogoutButton)
 	ctx.Step(`clicks the logout button`, th.clicksLogoutButton)
@@ -246,12 +353,21 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 	ctx.Step(`is redirected back to the Root View`, th.isRootView)
 
 	ctx.Step(`navigates to .* Basic Login`, th.navigateToBasicLogin)
+	ctx.Step(`sees the username field has focus`, th.identifierHasFocus)
 	ctx.Step(`fills in (their|her|his) correct username`, th.fillsInUsername)
 	ctx.Step(`fills in (their|her|his) incorrect username`, th.fillsInIncorrectUsername)
 	ctx.Step(`fills in (their|her|his) password`, th.fillsInPassword)
+	ctx.Step(`checks "Keep me signed in"`, th.checksKeepMeSignedIn)
 	ctx.Step(`submits the Login form`, th.submitsLoginForm)
+	ctx.Step(`(?:her|his|their) session cookie lasts (\d+) days`, th.sessionCookieLastsDays)
+	ctx.Step(`(?:her|his|their) session cookie ends with the browser session`, th.sessionCookieEndsWithBrowser)
+	ctx.Step(`verifies (her|his|their) email if asked`, th.verifiesEmailInterstitial)
 	ctx.Step(`see an error message.*There is no account with the Username`, th.seesNoAccountErrorMessage)
+	ctx.Step(`the org (does|doesn't) protect against account enumeration`, th.orgEnumerationProtection)
+	ctx.Step(`sees the error for an unknown username`, th.seesNoAccountErrorMessage)
 	ctx.Step(`fills in (their|her|his) incorrect password`, th.fillsInIncorrectPassword)
+	ctx.Step(`sees (?:her|his|their) username kept on the Login form`, th.seesOwnUsernamePreserved)
+	ctx.Step(`sees the page title "([^"]*)"`, th.seesPageTitle)
 	ctx.Step(`see an error message.*Authentication failed`, th.seesAuthFailedErrorMessage)
 	ctx.Step(`clicks on the Forgot Password button`, th.clicksForgotPasswordButton)
 	ctx.Step(`is redirected to the Self Service Password Reset View`, th.isPasswordResetView)
@@ -259,30 +375,53 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 	ctx.Step(`there is a new sign up user named ([^"]*)$`, th.createCurrentProfile)
 	ctx.Step(`user is added to the org ([^"]*) phone number`, th.addUser)
 	ctx.Step(`user is assigned to the group ([^"]*)$`, th.addUserToGroup)
+	ctx.Step(`user's last name is changed to ([^"]*) in the org$`, th.changesFamilyName)
+	ctx.Step(`(?:her|his|their) enrolled factors are reset`, th.resetsUserFactors)
+	ctx.Step(`(?:her|his|their) (Email|SMS|Voice|Phone) factor is enrolled in Okta`, th.userHasEnrolledFactor)
+	ctx.Step(`signs out and back in`, th.signsOutAndBackIn)
 
 	ctx.Step(`navigates to .* Self Service Registration View`, th.navigateToSelfServiceRegistration)
+	ctx.Step(`sees the First Name field has focus`, th.firstNameHasFocus)
 	ctx.Step(`fills (out|in) (their|her|his) First Name`, th.fillsInSignUpFirstName)
 	ctx.Step(`fills (out|in) (their|her|his) Last Name`, th.fillsInSignUpLastName)
 	ctx.Step(`fills (out|in) (their|her|his) Email$`, th.fillsInSignUpEmail)
 	ctx.Step(`fills (out|in) (their|her|his) Email with an invalid email format`, th.fillsInInvalidSignUpEmail)
 	ctx.Step(`sees an error message "([^"]*)"$`, th.seesErrorMessage)
 	ctx.Step(`submits the registration form`, th.submitsRegistrationForm)
+	ctx.Step(`sees an error that (?:her|his|their) (First Name|Last Name|Email) is required`, th.seesFieldError)
+	ctx.Step(`sees an error message that an account already exists`, th.seesAccountAlreadyExistsError)
+	ctx.Step(`sees a link to sign in instead`, th.seesSignInInsteadLink)
 	ctx.Step(`fills (out|in) (their|her|his) Password`, th.fillsInSignUpPassword)
 	ctx.Step(`confirms (their|her|his) Password`, th.fillsInSignUpConfirmPassword)
 	ctx.Step(`submits the set new password form`, th.submitsNewPasswordForm)
+	ctx.Step(`completes the email verification (?:her|his|their) org's policy requires`, th.completesRegistrationForPolicy)
 	ctx.Step(`sees (a|the) list of (optional|required) factors`, th.waitForEnrollFactorForm)
 	ctx.Step(`selects Email`, th.selectsEmail)
 	ctx.Step(`selects Phone`, th.selectsPhone)
 	ctx.Step(`(he|she) selects "Skip"`, th.clicksSkip)
+	ctx.Step(`(he|she) sees the option to skip`, th.seesSkipOption)
+	ctx.Step(`(he|she) doesn't see the option to skip`, th.doesNotSeeSkipOption)
 	ctx.Step(`(he|she) sees a page to input a code`, th.waitForEmailCodeForm)
+	ctx.Step(`sees the code is sent to (?:her|his|their) email`, th.seesOwnEnrollEmailTarget)
 	ctx.Step(`(he|she) inputs the correct code from (her|his) email`, th.fillsInTheEnrollmentCode)
 	ctx.Step(`sees a list of (optional|required) factors`, th.waitForEnrollFactorForm)
 	ctx.Step(`is redirected to the Root View`, th.isRootView)
+	ctx.Step(`(?:her|his|their) sign up session expires`, th.expiresFlowSession)
+	ctx.Step(`sees (?:her|his|their) session has expired`, th.seesFlowExpired)
+	ctx.Step(`clicks "Start over"`, th.clicksStartOver)
 	ctx.Step(`(he|she) sees a table with (her|his) profile info`, th.noop)
 	ctx.Step(`the cell for the value of "([^"]*)" is shown`, th.seesClaimsTableItemAndValueFromCurrentProfile)
+	ctx.Step(`(he|she) sees (her|his) phone number's country selected`, th.seesDefaultPhoneCountryFromProfile)
+	ctx.Step(`the app offers the phone countries "([^"]*)"`, th.setsPhoneCountries)
+	ctx.Step(`sees the country options "([^"]*)"`, th.seesCountryOptionsList)
+	ctx.Step(`sees the phone country "([^"]*)" selected`, th.seesDefaultPhoneCountry)
 	ctx.Step(`(he|she) inputs a valid phone number`, th.fillsInTheEnrollmentPhone)
 	ctx.Step(`(he|she) inputs an invalid phone number`, th.fillsInInvalidEnrollmentPhone)
 	ctx.Step(`(he|she) selects "Receive a Code"`, th.fillsInReceiveSMSCode)
+	ctx.Step(`selects the Voice method`, th.selectsVoiceMethod)
+	ctx.Step(`fills in the phone extension "([^"]*)"`, th.fillsInPhoneExtension)
+	ctx.Step(`selects "Receive a Call"`, th.submitsPhoneMethod)
+	ctx.Step(`inputs the correct code from (?:her|his|their) voice call`, th.fillsInTheEnrollmentCodeVoice)
 	ctx.Step(`the screen changes to receive an input for a code`, th.waitForEnrollPhoneForm)
 	ctx.Step(`(he|she) inputs the correct code from (her|his) SMS`, th.fillsInTheEnrollmentCodeSMS)
 	ctx.Step(`(he|she) selects "Verify"`, th.clicksVerifySMSCode)
@@ -293,15 +432,33 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 	ctx.Step(`submits the recovery form`, th.submitsTheRecoveryForm)
 	ctx.Step(`sees a page to input the code`, th.waitForEmailCodeForm)
 	ctx.Step(`fills in the correct code`, th.fillsInTheCorrectCode)
+	ctx.Step(`waits for (?:her|his|their) recovery code to expire`, th.waitsForRecoveryCodeToExpire)
+	ctx.Step(`sees an error that (?:her|his|their) code has expired`, th.seesExpiredCodeError)
 	ctx.Step(`submits the code form`, th.submitsTheCodeForm)
 	ctx.Step(`sees a page to set new password`, th.seesPageToSetNewPassword)
 	ctx.Step(`fills a password that fits within the password policy`, th.fillsPassword)
 	ctx.Step(`she submits new password form`, th.submitsNewPassword)
+	ctx.Step(`sees a message that (her|his) password was reset`, th.seesPasswordResetSuccess)
+	ctx.Step(`changes (her|his) password with a wrong current password`, th.changesPasswordWithWrongCurrentPassword)
+	ctx.Step(`changes (her|his) password$`, th.changesPassword)
+	ctx.Step(`sees a message that (her|his) password was changed`, th.seesPasswordChangeSuccess)
+	ctx.Step(`sees an error that (her|his) current password is incorrect`, th.seesWrongCurrentPasswordError)
 	ctx.Step(`inputs incorrect Email`, th.inputsIncorrectEmail)
+	ctx.Step(`sees the error for an email without an account`, th.seesRecoveryNoAccountError)
+	ctx.Step(`is told the code was sent only if (?:her|his|their) account exists`, th.seesRecoveryAccountNotice)
+	ctx.Step(`isn't told the code was sent only if (?:her|his|their) account exists`, th.doesntSeeRecoveryAccountNotice)
 	ctx.Step(`^she sees a message "([^"]*)"$`, th.seesErrorMessage)
 
 	ctx.Step(`fills in the incorrect code`, th.fillsInTheIncorrectCode)
+	ctx.Step(`enters a wrong code (\d+) times`, th.entersWrongCodeRepeatedly)
+	ctx.Step(`sees an error that there were too many attempts`, th.seesTooManyAttemptsError)
 	ctx.Step(`sees a list of factors`, th.factorList)
+	ctx.Step(`the app auto-selects the only authenticator offered`, th.enablesAutoSelectAuthenticator)
+	ctx.Step(`is taken straight to the (Email|Phone) challenge`, th.isTakenStraightToChallenge)
+	ctx.Step(`sees the authenticators in order "([^"]*)"`, th.seesAuthenticatorsListedInOrder)
+	ctx.Step(`sees (\d+) authenticator options?$`, th.seesAuthenticatorOptionCount)
+	ctx.Step(`sees "([^"]*)" preselected`, th.seesPreselectedAuthenticator)
+	ctx.Step(`switches to the "([^"]*)" authenticator`, th.switchesAuthenticator)
 
 	ctx.Step(`sees form with method and phone number$`, th.seesPhoneWithMethod)
 	ctx.Step(`sees form with method$`, th.seesMethod)
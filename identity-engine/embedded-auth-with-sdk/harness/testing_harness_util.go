@@ -31,14 +31,22 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cucumber/godog"
 	"github.com/tebeka/selenium"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/server"
 )
 
 const (
-	ERROR_DIV = `div[class="mx-auto py-4 px-2 my-2 w-full border-2 border-red-400 bg-red-100"]`
+	ERROR_DIV   = `div[class="mx-auto py-4 px-2 my-2 w-full border-2 border-red-400 bg-red-100"]`
+	SUCCESS_DIV = `div[id="success"]`
 )
 
 func a18nApiURL() string {
+	if a18nStubServer != nil {
+		return a18nStubServer.URL
+	}
 	url := os.Getenv("A18N_API_URL")
 	if url == "" {
 		url = "https://api.a18n.help"
@@ -54,6 +62,15 @@ func defaultInterval() time.Duration {
 	return time.Duration(time.Second * 3)
 }
 
+func defaultViewport() string {
+	// SELENIUM_VIEWPORT is either "maximize" or WIDTHxHEIGHT e.g. 1440x900
+	viewport := os.Getenv("SELENIUM_VIEWPORT")
+	if viewport == "" {
+		viewport = "1440x900"
+	}
+	return viewport
+}
+
 func claims() map[string]string {
 	claimsJSON := os.Getenv("OKTA_IDX_CLAIMS")
 	claims := map[string]string{}
@@ -70,6 +87,27 @@ func claimItem(key string) string {
 	return value
 }
 
+// expectedWelcome is the greeting the home page shows the signed in user,
+// built from the same welcome claim and text as the server's.
+func expectedWelcome(cfg *config.Config) string {
+	return config.WelcomeText(claimItem(cfg.WelcomeClaimKey()))
+}
+
+// profileDisplayName decorates a scenario's profile name with the optional
+// A18N_PROFILE_PREFIX and A18N_PROFILE_SUFFIX, e.g. a CI run id, so the A18N
+// profiles of concurrent runs don't collide and are easy to identify.
+func profileDisplayName(name string) string {
+	parts := []string{}
+	if prefix := os.Getenv("A18N_PROFILE_PREFIX"); prefix != "" {
+		parts = append(parts, prefix)
+	}
+	parts = append(parts, name)
+	if suffix := os.Getenv("A18N_PROFILE_SUFFIX"); suffix != "" {
+		parts = append(parts, suffix)
+	}
+	return strings.Join(parts, " ")
+}
+
 func randomString() string {
 	// Password requirements: at least 8 characters, a lowercase letter, an uppercase letter, a number, no parts of your username
 	digits := "0123456789"
@@ -121,6 +159,19 @@ func (th *TestHarness) navigateToSelfServiceRegistration() error {
 	return th.waitForPageRender()
 }
 
+// seesFlowExpired checks the user was told their flow expired and offered a
+// way to start over.
+func (th *TestHarness) seesFlowExpired() error {
+	if err := th.seesElement(`#flow-expired`); err != nil {
+		return err
+	}
+	return th.seesElement(`a#restart-flow[href="/flowExpired/restart"]`)
+}
+
+func (th *TestHarness) clicksStartOver() error {
+	return th.clickLink("Start over")
+}
+
 func (th *TestHarness) isRootView() error {
 	return th.isView(fmt.Sprintf("http://%s/", th.server.Address()))
 }
@@ -187,6 +238,33 @@ func (th *TestHarness) checkEntryPoints() error {
 	return nil
 }
 
+// entryPointLeadsTo clicks the entry point link on the Root View and checks
+// the form it leads to renders, not just that the link points at it.
+func (th *TestHarness) entryPointLeadsTo(text string, waitForForm waitFor) error {
+	if err := th.navigateToTheRootView(); err != nil {
+		return err
+	}
+	if err := th.clickLink(text); err != nil {
+		return err
+	}
+	if err := waitForForm(); err != nil {
+		return fmt.Errorf("the %q link didn't lead to its form: %w", text, err)
+	}
+	return nil
+}
+
+func (th *TestHarness) signInLinkLeadsToLoginForm() error {
+	return th.entryPointLeadsTo("Sign In", th.waitForLoginForm)
+}
+
+func (th *TestHarness) signUpLinkLeadsToRegistrationForm() error {
+	return th.entryPointLeadsTo("Sign Up", th.waitForRegistrationForm)
+}
+
+func (th *TestHarness) passwordRecoveryLinkLeadsToRecoveryForm() error {
+	return th.entryPointLeadsTo("Password Recovery", th.waitForPasswordRecoveryForm)
+}
+
 func (th *TestHarness) waitForLoginForm() error {
 	return th.seesElement(`form[action="/login"]`)
 }
@@ -211,6 +289,49 @@ func (th *TestHarness) waitForEmailCodeForm() error {
 	return th.seesElement(`input[id="code"]`)
 }
 
+// shownEmailMatches reports whether an address shown on a page is email,
+// either in full or masked, e.g. m***y@example.com, with the same first
+// letter and domain.
+func shownEmailMatches(shown, email string) bool {
+	if strings.EqualFold(shown, email) {
+		return true
+	}
+	at, shownAt := strings.LastIndex(email, "@"), strings.LastIndex(shown, "@")
+	if at < 1 || shownAt < 1 || !strings.Contains(shown[:shownAt], "*") {
+		return false
+	}
+	return strings.EqualFold(shown[shownAt:], email[at:]) && strings.EqualFold(shown[:1], email[:1])
+}
+
+// seesEnrollEmailTarget checks the email enrollment form names the address
+// the code is sent to, before waiting on that address for the code.
+func (th *TestHarness) seesEnrollEmailTarget(email string) error {
+	var shown string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elem, err := th.wd.FindElement(selenium.ByCSSSelector, `#enroll-email-target`)
+		if err != nil {
+			return false, nil
+		}
+		shown, err = elem.Text()
+		return err == nil && shown != "", nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected the form to show where the code was sent: %v", err)
+	}
+	if !shownEmailMatches(strings.TrimSpace(shown), email) {
+		return fmt.Errorf("expected the code to be sent to %s, the form shows %s", email, shown)
+	}
+	return nil
+}
+
+func (th *TestHarness) seesOwnEnrollEmailTarget() error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	return th.seesEnrollEmailTarget(profile.EmailAddress)
+}
+
 func (th *TestHarness) waitForEnrollPhoneForm() error {
 	return th.seesElement(`input[id="code"]`)
 }
@@ -220,13 +341,17 @@ func (th *TestHarness) waitForEnrollPhoneMethodForm() error {
 }
 
 func (th *TestHarness) seesClaimsTableItemAndValueFromCurrentProfile(key string) error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
 	keyID := fmt.Sprintf("%s-value", key)
 	var value string
 	switch {
 	case key == "name":
-		value = th.currentProfile.DisplayName
+		value = profile.DisplayName
 	case key == "email":
-		value = th.currentProfile.EmailAddress
+		value = profile.EmailAddress
 	}
 
 	return th.seesElementIDWithValue(keyID, value)
@@ -262,12 +387,54 @@ func (th *TestHarness) loginToApplication() error {
 		return err
 	}
 
-	text := fmt.Sprintf("Welcome, %s.", claimItem("name"))
+	text := expectedWelcome(th.server.Config())
 	return th.seesElementWithText(`html body h1`, text)
 }
 
+func (th *TestHarness) signsOutAndBackIn() error {
+	if err := th.clicksLogoutButton(); err != nil {
+		return err
+	}
+	if err := th.isLoggedOut(); err != nil {
+		return err
+	}
+	if err := th.navigateToBasicLogin(); err != nil {
+		return err
+	}
+	if err := th.fillsInUsername(); err != nil {
+		return err
+	}
+	if err := th.fillsInPassword(); err != nil {
+		return err
+	}
+	if err := th.submitsLoginForm(); err != nil {
+		return err
+	}
+	// load the root view again so the claims come from a fresh /userinfo
+	// call made with the new access token
+	return th.navigateToTheRootView()
+}
+
 type waitFor func() error
 
+// waitForAnyOf waits until an element matching one of the selectors is on the
+// page and returns the first selector found.
+func (th *TestHarness) waitForAnyOf(selectors ...string) (string, error) {
+	var seen string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		for _, selector := range selectors {
+			if _, err := th.wd.FindElement(selenium.ByCSSSelector, selector); err == nil {
+				seen = selector
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}, defaultTimeout(), defaultInterval())
+
+	return seen, err
+}
+
 func (th *TestHarness) fillsInFormValue(selector, value string, waitForForm waitFor) error {
 	if err := waitForForm(); err != nil {
 		return err
@@ -310,70 +477,112 @@ func (th *TestHarness) existingUser() error {
 }
 
 func (th *TestHarness) fillsInUsername() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="identifier"]`, th.currentProfile.EmailAddress, th.waitForLoginForm)
+	return th.fillsInFormValue(`input[name="identifier"]`, profile.EmailAddress, th.waitForLoginForm)
 }
 
 func (th *TestHarness) fillsInIncorrectUsername() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="identifier"]`, "TYPO"+th.currentProfile.EmailAddress, th.waitForLoginForm)
+	return th.fillsInFormValue(`input[name="identifier"]`, "TYPO"+profile.EmailAddress, th.waitForLoginForm)
 }
 
 func (th *TestHarness) fillsInPassword() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="password"]`, th.currentProfile.Password, th.waitForLoginForm)
+	return th.fillsInFormValue(`input[name="password"]`, profile.Password, th.waitForLoginForm)
 }
 
 func (th *TestHarness) fillsInIncorrectPassword() error {
 	return th.fillsInFormValue(`input[name="password"]`, "wrong password", th.waitForLoginForm)
 }
 
+// seesPreservedUsername checks the login form came back after a failed sign
+// in with the username filled in and the password empty.
+func (th *TestHarness) seesPreservedUsername(value string) error {
+	if err := th.waitForLoginForm(); err != nil {
+		return err
+	}
+	fields := map[string]string{
+		`input[name="identifier"]`: value,
+		`input[name="password"]`:   "",
+	}
+	for selector, want := range fields {
+		elem, err := th.wd.FindElement(selenium.ByCSSSelector, selector)
+		if err != nil {
+			return err
+		}
+		got, err := elem.GetAttribute("value")
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("expected %s to hold %q, found %q", selector, want, got)
+		}
+	}
+	return nil
+}
+
+func (th *TestHarness) seesOwnUsernamePreserved() error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	return th.seesPreservedUsername(profile.EmailAddress)
+}
+
 func (th *TestHarness) fillsInSignUpFirstName() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="firstName"]`, th.currentProfile.GivenName, th.waitForRegistrationForm)
+	return th.fillsInFormValue(`input[name="firstName"]`, profile.GivenName, th.waitForRegistrationForm)
 }
 
 func (th *TestHarness) fillsInSignUpLastName() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="lastName"]`, th.currentProfile.FamilyName, th.waitForRegistrationForm)
+	return th.fillsInFormValue(`input[name="lastName"]`, profile.FamilyName, th.waitForRegistrationForm)
 }
 
 func (th *TestHarness) fillsInSignUpEmail() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="email"]`, th.currentProfile.EmailAddress, th.waitForRegistrationForm)
+	return th.fillsInFormValue(`input[name="email"]`, profile.EmailAddress, th.waitForRegistrationForm)
 }
 
 func (th *TestHarness) fillsInInvalidSignUpEmail() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	if _, err := th.mustCurrentProfile(); err != nil {
+		return err
 	}
 	return th.fillsInFormValue(`input[name="email"]`, "invalid-email-address-dot-com", th.waitForRegistrationForm)
 }
 
 func (th *TestHarness) fillsInSignUpPassword() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="newPassword"]`, th.currentProfile.Password, th.waitForEnrollPasswordForm)
+	return th.fillsInFormValue(`input[name="newPassword"]`, profile.Password, th.waitForEnrollPasswordForm)
 }
 
 func (th *TestHarness) fillsInSignUpConfirmPassword() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="confirmPassword"]`, th.currentProfile.Password, th.waitForEnrollPasswordForm)
+	return th.fillsInFormValue(`input[name="confirmPassword"]`, profile.Password, th.waitForEnrollPasswordForm)
 }
 
 func (th *TestHarness) submitsNewPasswordForm() error {
@@ -407,21 +616,34 @@ func (th *TestHarness) seesAuthFailedErrorMessage() error {
 }
 
 func (th *TestHarness) seesNoAccountErrorMessage() error {
-	return th.matchErrorMessage("There is no account with the Username")
+	return th.matchErrorMessage(noAccountError())
+}
+
+func (th *TestHarness) seesAccountAlreadyExistsError() error {
+	return th.matchErrorMessage("already exists")
+}
+
+func (th *TestHarness) seesSignInInsteadLink() error {
+	return th.seesElementWithText(`a[href="/login"]`, "Sign in instead")
 }
 
 func (th *TestHarness) seesErrorMessage(message string) error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
 	if strings.Contains(message, "is no account") {
-		message += " " + strings.ReplaceAll(th.currentProfile.EmailAddress, "@", "+1@") + "."
+		if enumerationProtected() {
+			message = noAccountError()
+		} else {
+			message += " " + strings.ReplaceAll(profile.EmailAddress, "@", "+1@") + "."
+		}
 	}
 	return th.matchErrorMessage(message)
 }
 
 func (th *TestHarness) isLoggedOut() error {
-	text := fmt.Sprintf("Welcome, %s.", claimItem("name"))
+	text := expectedWelcome(th.server.Config())
 	return th.doesNotSeeElementWithText(`html body h1`, text)
 }
 
@@ -444,6 +666,39 @@ func (th *TestHarness) seesClaimsTable() error {
 	return nil
 }
 
+// seesClaimLabel checks the claims table shows the friendly label for a
+// claim. The raw key stays available to seesClaimsTable.
+func (th *TestHarness) seesClaimLabel(key, label string) error {
+	return th.seesElementIDWithValue(fmt.Sprintf("%s-label", key), label)
+}
+
+// seesGroup checks the profile page lists the group among the user's groups.
+func (th *TestHarness) seesGroup(name string) error {
+	var listed []string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elems, err := th.wd.FindElements(selenium.ByCSSSelector, "#groups .group-name")
+		if err != nil {
+			return false, nil
+		}
+		listed = listed[:0]
+		for _, elem := range elems {
+			text, err := elem.Text()
+			if err != nil {
+				return false, nil
+			}
+			if strings.TrimSpace(text) == name {
+				return true, nil
+			}
+			listed = append(listed, strings.TrimSpace(text))
+		}
+		return false, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected the group %q on the profile page, found %q: %w", name, listed, err)
+	}
+	return nil
+}
+
 func (th *TestHarness) doesntSeeClaimsTable() error {
 	claims := claims()
 
@@ -487,6 +742,90 @@ func (th *TestHarness) seesElement(selector string) error {
 	return err
 }
 
+// seesPageTitle checks the browser's title bar names the current page, e.g.
+// "Login | Okta Golang Direct Auth Samples".
+func (th *TestHarness) seesPageTitle(expected string) error {
+	var title string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		var err error
+		if title, err = th.wd.Title(); err != nil {
+			return false, nil
+		}
+		return strings.HasPrefix(title, expected+" | "), nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected the page title to be %q, found %q", expected, title)
+	}
+	return nil
+}
+
+// doesNotSeeElement checks nothing on the rendered page matches selector.
+func (th *TestHarness) doesNotSeeElement(selector string) error {
+	elems, err := th.wd.FindElements(selenium.ByCSSSelector, selector)
+	if err != nil {
+		return err
+	}
+	if len(elems) != 0 {
+		return fmt.Errorf("didn't expect to find %s in page but found %d elems", selector, len(elems))
+	}
+	return nil
+}
+
+// seesElementCount waits for exactly n elements to match selector.
+func (th *TestHarness) seesElementCount(selector string, n int) error {
+	var count int
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elems, err := th.wd.FindElements(selenium.ByCSSSelector, selector)
+		if err != nil {
+			return false, nil
+		}
+		count = len(elems)
+		return count == n, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected %d elements matching %s but found %d", n, selector, count)
+	}
+	return nil
+}
+
+// fieldHasFocus waits for the element matched by selector to be the page's
+// active element. Elements are compared by their WebDriver references.
+func (th *TestHarness) fieldHasFocus(selector string) error {
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elem, err := th.wd.FindElement(selenium.ByCSSSelector, selector)
+		if err != nil {
+			return false, nil
+		}
+		active, err := th.wd.ActiveElement()
+		if err != nil {
+			return false, nil
+		}
+
+		elemRef, err := json.Marshal(elem)
+		if err != nil {
+			return false, err
+		}
+		activeRef, err := json.Marshal(active)
+		if err != nil {
+			return false, err
+		}
+
+		return bytes.Equal(elemRef, activeRef), nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected %s to have focus: %v", selector, err)
+	}
+	return nil
+}
+
+func (th *TestHarness) identifierHasFocus() error {
+	return th.fieldHasFocus(`input[name="identifier"]`)
+}
+
+func (th *TestHarness) firstNameHasFocus() error {
+	return th.fieldHasFocus(`input[name="firstName"]`)
+}
+
 func (th *TestHarness) clickLink(text string) error {
 	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
 		elem, err := th.wd.FindElement(selenium.ByLinkText, text)
@@ -525,6 +864,44 @@ func (th *TestHarness) entersText(selector, text string) error {
 	return err
 }
 
+// selectsDropdownOption picks the option of the native <select> matched by
+// selector whose visible text is visibleText, e.g. a phone country code.
+func (th *TestHarness) selectsDropdownOption(selector, visibleText string) error {
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elem, err := th.wd.FindElement(selenium.ByCSSSelector, selector)
+		if err != nil {
+			return false, nil
+		}
+
+		options, err := elem.FindElements(selenium.ByTagName, "option")
+		if err != nil {
+			return false, nil
+		}
+
+		for _, option := range options {
+			text, err := option.Text()
+			if err != nil {
+				return false, nil
+			}
+			if strings.TrimSpace(text) != visibleText {
+				continue
+			}
+			if err = option.Click(); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+
+		return false, nil
+	}, defaultTimeout(), defaultInterval())
+
+	return err
+}
+
+func (th *TestHarness) selectsFromDropdown(visibleText, name string) error {
+	return th.selectsDropdownOption(fmt.Sprintf(`select[name=%q]`, name), visibleText)
+}
+
 func (th *TestHarness) seesElementWithText(selector, text string) error {
 	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
 		elem, err := th.wd.FindElement(selenium.ByCSSSelector, selector)
@@ -693,15 +1070,16 @@ func (th *TestHarness) navigatesToThePasswordRecoveryView() error {
 }
 
 func (th *TestHarness) inputsCorrectEmail() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
 
 	if err := th.waitForPasswordRecoveryForm(); err != nil {
 		return err
 	}
 
-	return th.entersText(`input[name="identifier"]`, th.currentProfile.EmailAddress)
+	return th.entersText(`input[name="identifier"]`, profile.EmailAddress)
 }
 
 func (th *TestHarness) submitsForm(selector, text string) error {
@@ -712,6 +1090,38 @@ func (th *TestHarness) submitsLoginForm() error {
 	return th.submitsForm(`button[type="submit"]`, "Login")
 }
 
+func (th *TestHarness) checksKeepMeSignedIn() error {
+	return th.clicksFormCheckItem(`input[name="keep_signed_in"]`, th.waitForLoginForm)
+}
+
+// sessionCookieLastsDays checks the session cookie expires about the given
+// number of days from now, as it does when "keep me signed in" is checked.
+func (th *TestHarness) sessionCookieLastsDays(days int) error {
+	cookie, err := th.wd.GetCookie("direct-auth")
+	if err != nil {
+		return err
+	}
+	expected := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	expiry := time.Unix(int64(cookie.Expiry), 0)
+	if cookie.Expiry == 0 || expiry.Before(expected.Add(-time.Hour)) || expiry.After(expected.Add(time.Hour)) {
+		return fmt.Errorf("expected the session cookie to expire around %s, it expires %s", expected, expiry)
+	}
+	return nil
+}
+
+// sessionCookieEndsWithBrowser checks the session cookie has no expiry, so
+// it is dropped when the browser closes.
+func (th *TestHarness) sessionCookieEndsWithBrowser() error {
+	cookie, err := th.wd.GetCookie("direct-auth")
+	if err != nil {
+		return err
+	}
+	if cookie.Expiry != 0 {
+		return fmt.Errorf("expected a browser session cookie, it expires %s", time.Unix(int64(cookie.Expiry), 0))
+	}
+	return nil
+}
+
 func (th *TestHarness) submitsTheRecoveryForm() error {
 	return th.submitsForm(`button[type="submit"]`, "Submit")
 }
@@ -720,6 +1130,21 @@ func (th *TestHarness) submitsRegistrationForm() error {
 	return th.submitsForm(`button[type="submit"]`, "Register")
 }
 
+// seesFieldError checks the registration form shows the required-field
+// message under the field with the given label.
+func (th *TestHarness) seesFieldError(label string) error {
+	fields := map[string]string{
+		"First Name": "firstName",
+		"Last Name":  "lastName",
+		"Email":      "email",
+	}
+	field, ok := fields[label]
+	if !ok {
+		return fmt.Errorf("unknown registration field %q", label)
+	}
+	return th.seesElementWithText(fmt.Sprintf("#%s-error", field), "This field cannot be left blank")
+}
+
 func (th *TestHarness) submitsTheCodeForm() error {
 	return th.submitsForm(`button[type="submit"]`, "Submit")
 }
@@ -728,35 +1153,271 @@ func (th *TestHarness) submitsNewPassword() error {
 	return th.submitsForm(`button[type="submit"]`, "Submit")
 }
 
+// seesPasswordResetSuccess checks for the confirmation shown once a new
+// password is set. PASSWORD_RESET_SUCCESS_TEXT overrides the expected text
+// for localized apps.
+func (th *TestHarness) seesPasswordResetSuccess() error {
+	text := os.Getenv("PASSWORD_RESET_SUCCESS_TEXT")
+	if text == "" {
+		text = "Your password has been reset."
+	}
+	return th.seesElementWithText(SUCCESS_DIV, text)
+}
+
+// changesPasswordWhileAuthenticated submits the current password, the code
+// sent to their email when Okta asks the user to re-authenticate, and then
+// the new password.
+func (th *TestHarness) changesPasswordWhileAuthenticated(oldPassword, newPassword string) error {
+	settingsURL := fmt.Sprintf("http://%s/settings/password", th.server.Address())
+	if err := th.wd.Get(settingsURL); err != nil {
+		return err
+	}
+	if err := th.seesElement(`form[action="/settings/password"]`); err != nil {
+		return err
+	}
+	if err := th.entersText(`input[name="currentPassword"]`, oldPassword); err != nil {
+		return err
+	}
+	if err := th.submitsForm(`button[type="submit"]`, "Submit"); err != nil {
+		return err
+	}
+
+	seen, err := th.waitForAnyOf(`form[action="/settings/password/code"]`, `form[action="/settings/password/newPassword"]`, ERROR_DIV)
+	if err != nil {
+		return err
+	}
+	if seen == ERROR_DIV {
+		return nil
+	}
+	if seen == `form[action="/settings/password/code"]` {
+		if err := th.fillsInTheCorrectCode(); err != nil {
+			return err
+		}
+		if err := th.submitsTheCodeForm(); err != nil {
+			return err
+		}
+		if err := th.seesElement(`form[action="/settings/password/newPassword"]`); err != nil {
+			return err
+		}
+	}
+
+	if err := th.entersText(`input[name="newPassword"]`, newPassword); err != nil {
+		return err
+	}
+	if err := th.entersText(`input[name="confirmPassword"]`, newPassword); err != nil {
+		return err
+	}
+	return th.submitsForm(`button[type="submit"]`, "Submit")
+}
+
+func (th *TestHarness) changesPassword() error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	newPassword := th.generatePassword()
+	if err = th.changesPasswordWhileAuthenticated(profile.Password, newPassword); err != nil {
+		return err
+	}
+	profile.Password = newPassword
+	return nil
+}
+
+func (th *TestHarness) changesPasswordWithWrongCurrentPassword() error {
+	return th.changesPasswordWhileAuthenticated(randomString(), th.generatePassword())
+}
+
+func (th *TestHarness) seesPasswordChangeSuccess() error {
+	return th.seesElementWithText(SUCCESS_DIV, "Your password has been changed.")
+}
+
+func (th *TestHarness) seesWrongCurrentPasswordError() error {
+	return th.seesElementWithText(ERROR_DIV, "Your current password is incorrect")
+}
+
 func (th *TestHarness) seesPageToInputTheCode() error {
 	return th.seesElement(`form[action="/passwordRecovery/code"]`)
 }
 
 func (th *TestHarness) fillsInTheCorrectCode() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	code, err := th.verificationCode(th.currentProfile.URL, EMAIL_CODE_TYPE)
+	code, err := th.verificationCode(profile.URL, EMAIL_CODE_TYPE)
 	if err != nil {
-		return fmt.Errorf("faild to find latest verification code for user %s: %v", th.currentProfile.EmailAddress, err)
+		return fmt.Errorf("faild to find latest verification code for user %s: %v", profile.EmailAddress, err)
+	}
+	if err = checkVerificationCode(code); err != nil {
+		return fmt.Errorf("user %s: %w", profile.EmailAddress, err)
 	}
 	return th.entersText(`input[name="code"]`, code)
 }
 
+// verifiesEmailInterstitial completes the email verification some sign-on
+// policies ask of existing users before they're signed in. It's a no-op when
+// the user is signed in straight away.
+func (th *TestHarness) verifiesEmailInterstitial() error {
+	seen, err := th.waitForAnyOf(`form[action="/login/factors/email"]`, `form[action="/logout"]`)
+	if err != nil {
+		return err
+	}
+	if seen != `form[action="/login/factors/email"]` {
+		return nil
+	}
+
+	if err := th.fillsInTheCorrectCode(); err != nil {
+		return err
+	}
+	return th.submitsTheCodeForm()
+}
+
 func (th *TestHarness) fillsInTheIncorrectCode() error {
 	return th.entersText(`input[name="code"]`, randomString())
 }
 
+// entersWrongCodeRepeatedly submits n wrong verification codes in a row,
+// waiting for the code form to come back after each one.
+func (th *TestHarness) entersWrongCodeRepeatedly(n int) error {
+	for i := 0; i < n; i++ {
+		if err := th.fillsInTheIncorrectCode(); err != nil {
+			return fmt.Errorf("attempt %d: %w", i+1, err)
+		}
+		if err := th.submitsTheCodeForm(); err != nil {
+			return fmt.Errorf("attempt %d: %w", i+1, err)
+		}
+		if err := th.seesElement(ERROR_DIV); err != nil {
+			return fmt.Errorf("attempt %d: expected an error: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// seesTooManyAttemptsError checks a sign in that ran out of verification
+// attempts was sent back to the login form to start over.
+func (th *TestHarness) seesTooManyAttemptsError() error {
+	if err := th.waitForLoginForm(); err != nil {
+		return err
+	}
+	return th.matchErrorMessage("Too many attempts. Sign in again to get a new code.")
+}
+
 func (th *TestHarness) factorList() error {
 	return th.seesElement(`form[action="/login/factors/proceed"]`)
 }
 
+// enablesAutoSelectAuthenticator lets the app skip the list of
+// authenticators when only one is offered, for the rest of the scenario.
+func (th *TestHarness) enablesAutoSelectAuthenticator() error {
+	th.server.UpdateConfig(func(c *config.Config) {
+		c.AutoSelectAuthenticator = true
+	})
+	return nil
+}
+
+// challengeViews are where the challenge of each authenticator starts and
+// the form shown there.
+var challengeViews = map[string]struct{ path, form string }{
+	"Email": {"/login/factors/email", `input[id="code"]`},
+	"Phone": {"/login/factors/phone/method", `form[action="/login/factors/phone"]`},
+}
+
+// isTakenStraightToChallenge checks signing in landed on the challenge of
+// the named authenticator without the list of authenticators in between.
+func (th *TestHarness) isTakenStraightToChallenge(name string) error {
+	view, ok := challengeViews[name]
+	if !ok {
+		return fmt.Errorf("unknown authenticator %q", name)
+	}
+	if err := th.seesElement(`form[action="/login/factors/proceed"], ` + view.form); err != nil {
+		return err
+	}
+	if _, err := th.wd.FindElement(selenium.ByCSSSelector, `form[action="/login/factors/proceed"]`); err == nil {
+		return fmt.Errorf("expected to skip the list of authenticators for %s", name)
+	}
+	return th.isView(fmt.Sprintf("http://%s%s", th.server.Address(), view.path))
+}
+
+// seesAuthenticatorsInOrder asserts the factor options are rendered in
+// exactly the given order, i.e. the order the sign-on policy prioritizes.
+func (th *TestHarness) seesAuthenticatorsInOrder(names ...string) error {
+	var observed []string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		labels, err := th.wd.FindElements(selenium.ByCSSSelector, `input[name="push_factor"] + label`)
+		if err != nil {
+			return false, nil
+		}
+		observed = observed[:0]
+		for _, label := range labels {
+			text, err := label.Text()
+			if err != nil {
+				return false, nil
+			}
+			observed = append(observed, strings.TrimSpace(text))
+		}
+		return strings.Join(observed, ", ") == strings.Join(names, ", "), nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected authenticators in order [%s] but saw [%s]", strings.Join(names, ", "), strings.Join(observed, ", "))
+	}
+	return nil
+}
+
+// seesPreselectedAuthenticator checks the verification form opens with the
+// named authenticator's radio already checked.
+func (th *TestHarness) seesPreselectedAuthenticator(name string) error {
+	var checked []string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		radios, err := th.wd.FindElements(selenium.ByCSSSelector, `input[name="push_factor"]`)
+		if err != nil {
+			return false, nil
+		}
+		checked = checked[:0]
+		for _, radio := range radios {
+			selected, err := radio.IsSelected()
+			if err != nil || !selected {
+				continue
+			}
+			id, err := radio.GetAttribute("id")
+			if err != nil {
+				return false, nil
+			}
+			label, err := th.wd.FindElement(selenium.ByCSSSelector, fmt.Sprintf(`label[for=%q]`, id))
+			if err != nil {
+				return false, nil
+			}
+			text, err := label.Text()
+			if err != nil {
+				return false, nil
+			}
+			checked = append(checked, strings.TrimSpace(text))
+		}
+		return len(checked) == 1 && checked[0] == name, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected %q to be preselected but saw [%s]", name, strings.Join(checked, ", "))
+	}
+	return nil
+}
+
+func (th *TestHarness) seesAuthenticatorOptionCount(n int) error {
+	return th.seesElementCount(`form[action="/enrollFactor"] input[name="push_factor"]`, n)
+}
+
+func (th *TestHarness) seesAuthenticatorsListedInOrder(list string) error {
+	names := strings.Split(list, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return th.seesAuthenticatorsInOrder(names...)
+}
+
 func (th *TestHarness) seesPageToSetNewPassword() error {
 	return th.seesElement(`form[action="/passwordRecovery/newPassword"]`)
 }
 
 func (th *TestHarness) fillsPassword() error {
-	p := randomString()
+	p := th.generatePassword()
 	if err := th.entersText(`input[name="newPassword"]`, p); err != nil {
 		return err
 	}
@@ -764,10 +1425,11 @@ func (th *TestHarness) fillsPassword() error {
 }
 
 func (th *TestHarness) inputsIncorrectEmail() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.entersText(`input[name="identifier"]`, strings.ReplaceAll(th.currentProfile.EmailAddress, "@", "+1@"))
+	return th.entersText(`input[name="identifier"]`, strings.ReplaceAll(profile.EmailAddress, "@", "+1@"))
 }
 
 func (th *TestHarness) destroyCurrentProfile() error {
@@ -792,6 +1454,15 @@ func (th *TestHarness) createCurrentProfile(name string) error {
 	return err
 }
 
+// mustCurrentProfile returns the profile established by a Given step, or an
+// error if the scenario hasn't set one up.
+func (th *TestHarness) mustCurrentProfile() (*A18NProfile, error) {
+	if th.currentProfile == nil {
+		return nil, errors.New("test harness doesn't have a current profile")
+	}
+	return th.currentProfile, nil
+}
+
 func (th *TestHarness) selectsEmail() error {
 	if err := th.clicksFormCheckItem(`input[id="push_email"]`, th.waitForEnrollFactorForm); err != nil {
 		return err
@@ -806,17 +1477,53 @@ func (th *TestHarness) selectsPhone() error {
 	return th.clicksButtonWithText(`button[type="submit"]`, "Continue")
 }
 
+// switchesAuthenticator leaves the challenge in progress through the "Verify
+// with something else" link and picks the named authenticator instead.
+func (th *TestHarness) switchesAuthenticator(name string) error {
+	if err := th.clickLink("Verify with something else"); err != nil {
+		return fmt.Errorf("expected a link to verify with something else: %w", err)
+	}
+	if err := th.factorList(); err != nil {
+		return err
+	}
+	switch name {
+	case "Email":
+		return th.selectsEmail()
+	case "Phone", "SMS":
+		return th.selectsPhone()
+	}
+	return fmt.Errorf("unknown authenticator %q", name)
+}
+
+func (th *TestHarness) seesSkipOption() error {
+	if err := th.waitForEnrollFactorForm(); err != nil {
+		return err
+	}
+	return th.seesElement(`input[type="submit"][value="Skip"]`)
+}
+
+func (th *TestHarness) doesNotSeeSkipOption() error {
+	if err := th.waitForEnrollFactorForm(); err != nil {
+		return err
+	}
+	return th.doesNotSeeElement(`input[type="submit"][value="Skip"]`)
+}
+
 func (th *TestHarness) clicksSkip() error {
 	return th.clicksInputWithValue(`input[type="submit"]`, "Skip")
 }
 
 func (th *TestHarness) fillsInTheEnrollmentCode() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	code, err := th.verificationCode(th.currentProfile.URL, EMAIL_CODE_TYPE)
+	code, err := th.verificationCode(profile.URL, EMAIL_CODE_TYPE)
 	if err != nil {
-		return fmt.Errorf("faild to find latest verification code for user %s: %v", th.currentProfile.ProfileID, err)
+		return fmt.Errorf("faild to find latest verification code for user %s: %v", profile.ProfileID, err)
+	}
+	if err = checkVerificationCode(code); err != nil {
+		return fmt.Errorf("user %s: %w", profile.ProfileID, err)
 	}
 	if err = th.entersText(`input[name="code"]`, code); err != nil {
 		return err
@@ -825,12 +1532,94 @@ func (th *TestHarness) fillsInTheEnrollmentCode() error {
 }
 
 func (th *TestHarness) fillsInTheEnrollmentPhone() error {
-	if err := th.entersText(`input[name="phoneNumber"]`, th.currentProfile.PhoneNumber); err != nil {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	if err := th.entersText(`input[name="phoneNumber"]`, profile.PhoneNumber); err != nil {
 		return err
 	}
 	return th.clicksButtonWithText(`button[type="submit"]`, "Submit")
 }
 
+// seesDefaultPhoneCountry checks the enroll phone form pre-selects the
+// country with the given dial code, e.g. "+1".
+func (th *TestHarness) seesDefaultPhoneCountry(code string) error {
+	var selected string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elem, err := th.wd.FindElement(selenium.ByCSSSelector, `select[name="phoneCountry"] option:checked`)
+		if err != nil {
+			return false, nil
+		}
+		selected, err = elem.GetAttribute("data-dial-code")
+		if err != nil {
+			return false, nil
+		}
+		return selected == code, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected phone country %s to be selected but found %q", code, selected)
+	}
+	return nil
+}
+
+// setsPhoneCountries restricts and orders the countries the enroll phone
+// form offers, e.g. "GB,FR".
+func (th *TestHarness) setsPhoneCountries(codes string) error {
+	countries, err := config.ParsePhoneCountries(codes)
+	if err != nil {
+		return err
+	}
+	th.server.UpdateConfig(func(c *config.Config) {
+		c.PhoneCountries = countries
+	})
+	return nil
+}
+
+func (th *TestHarness) seesCountryOptionsList(codes string) error {
+	return th.seesCountryOptions(strings.Split(codes, ",")...)
+}
+
+// seesCountryOptions checks the enroll phone form offers exactly the
+// countries with the given codes, in that order.
+func (th *TestHarness) seesCountryOptions(codes ...string) error {
+	var offered []string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		options, err := th.wd.FindElements(selenium.ByCSSSelector, `select[name="phoneCountry"] option`)
+		if err != nil || len(options) == 0 {
+			return false, nil
+		}
+		offered = offered[:0]
+		for _, option := range options {
+			code, err := option.GetAttribute("value")
+			if err != nil {
+				return false, nil
+			}
+			offered = append(offered, code)
+		}
+		return true, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected the phone country options %v but found none", codes)
+	}
+	if strings.Join(offered, ",") != strings.Join(codes, ",") {
+		return fmt.Errorf("expected the phone country options %v, found %v", codes, offered)
+	}
+	return nil
+}
+
+func (th *TestHarness) seesDefaultPhoneCountryFromProfile() error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	code := server.DialCode(profile.PhoneNumber)
+	if code == "" {
+		return fmt.Errorf("no known dial code for phone number %s", profile.PhoneNumber)
+	}
+	return th.seesDefaultPhoneCountry(code)
+}
+
 func (th *TestHarness) fillsInInvalidEnrollmentPhone() error {
 	if err := th.entersText(`input[name="phoneNumber"]`, "not-a-phone-number"); err != nil {
 		return err
@@ -846,10 +1635,41 @@ func (th *TestHarness) fillsInReceiveSMSCode() error {
 	return th.clicksButtonWithText(`button[type="submit"]`, "Continue")
 }
 
+// selectsVoiceMethod picks the voice call method, which reveals the optional
+// extension field.
+func (th *TestHarness) selectsVoiceMethod() error {
+	if err := th.clicksFormCheckItem(`input[id="voice"]`, th.waitForEnrollPhoneMethodForm); err != nil {
+		return err
+	}
+	return th.seesElement(`#extension-field input[name="extension"]`)
+}
+
+// fillsInPhoneExtension leaves the scenario pending: Okta only takes an E.164
+// number when enrolling a phone, so the sample refuses an extension.
+func (th *TestHarness) fillsInPhoneExtension(ext string) error {
+	return fmt.Errorf("%w: Okta can't dial the extension %q yet", godog.ErrPending, ext)
+}
+
+func (th *TestHarness) submitsPhoneMethod() error {
+	return th.clicksButtonWithText(`button[type="submit"]`, "Continue")
+}
+
 func (th *TestHarness) fillsInTheEnrollmentCodeSMS() error {
-	code, err := th.verificationCode(th.currentProfile.URL, SMS_CODE_TYPE)
+	return th.fillsInThePhoneEnrollmentCode(SMS_CODE_TYPE)
+}
+
+func (th *TestHarness) fillsInTheEnrollmentCodeVoice() error {
+	return th.fillsInThePhoneEnrollmentCode(VOICE_CODE_TYPE)
+}
+
+func (th *TestHarness) fillsInThePhoneEnrollmentCode(codeType string) error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	code, err := th.verificationCode(profile.URL, codeType)
 	if err != nil {
-		return fmt.Errorf("faild to find latest verification code for user %s: %v", th.currentProfile.ProfileID, err)
+		return fmt.Errorf("faild to find latest verification code for user %s: %v", profile.ProfileID, err)
 	}
 	if err = th.entersText(`input[name="code"]`, code); err != nil {
 		return err
@@ -871,7 +1691,11 @@ func (th *TestHarness) seesMethod() error {
 }
 
 func (th *TestHarness) submitsPhoneWithMethod() error {
-	if err := th.entersText(`input[name="phoneNumber"]`, th.currentProfile.PhoneNumber); err != nil {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	if err := th.entersText(`input[name="phoneNumber"]`, profile.PhoneNumber); err != nil {
 		return err
 	}
 	if err := th.clicksFormCheckItem(`input[id="sms"]`, th.waitForEnrollPhoneMethodForm); err != nil {
@@ -897,16 +1721,62 @@ func (th *TestHarness) submitsMethod() error {
 	return th.clicksButtonWithText(`button[type="submit"]`, "Continue")
 }
 
+// waitsForRecoveryCodeToExpire waits out HARNESS_RECOVERY_CODE_LIFETIME,
+// the org's recovery token lifetime, so the code just sent is stale. Without
+// it the scenario is left pending rather than waiting in every run.
+func (th *TestHarness) waitsForRecoveryCodeToExpire() error {
+	lifetime, err := time.ParseDuration(os.Getenv("HARNESS_RECOVERY_CODE_LIFETIME"))
+	if err != nil || lifetime <= 0 {
+		return fmt.Errorf("%w: set HARNESS_RECOVERY_CODE_LIFETIME to wait for the recovery code to expire", godog.ErrPending)
+	}
+	select {
+	case <-time.After(lifetime + 30*time.Second):
+		return nil
+	case <-th.scenarioContext().Done():
+		return th.scenarioContext().Err()
+	}
+}
+
+func (th *TestHarness) seesExpiredCodeError() error {
+	return th.matchErrorMessage("Your code has expired. Request a new one.")
+}
+
+// debugSleep pauses a scenario, e.g. "And sleep 60s", for local debugging.
+// It only sleeps when HARNESS_ALLOW_SLEEP=true so a step left in a scenario
+// doesn't slow down CI.
 func (th *TestHarness) debugSleep(amount string) error {
-	// And sleep 60s
 	d, err := time.ParseDuration(amount)
 	if err != nil {
 		return err
 	}
+	if os.Getenv("HARNESS_ALLOW_SLEEP") != "true" {
+		fmt.Printf("WARNING: skipping sleep %s, set HARNESS_ALLOW_SLEEP=true to allow it\n", d)
+		return nil
+	}
 	time.Sleep(d)
 	return nil
 }
 
+func (th *TestHarness) setsDefaultViewport() error {
+	viewport := defaultViewport()
+	if viewport == "maximize" {
+		return th.maximizesViewport()
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(viewport, "%dx%d", &width, &height); err != nil {
+		return fmt.Errorf("unable to parse viewport %q: %v", viewport, err)
+	}
+	return th.setsViewport(width, height)
+}
+
+func (th *TestHarness) setsViewport(width, height int) error {
+	return th.wd.ResizeWindow("", width, height)
+}
+
+func (th *TestHarness) maximizesViewport() error {
+	return th.wd.MaximizeWindow("")
+}
+
 func (th *TestHarness) clicksVerifySMSCode() error {
 	return th.clicksButtonWithText(`button[type="submit"]`, "Submit")
 }
@@ -919,6 +1789,8 @@ loop:
 		select {
 		case <-timeout:
 			return "", fmt.Errorf("%s didn't receive %s verification code (one minute timeout)", profileURL, codeType)
+		case <-th.scenarioContext().Done():
+			return "", fmt.Errorf("%s didn't receive %s verification code before the scenario timed out", profileURL, codeType)
 		case <-checker:
 			code, err := th.latestVerificationCode(profileURL, codeType)
 			if err != nil {
@@ -935,12 +1807,12 @@ loop:
 func (th *TestHarness) latestVerificationCode(profileURL, codeType string) (string, error) {
 	// codeType: email, sms, voice
 	// e.g. api.a18n.help/v1/profile/nAfBjtIFF3/sms/latest
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s/latest", profileURL, codeType), nil)
+	req, err := http.NewRequestWithContext(th.scenarioContext(), http.MethodGet, fmt.Sprintf("%s/%s/latest", profileURL, codeType), nil)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("x-api-key", os.Getenv("A18N_API_KEY"))
-	resp, err := th.httpClient.Do(req)
+	resp, err := th.doA18N(req)
 	if err != nil {
 		return "", err
 	}
@@ -956,15 +1828,32 @@ func (th *TestHarness) latestVerificationCode(profileURL, codeType string) (stri
 		return "", err
 	}
 	if time.Now().UTC().Sub(content.CreatedAt.UTC()) < time.Second*60 {
-		verificationCodeRegexp := regexp.MustCompile(`[:\s][0-9]{6}`)
-		code := verificationCodeRegexp.FindString(content.Content)
-		if code != "" {
-			return strings.TrimSpace(code), nil
-		}
+		return extractVerificationCode(content.Content), nil
 	}
 	return "", nil
 }
 
+var (
+	verificationCodeInMessage  = regexp.MustCompile(`[:\s][0-9]{6}`)
+	wellFormedVerificationCode = regexp.MustCompile(`^\d{6}$`)
+)
+
+// extractVerificationCode finds the code in an email or SMS message, or ""
+// if there is none.
+func extractVerificationCode(message string) string {
+	code := verificationCodeInMessage.FindString(message)
+	return strings.TrimLeft(code, ": \t\r\n")
+}
+
+// checkVerificationCode fails fast on a code that wasn't extracted properly,
+// before it's entered and rejected as a wrong code.
+func checkVerificationCode(code string) error {
+	if !wellFormedVerificationCode.MatchString(code) {
+		return fmt.Errorf("verification code %q parsed from the message isn't 6 digits", code)
+	}
+	return nil
+}
+
 func (th *TestHarness) deleteProfile(profile *A18NProfile) error {
 	if profile.URL == "" {
 		return nil
@@ -974,7 +1863,7 @@ func (th *TestHarness) deleteProfile(profile *A18NProfile) error {
 		return err
 	}
 	req.Header.Set("x-api-key", os.Getenv("A18N_API_KEY"))
-	resp, err := th.httpClient.Do(req)
+	resp, err := th.doA18N(req)
 	if err != nil {
 		return err
 	}
@@ -983,15 +1872,15 @@ func (th *TestHarness) deleteProfile(profile *A18NProfile) error {
 }
 
 func (th *TestHarness) createProfile(name string) (*A18NProfile, error) {
-	data := fmt.Sprintf("{\"displayName\":%q}", name)
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v1/profile", a18nApiURL()), bytes.NewBufferString(data))
+	data := fmt.Sprintf("{\"displayName\":%q}", profileDisplayName(name))
+	req, err := http.NewRequestWithContext(th.scenarioContext(), http.MethodPost, fmt.Sprintf("%s/v1/profile", a18nApiURL()), bytes.NewBufferString(data))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("x-api-key", os.Getenv("A18N_API_KEY"))
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("Content-Length", strconv.Itoa(len(data)))
-	resp, err := th.httpClient.Do(req)
+	resp, err := th.doA18N(req)
 	if err != nil {
 		return nil, err
 	}
@@ -1009,10 +1898,13 @@ func (th *TestHarness) createProfile(name string) (*A18NProfile, error) {
 		return nil, fmt.Errorf("there was an A18N API error: %s", profile.ErrorDesc)
 	}
 
+	// the org user is named without the A18N prefix/suffix, so the claims
+	// are checked against the undecorated name
+	profile.DisplayName = name
 	givenFamily := strings.Split(name, " ")
 	profile.GivenName = givenFamily[0]
 	profile.FamilyName = givenFamily[1]
-	profile.Password = randomString()
+	profile.Password = th.generatePassword()
 
 	return &profile, nil
 }
@@ -1023,7 +1915,7 @@ func (th *TestHarness) profiles() (*A18NProfiles, error) {
 		return nil, err
 	}
 	req.Header.Set("x-api-key", os.Getenv("A18N_API_KEY"))
-	resp, err := th.httpClient.Do(req)
+	resp, err := th.doA18N(req)
 	if err != nil {
 		return nil, err
 	}
@@ -1040,6 +1932,41 @@ func (th *TestHarness) profiles() (*A18NProfiles, error) {
 	return &profiles, nil
 }
 
+// cleanupStaleProfiles deletes A18N profiles leaked by earlier runs that were
+// created more than olderThan ago. When A18N_PROFILE_PREFIX is set only
+// profiles carrying that prefix are considered. The static profile named by
+// A18N_STATIC_PROFILE_ID is never deleted.
+func (th *TestHarness) cleanupStaleProfiles(olderThan time.Duration) (int, error) {
+	profiles, err := th.profiles()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	staticProfileID := os.Getenv("A18N_STATIC_PROFILE_ID")
+	prefix := os.Getenv("A18N_PROFILE_PREFIX")
+	reclaimed := 0
+	for i := range profiles.Profiles {
+		profile := &profiles.Profiles[i]
+		if staticProfileID != "" && profile.ProfileID == staticProfileID {
+			continue
+		}
+		if prefix != "" && !strings.HasPrefix(profile.DisplayName, prefix+" ") {
+			continue
+		}
+		if profile.CreatedAt.IsZero() || profile.CreatedAt.After(cutoff) {
+			continue
+		}
+		if profile.URL == "" {
+			profile.URL = fmt.Sprintf("%s/v1/profile/%s", a18nApiURL(), profile.ProfileID)
+		}
+		if err = th.deleteProfile(profile); err != nil {
+			return reclaimed, err
+		}
+		reclaimed++
+	}
+	return reclaimed, nil
+}
+
 type userFactor struct {
 	ID         string                 `json:"id"`
 	FactorType string                 `json:"factorType"`
@@ -1067,15 +1994,16 @@ func (th *TestHarness) waitForFacebookLoginForm() error {
 }
 
 func (th *TestHarness) logsIntoFacebook() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
 
-	err := th.fillsInFormValue(`input[name="email"]`, th.currentProfile.EmailAddress, th.waitForFacebookLoginForm)
+	err = th.fillsInFormValue(`input[name="email"]`, profile.EmailAddress, th.waitForFacebookLoginForm)
 	if err != nil {
 		return err
 	}
-	err = th.fillsInFormValue(`input[name="pass"]`, th.currentProfile.Password, th.waitForFacebookLoginForm)
+	err = th.fillsInFormValue(`input[name="pass"]`, profile.Password, th.waitForFacebookLoginForm)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,68 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractVerificationCode(t *testing.T) {
+	for message, expected := range map[string]string{
+		"Your verification code is 123456":  "123456",
+		"Your verification code is:654321":  "654321",
+		"Enter this code:\n 112233\nThanks": "112233",
+		"No code in this message":           "",
+	} {
+		code := extractVerificationCode(message)
+		if code != expected {
+			t.Errorf("expected %q from %q, got %q", expected, message, code)
+		}
+		if expected != "" {
+			if err := checkVerificationCode(code); err != nil {
+				t.Errorf("expected %q to be well-formed, got %v", code, err)
+			}
+		}
+	}
+}
+
+func TestCheckVerificationCodeRejectsMalformedMessage(t *testing.T) {
+	err := checkVerificationCode(extractVerificationCode("Your verification code is 12345"))
+	if err == nil {
+		t.Fatal("expected an error for a message without a 6 digit code")
+	}
+	if !strings.Contains(err.Error(), `verification code "" parsed from the message isn't 6 digits`) {
+		t.Errorf("expected a descriptive error, got %v", err)
+	}
+
+	if err := checkVerificationCode("12a456"); err == nil {
+		t.Error("expected an error for a code with a letter in it")
+	}
+}
+
+func TestShownEmailMatches(t *testing.T) {
+	for _, shown := range []string{"mary@example.com", "Mary@Example.com", "m***y@example.com", "m***@example.com"} {
+		if !shownEmailMatches(shown, "mary@example.com") {
+			t.Errorf("expected %q to match mary@example.com", shown)
+		}
+	}
+	for _, shown := range []string{"joe@example.com", "j***e@example.com", "m***y@example.org", "mary@example.org", ""} {
+		if shownEmailMatches(shown, "mary@example.com") {
+			t.Errorf("expected %q not to match mary@example.com", shown)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tebeka/selenium"
+)
+
+// seleniumVerbose reports whether SELENIUM_VERBOSE asks for every WebDriver
+// action to be logged, it's off by default to keep the logs readable.
+func seleniumVerbose() bool {
+	verbose, _ := strconv.ParseBool(os.Getenv("SELENIUM_VERBOSE"))
+	return verbose
+}
+
+// verboseWebDriver logs the navigation and element lookups of a WebDriver
+// session, and the actions taken on the elements it finds.
+type verboseWebDriver struct {
+	selenium.WebDriver
+}
+
+func newVerboseWebDriver(wd selenium.WebDriver) selenium.WebDriver {
+	return &verboseWebDriver{WebDriver: wd}
+}
+
+// logWebDriverAction writes one WebDriver action as key=value pairs so CI
+// logs can be grepped by action or selector.
+func logWebDriverAction(action, by, target string, started time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	line := "webdriver action=" + action
+	if by != "" {
+		line += " by=" + strconv.Quote(by)
+	}
+	line += " target=" + strconv.Quote(target) + " result=" + result + " duration=" + time.Since(started).Round(time.Millisecond).String()
+	if err != nil {
+		line += " error=" + strconv.Quote(err.Error())
+	}
+	log.Print(line)
+}
+
+func (wd *verboseWebDriver) Get(url string) error {
+	started := time.Now()
+	err := wd.WebDriver.Get(url)
+	logWebDriverAction("get", "", url, started, err)
+	return err
+}
+
+func (wd *verboseWebDriver) FindElement(by, value string) (selenium.WebElement, error) {
+	started := time.Now()
+	elem, err := wd.WebDriver.FindElement(by, value)
+	logWebDriverAction("find", by, value, started, err)
+	if err != nil {
+		return nil, err
+	}
+	return &verboseWebElement{WebElement: elem, by: by, value: value}, nil
+}
+
+func (wd *verboseWebDriver) FindElements(by, value string) ([]selenium.WebElement, error) {
+	started := time.Now()
+	elems, err := wd.WebDriver.FindElements(by, value)
+	logWebDriverAction("find_all", by, value, started, err)
+	for i, elem := range elems {
+		elems[i] = &verboseWebElement{WebElement: elem, by: by, value: value}
+	}
+	return elems, err
+}
+
+// verboseWebElement logs the actions taken on an element, naming it by the
+// selector it was found with.
+type verboseWebElement struct {
+	selenium.WebElement
+	by, value string
+}
+
+func (e *verboseWebElement) Click() error {
+	started := time.Now()
+	err := e.WebElement.Click()
+	logWebDriverAction("click", e.by, e.value, started, err)
+	return err
+}
+
+func (e *verboseWebElement) SendKeys(keys string) error {
+	started := time.Now()
+	err := e.WebElement.SendKeys(keys)
+	logWebDriverAction("sendkeys", e.by, e.value, started, err)
+	return err
+}
+
+func (e *verboseWebElement) Clear() error {
+	started := time.Now()
+	err := e.WebElement.Clear()
+	logWebDriverAction("clear", e.by, e.value, started, err)
+	return err
+}
@@ -0,0 +1,77 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/tebeka/selenium"
+)
+
+type stubWebDriver struct {
+	selenium.WebDriver
+}
+
+func (stubWebDriver) FindElement(by, value string) (selenium.WebElement, error) {
+	if value == "#missing" {
+		return nil, errors.New("no such element")
+	}
+	return stubWebElement{}, nil
+}
+
+type stubWebElement struct {
+	selenium.WebElement
+}
+
+func (stubWebElement) SendKeys(keys string) error { return nil }
+
+func TestVerboseWebDriverLogsActions(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	wd := newVerboseWebDriver(stubWebDriver{})
+	elem, err := wd.FindElement(selenium.ByCSSSelector, `input[name="password"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = elem.SendKeys("s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wd.FindElement(selenium.ByCSSSelector, "#missing"); err == nil {
+		t.Fatal("expected the lookup to fail")
+	}
+
+	logged := buf.String()
+	for _, want := range []string{
+		`action=find by="css selector" target="input[name=\"password\"]" result=ok`,
+		`action=sendkeys by="css selector" target="input[name=\"password\"]" result=ok`,
+		`action=find by="css selector" target="#missing" result=error`,
+		`error="no such element"`,
+	} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("expected the log to contain %s, got:\n%s", want, logged)
+		}
+	}
+	if strings.Contains(logged, "s3cr3t") {
+		t.Error("expected the typed keys not to be logged")
+	}
+}
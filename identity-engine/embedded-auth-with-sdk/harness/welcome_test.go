@@ -0,0 +1,83 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	idx "github.com/okta/okta-idx-golang"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/views"
+)
+
+var homeHeading = regexp.MustCompile(`(?s)<h1[^>]*>(.*?)</h1>`)
+
+// renderWelcome executes home.gohtml for a user signed in with the claims in
+// OKTA_IDX_CLAIMS and returns its heading, the greeting the harness checks.
+func renderWelcome(t *testing.T, cfg *config.Config) string {
+	t.Helper()
+	client, err := idx.NewClientWithSettings(
+		idx.WithIssuer("https://example.okta.com/oauth2/default"),
+		idx.WithClientID("client-id"),
+		idx.WithClientSecret("client-secret"),
+		idx.WithScopes([]string{"openid", "profile", "email"}),
+		idx.WithRedirectURI("http://localhost:8000/login/callback"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := views.NewView(client, nil, config.DefaultClaimLabels, cfg.WelcomeClaimKey()).TemplateFuncs()
+	tpl := template.Must(template.New("").Funcs(funcs).ParseGlob("../views/*.gohtml"))
+
+	var buf bytes.Buffer
+	err = tpl.ExecuteTemplate(&buf, "home.gohtml", map[string]interface{}{
+		"Authenticated": true,
+		"Profile":       claims(),
+		"Errors":        "",
+		"Success":       "",
+		"ProfileError":  "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	heading := homeHeading.FindStringSubmatch(buf.String())
+	if heading == nil {
+		t.Fatalf("expected a heading on the home page, got %s", buf.String())
+	}
+	return strings.TrimSpace(heading[1])
+}
+
+func TestWelcomeMatchesHomePage(t *testing.T) {
+	defer os.Setenv("OKTA_IDX_CLAIMS", os.Getenv("OKTA_IDX_CLAIMS"))
+	os.Setenv("OKTA_IDX_CLAIMS", `{"name":"Mary Acme","given_name":"Mary"}`)
+	cfg := &config.Config{}
+
+	if rendered, expected := renderWelcome(t, cfg), expectedWelcome(cfg); rendered != expected || expected != "Welcome, Mary Acme." {
+		t.Errorf("expected the page %q and the harness %q to greet Mary Acme", rendered, expected)
+	}
+
+	cfg.WelcomeClaim = "given_name"
+	if rendered, expected := renderWelcome(t, cfg), expectedWelcome(cfg); rendered != expected || expected != "Welcome, Mary." {
+		t.Errorf("expected the page %q and the harness %q to greet Mary", rendered, expected)
+	}
+}
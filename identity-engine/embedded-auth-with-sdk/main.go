@@ -17,12 +17,50 @@
 package main
 
 import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
 	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/server"
 )
 
 func main() {
-	cfg := &config.Config{}
+	claimLabels, err := config.ParseClaimLabels(os.Getenv("CLAIM_LABELS"))
+	if err != nil {
+		log.Fatalf("CLAIM_LABELS error: %+v", err)
+	}
+	phoneCountries, err := config.ParsePhoneCountries(os.Getenv("PHONE_COUNTRIES"))
+	if err != nil {
+		log.Fatalf("PHONE_COUNTRIES error: %+v", err)
+	}
+	cfg := &config.Config{
+		ClaimLabels:    claimLabels,
+		PhoneCountries: phoneCountries,
+	}
+	if attempts := os.Getenv("MAX_CODE_ATTEMPTS"); attempts != "" {
+		cfg.MaxCodeAttempts, err = strconv.Atoi(attempts)
+		if err != nil || cfg.MaxCodeAttempts < 1 {
+			log.Fatalf("MAX_CODE_ATTEMPTS must be a positive number, got %q", attempts)
+		}
+	}
+	if timeout := os.Getenv("IDX_TIMEOUT"); timeout != "" {
+		cfg.IDXTimeout, err = time.ParseDuration(timeout)
+		if err != nil || cfg.IDXTimeout <= 0 {
+			log.Fatalf("IDX_TIMEOUT must be a positive duration, e.g. 10s, got %q", timeout)
+		}
+	}
+	if retries := os.Getenv("IDX_RETRIES"); retries != "" {
+		cfg.IDXRetries, err = strconv.Atoi(retries)
+		if err != nil || cfg.IDXRetries < 0 {
+			log.Fatalf("IDX_RETRIES must be zero or a positive number, got %q", retries)
+		}
+	}
+	cfg.AutoSelectAuthenticator = os.Getenv("AUTO_SELECT_AUTHENTICATOR") != "false"
+	cfg.ShowFullEmail = os.Getenv("SHOW_FULL_EMAIL") == "true"
+	cfg.GroupsClaim = os.Getenv("GROUPS_CLAIM")
+	cfg.WelcomeClaim = os.Getenv("WELCOME_CLAIM")
 	server := server.NewServer(cfg)
 
 	server.Run()
@@ -0,0 +1,250 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+	"unicode"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+// incorrectPasswordI18NKeys are the IDX error keys of a rejected password;
+// Okta sends the generic authentication failure when it hides whether the
+// account exists.
+var incorrectPasswordI18NKeys = []string{"incorrectPassword", "errors.E0000004"}
+
+// isIncorrectPasswordError reports whether IDX refused the identify step
+// because of the password, as opposed to the flow or the service failing.
+func isIncorrectPasswordError(err error) bool {
+	return hasIDXErrorKey(err, incorrectPasswordI18NKeys...)
+}
+
+// validatePasswordChange checks the new password against Okta's default
+// password policy so common mistakes are caught before the flow is spent.
+// The org's own policy may be stricter; Okta enforces it when the password
+// is set and its error is shown then.
+func validatePasswordChange(newPassword, confirmPassword string) string {
+	if newPassword != confirmPassword {
+		return "Passwords do not match"
+	}
+
+	var lower, upper, digit bool
+	for _, c := range newPassword {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
+	}
+	if len(newPassword) < 8 || !lower || !upper || !digit {
+		return "Password requirements: at least 8 characters, a lowercase letter, an uppercase letter, a number"
+	}
+	return ""
+}
+
+func (s *Server) changePassword(w http.ResponseWriter, r *http.Request) {
+	if !s.IsAuthenticated(r) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	s.render("changePassword.gohtml", w, r)
+}
+
+// checkCurrentPassword signs in with the current password to prove the user
+// knows it. The sign in is only a check, so it's cancelled however it ends.
+func (s *Server) checkCurrentPassword(ctx context.Context, identifier, password string) error {
+	lr, err := s.idxClient.InitLogin(ctx)
+	if err != nil {
+		return err
+	}
+	defer lr.Cancel(ctx)
+	_, err = lr.Identify(ctx, &idx.IdentifyRequest{
+		Identifier: identifier,
+		Credentials: idx.Credentials{
+			Password: password,
+		},
+	})
+	return err
+}
+
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if !s.IsAuthenticated(r) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	// Get session store so we can store our tokens
+	session, err := sessionStore.Get(r, "direct-auth")
+	if err != nil {
+		log.Fatalf("could not get store: %s", err)
+	}
+
+	profile, err := s.getProfileData(r)
+	if err != nil {
+		session.Values["Errors"] = err.Error()
+		session.Save(r, w)
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+	identifier := profile["preferred_username"]
+	if identifier == "" {
+		identifier = profile["email"]
+	}
+
+	err = s.checkCurrentPassword(context.TODO(), identifier, r.FormValue("currentPassword"))
+	if err != nil {
+		if isIncorrectPasswordError(err) {
+			session.Values["Errors"] = "Your current password is incorrect"
+		} else {
+			session.Values["Errors"] = err.Error()
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+
+	// Okta only lets a password be replaced through the recovery flow, which
+	// re-authenticates the user with a code sent to their email
+	rpr, err := s.idxClient.InitPasswordReset(context.TODO(), &idx.IdentifyRequest{Identifier: identifier})
+	if err == nil && rpr.HasStep(idx.ResetPasswordStepEmailVerification) {
+		rpr, err = rpr.VerifyEmail(context.TODO())
+	}
+	if err != nil {
+		session.Values["Errors"] = err.Error()
+		session.Save(r, w)
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+
+	s.cache.Set("changePasswordFlow", rpr, time.Minute*5)
+
+	if rpr.HasStep(idx.ResetPasswordStepNewPassword) {
+		http.Redirect(w, r, "/settings/password/newPassword", http.StatusFound)
+		return
+	}
+	if !rpr.HasStep(idx.ResetPasswordStepEmailConfirmation) {
+		rpr.Cancel(context.TODO())
+		s.cache.Delete("changePasswordFlow")
+		session.Values["Errors"] = "This sample does not support this use case, please review your policy setup and try again."
+		session.Save(r, w)
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/password/code", http.StatusFound)
+}
+
+func (s *Server) changePasswordCode(w http.ResponseWriter, r *http.Request) {
+	if _, found := s.cache.Get("changePasswordFlow"); !found {
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+	s.render("changePasswordCode.gohtml", w, r)
+}
+
+func (s *Server) handleChangePasswordCode(w http.ResponseWriter, r *http.Request) {
+	tmp, found := s.cache.Get("changePasswordFlow")
+	if !found {
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+	rpr := tmp.(*idx.ResetPasswordResponse)
+
+	// Get session store so we can store our tokens
+	session, err := sessionStore.Get(r, "direct-auth")
+	if err != nil {
+		log.Fatalf("could not get store: %s", err)
+	}
+
+	rpr, err = rpr.ConfirmEmail(context.TODO(), r.FormValue("code"))
+	if err != nil {
+		session.Values["Errors"] = err.Error()
+		session.Save(r, w)
+		http.Redirect(w, r, "/settings/password/code", http.StatusFound)
+		return
+	}
+	s.cache.Set("changePasswordFlow", rpr, time.Minute*5)
+
+	if !rpr.HasStep(idx.ResetPasswordStepNewPassword) {
+		rpr.Cancel(context.TODO())
+		s.cache.Delete("changePasswordFlow")
+		session.Values["Errors"] = "We encountered an unexpected error, please try again"
+		session.Save(r, w)
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/password/newPassword", http.StatusFound)
+}
+
+func (s *Server) changePasswordNewPassword(w http.ResponseWriter, r *http.Request) {
+	if _, found := s.cache.Get("changePasswordFlow"); !found {
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+	s.render("changePasswordNewPassword.gohtml", w, r)
+}
+
+// handleChangePasswordNewPassword finishes the change once Okta asks for the
+// new password. It's only read from this form, never kept between requests.
+func (s *Server) handleChangePasswordNewPassword(w http.ResponseWriter, r *http.Request) {
+	tmp, found := s.cache.Get("changePasswordFlow")
+	if !found {
+		http.Redirect(w, r, "/settings/password", http.StatusFound)
+		return
+	}
+	rpr := tmp.(*idx.ResetPasswordResponse)
+
+	session, err := sessionStore.Get(r, "direct-auth")
+	if err != nil {
+		log.Fatalf("could not get store: %s", err)
+	}
+
+	newPassword := r.FormValue("newPassword")
+	if msg := validatePasswordChange(newPassword, r.FormValue("confirmPassword")); msg != "" {
+		session.Values["Errors"] = msg
+		session.Save(r, w)
+		http.Redirect(w, r, "/settings/password/newPassword", http.StatusFound)
+		return
+	}
+
+	rpr, err = rpr.SetNewPassword(context.TODO(), newPassword)
+	if err != nil {
+		session.Values["Errors"] = err.Error()
+		session.Save(r, w)
+		http.Redirect(w, r, "/settings/password/newPassword", http.StatusFound)
+		return
+	}
+	s.cache.Delete("changePasswordFlow")
+
+	// The recovery flow signs the user in again, so keep the fresh tokens
+	if rpr.Token() != nil {
+		session.Values["access_token"] = rpr.Token().AccessToken
+		session.Values["id_token"] = rpr.Token().IDToken
+	}
+	session.Values["Success"] = "Your password has been changed."
+	session.Save(r, w)
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
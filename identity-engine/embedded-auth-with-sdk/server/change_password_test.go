@@ -0,0 +1,73 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+func TestValidatePasswordChange(t *testing.T) {
+	tests := []struct {
+		name           string
+		newPw, confirm string
+		valid          bool
+	}{
+		{"valid", "N3wPassword", "N3wPassword", true},
+		{"mismatch", "N3wPassword", "N3wPassw0rd", false},
+		{"too short", "N3wPass", "N3wPass", false},
+		{"no digit", "NewPassword", "NewPassword", false},
+		{"no uppercase", "n3wpassword", "n3wpassword", false},
+		{"no lowercase", "N3WPASSWORD", "N3WPASSWORD", false},
+	}
+	for _, tt := range tests {
+		msg := validatePasswordChange(tt.newPw, tt.confirm)
+		if tt.valid && msg != "" {
+			t.Errorf("%s: expected no error, got %q", tt.name, msg)
+		}
+		if !tt.valid && msg == "" {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestIsIncorrectPasswordError(t *testing.T) {
+	withKey := func(key string) error {
+		err := &idx.ErrorResponse{}
+		err.Message.Values = []idx.MessageValue{{I18N: idx.MessageValueI18N{Key: key}}}
+		return fmt.Errorf("identify: %w", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"incorrect password", withKey("incorrectPassword"), true},
+		{"authentication failed", withKey("errors.E0000004"), true},
+		{"expired session", withKey(sessionExpiredI18NKey), false},
+		{"network error", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := isIncorrectPasswordError(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
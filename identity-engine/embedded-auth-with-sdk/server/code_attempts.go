@@ -0,0 +1,76 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// DefaultMaxCodeAttempts is how many wrong verification codes a sign in
+	// allows when the config doesn't set MaxCodeAttempts.
+	DefaultMaxCodeAttempts = 5
+
+	tooManyAttemptsMessage = "Too many attempts. Sign in again to get a new code."
+	codeAttemptsKey        = "CodeAttempts"
+)
+
+func (s *Server) maxCodeAttempts() int {
+	if s.config.MaxCodeAttempts > 0 {
+		return s.config.MaxCodeAttempts
+	}
+	return DefaultMaxCodeAttempts
+}
+
+// isTooManyAttemptsError reports whether Okta refused a verification code
+// because the factor was locked after too many attempts.
+func isTooManyAttemptsError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "too many") || strings.Contains(msg, "locked")
+}
+
+// recordFailedCodeAttempt counts a wrong verification code for the sign in
+// in progress and reports whether it has run out of attempts, either by the
+// sample's own count or because Okta locked the factor.
+func (s *Server) recordFailedCodeAttempt(session *sessions.Session, err error) bool {
+	if isTooManyAttemptsError(err) {
+		delete(session.Values, codeAttemptsKey)
+		return true
+	}
+	attempts, _ := session.Values[codeAttemptsKey].(int)
+	attempts++
+	if attempts >= s.maxCodeAttempts() {
+		delete(session.Values, codeAttemptsKey)
+		return true
+	}
+	session.Values[codeAttemptsKey] = attempts
+	return false
+}
+
+// abandonLogin ends a sign in that ran out of verification attempts so the
+// next one starts from the login form with a fresh flow.
+func (s *Server) abandonLogin(w http.ResponseWriter, r *http.Request, session *sessions.Session) {
+	s.cache.Delete("loginResponse")
+	s.ViewData["InvalidEmailCode"] = false
+	s.ViewData["InvalidPhoneCode"] = false
+	session.Values["Errors"] = tooManyAttemptsMessage
+	session.Save(r, w)
+	http.Redirect(w, r, "/login", http.StatusFound)
+}
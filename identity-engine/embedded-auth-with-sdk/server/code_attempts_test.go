@@ -0,0 +1,56 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
+)
+
+func TestRecordFailedCodeAttempt(t *testing.T) {
+	s := &Server{config: &config.Config{MaxCodeAttempts: 3}}
+	session := sessions.NewSession(sessionStore, "direct-auth")
+	wrongCode := errors.New("Invalid code. Try again.")
+
+	for i := 1; i < 3; i++ {
+		if s.recordFailedCodeAttempt(session, wrongCode) {
+			t.Fatalf("expected attempt %d to be allowed", i)
+		}
+	}
+	if !s.recordFailedCodeAttempt(session, wrongCode) {
+		t.Fatal("expected the third wrong code to run out of attempts")
+	}
+	if _, ok := session.Values[codeAttemptsKey]; ok {
+		t.Error("expected the attempt count to be reset")
+	}
+}
+
+func TestRecordFailedCodeAttemptHonorsOktaLockout(t *testing.T) {
+	s := &Server{config: &config.Config{}}
+	session := sessions.NewSession(sessionStore, "direct-auth")
+
+	if !s.recordFailedCodeAttempt(session, errors.New("Too many attempts. Your account is locked.")) {
+		t.Error("expected Okta's lockout to end the sign in")
+	}
+	if s.maxCodeAttempts() != DefaultMaxCodeAttempts {
+		t.Errorf("expected the default of %d attempts, got %d", DefaultMaxCodeAttempts, s.maxCodeAttempts())
+	}
+}
@@ -0,0 +1,54 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+// enrollEmailKey keeps the address a registration enrolls, so the email
+// enrollment form can say where the code was sent.
+const enrollEmailKey = "EnrollEmail"
+
+// maskEmail hides most of the local part of an address the way Okta does,
+// e.g. "m***y@example.com" for mary@example.com.
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return email
+	}
+	local, domain := email[:at], email[at:]
+	if len(local) <= 2 {
+		return local[:1] + "***" + domain
+	}
+	return local[:1] + "***" + local[len(local)-1:] + domain
+}
+
+// enrollEmailTarget is the address shown on the email enrollment form,
+// masked unless ShowFullEmail is set.
+func (s *Server) enrollEmailTarget(session *sessions.Session) string {
+	if session == nil {
+		return ""
+	}
+	email, _ := session.Values[enrollEmailKey].(string)
+	if email == "" || s.config.ShowFullEmail {
+		return email
+	}
+	return maskEmail(email)
+}
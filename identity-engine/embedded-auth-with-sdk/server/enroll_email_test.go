@@ -0,0 +1,56 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"testing"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
+)
+
+func TestMaskEmail(t *testing.T) {
+	tests := map[string]string{
+		"mary@example.com": "m***y@example.com",
+		"jo@example.com":   "j***@example.com",
+		"m@example.com":    "m***@example.com",
+		"not-an-email":     "not-an-email",
+	}
+	for email, expected := range tests {
+		if got := maskEmail(email); got != expected {
+			t.Errorf("expected %q for %q, got %q", expected, email, got)
+		}
+	}
+}
+
+func TestEnrollEmailTarget(t *testing.T) {
+	session := sessions.NewSession(sessions.NewCookieStore([]byte("key")), "direct-auth")
+	session.Values[enrollEmailKey] = "mary@example.com"
+
+	s := &Server{config: &config.Config{}}
+	if got := s.enrollEmailTarget(session); got != "m***y@example.com" {
+		t.Errorf("expected the address to be masked by default, got %q", got)
+	}
+	s.config.ShowFullEmail = true
+	if got := s.enrollEmailTarget(session); got != "mary@example.com" {
+		t.Errorf("expected the full address, got %q", got)
+	}
+	if got := s.enrollEmailTarget(nil); got != "" {
+		t.Errorf("expected no address without a session, got %q", got)
+	}
+}
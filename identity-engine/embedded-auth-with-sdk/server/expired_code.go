@@ -0,0 +1,29 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+const (
+	expiredCodeMessage = "Your code has expired. Request a new one."
+	expiredCodeI18NKey = "api.authn.error.PASSCODE_EXPIRED"
+)
+
+// isExpiredCodeError reports whether Okta refused a verification code because
+// it outlived the token lifetime, as opposed to it being wrong. Only the
+// error's key is trusted; its message is localized.
+func isExpiredCodeError(err error) bool {
+	return hasIDXErrorKey(err, expiredCodeI18NKey)
+}
@@ -0,0 +1,48 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+func TestIsExpiredCodeError(t *testing.T) {
+	var byKey idx.ErrorResponse
+	err := json.Unmarshal([]byte(`{"messages":{"type":"array","value":[{"message":"Something went wrong.","i18n":{"key":"api.authn.error.PASSCODE_EXPIRED"},"class":"ERROR"}]}}`), &byKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[error]bool{
+		&byKey:                                      true,
+		fmt.Errorf("confirm: %w", &byKey):           true,
+		sessionExpiredError(t):                      false,
+		errors.New("The passcode has expired."):     false,
+		errors.New("Invalid code. Try again."):      false,
+		errors.New("Too many attempts. Try later."): false,
+	}
+	for err, expected := range tests {
+		if got := isExpiredCodeError(err); got != expected {
+			t.Errorf("isExpiredCodeError(%q) = %v, expected %v", err, got, expected)
+		}
+	}
+}
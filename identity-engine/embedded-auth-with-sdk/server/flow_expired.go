@@ -0,0 +1,79 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+const sessionExpiredI18NKey = "idx.session.expired"
+
+// flowCacheKeys are the cached steps of the sign in, registration and
+// password flows in progress.
+var flowCacheKeys = []string{
+	"loginResponse",
+	"enrollResponse",
+	"resetPasswordFlow",
+	"changePasswordFlow",
+	"phoneNumber",
+	"phoneMethod",
+	"Errors",
+}
+
+// isFlowExpiredError reports whether IDX refused a step because the
+// interaction it belongs to outlived its session, as opposed to a bad input.
+// Only the error's key is trusted; its message is localized.
+func isFlowExpiredError(err error) bool {
+	return hasIDXErrorKey(err, sessionExpiredI18NKey)
+}
+
+// flowExpired sends the user to the expired flow page when err is an IDX
+// session expiry and reports whether it did.
+func (s *Server) flowExpired(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !isFlowExpiredError(err) {
+		return false
+	}
+	http.Redirect(w, r, "/flowExpired", http.StatusFound)
+	return true
+}
+
+// clearFlowState forgets every step of the flows in progress so the next one
+// starts from scratch. The user's tokens and preferences are kept.
+func (s *Server) clearFlowState(session *sessions.Session) {
+	for _, key := range flowCacheKeys {
+		s.cache.Delete(key)
+	}
+	for _, key := range []string{identifierKey, enrollEmailKey, codeAttemptsKey, "AccountExists", "Errors"} {
+		delete(session.Values, key)
+	}
+	s.ViewData["InvalidEmailCode"] = false
+	s.ViewData["InvalidPhoneCode"] = false
+}
+
+func (s *Server) flowExpiredView(w http.ResponseWriter, r *http.Request) {
+	s.render("flowExpired.gohtml", w, r)
+}
+
+func (s *Server) restartFlow(w http.ResponseWriter, r *http.Request) {
+	if session, err := sessionStore.Get(r, "direct-auth"); err == nil {
+		s.clearFlowState(session)
+		session.Save(r, w)
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
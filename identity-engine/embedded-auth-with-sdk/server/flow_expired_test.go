@@ -0,0 +1,107 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	idx "github.com/okta/okta-idx-golang"
+	"github.com/patrickmn/go-cache"
+)
+
+// sessionExpiredError is the error IDX answers with once the interaction has
+// outlived its session.
+func sessionExpiredError(t *testing.T) *idx.ErrorResponse {
+	t.Helper()
+	var idxErr idx.ErrorResponse
+	err := json.Unmarshal([]byte(`{"messages":{"type":"array","value":[{"message":"The session has expired.","i18n":{"key":"idx.session.expired"},"class":"ERROR"}]}}`), &idxErr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &idxErr
+}
+
+func TestIsFlowExpiredError(t *testing.T) {
+	byKey := sessionExpiredError(t)
+
+	tests := map[error]bool{
+		byKey:                                   true,
+		fmt.Errorf("enroll: %w", byKey):         true,
+		errors.New("The session has expired."):  false,
+		errors.New("The passcode has expired."): false,
+		errors.New("Invalid code. Try again."):  false,
+		nil:                                     false,
+	}
+	for err, expected := range tests {
+		if got := isFlowExpiredError(err); got != expected {
+			t.Errorf("isFlowExpiredError(%v) = %v, expected %v", err, got, expected)
+		}
+	}
+	if isExpiredCodeError(byKey) {
+		t.Error("expected an expired session not to be taken for an expired code")
+	}
+}
+
+func TestFlowExpiredRedirects(t *testing.T) {
+	s := &Server{}
+
+	rec := httptest.NewRecorder()
+	if s.flowExpired(rec, httptest.NewRequest(http.MethodPost, "/enrollEmail", nil), errors.New("Invalid code. Try again.")) {
+		t.Fatal("expected a wrong code to be left to the handler")
+	}
+
+	rec = httptest.NewRecorder()
+	if !s.flowExpired(rec, httptest.NewRequest(http.MethodPost, "/enrollEmail", nil), sessionExpiredError(t)) {
+		t.Fatal("expected an expired session to be handled")
+	}
+	if loc := rec.Header().Get("Location"); loc != "/flowExpired" {
+		t.Errorf("expected a redirect to /flowExpired, got %q", loc)
+	}
+}
+
+func TestClearFlowState(t *testing.T) {
+	s := &Server{cache: cache.New(time.Minute, time.Minute), ViewData: ViewData{"InvalidEmailCode": true}}
+	s.cache.Set("enrollResponse", &idx.EnrollmentResponse{}, time.Minute)
+	s.cache.Set("phoneNumber", "+15556667777", time.Minute)
+	session := sessions.NewSession(sessionStore, "direct-auth")
+	session.Values[enrollEmailKey] = "mary@example.com"
+	session.Values["access_token"] = "token"
+
+	s.clearFlowState(session)
+
+	for _, key := range []string{"enrollResponse", "phoneNumber"} {
+		if _, ok := s.cache.Get(key); ok {
+			t.Errorf("expected %s to be cleared", key)
+		}
+	}
+	if _, ok := session.Values[enrollEmailKey]; ok {
+		t.Error("expected the enrollment email to be cleared")
+	}
+	if session.Values["access_token"] != "token" {
+		t.Error("expected the tokens to be kept")
+	}
+	if s.ViewData["InvalidEmailCode"] != false {
+		t.Error("expected the invalid code flag to be reset")
+	}
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	verifier "github.com/okta/okta-jwt-verifier-golang"
+)
+
+// defaultGroupsClaim is the ID token claim Okta lists a user's groups in once
+// the groups scope or a groups claim is configured for the app.
+const defaultGroupsClaim = "groups"
+
+// verifyIDToken checks the ID token Okta issued on sign in was signed by the
+// configured authorization server for this app and returns its claims.
+func (s *Server) verifyIDToken(idToken string) (map[string]interface{}, error) {
+	idxConfig := s.idxClient.Config()
+	jv := verifier.JwtVerifier{
+		Issuer:           idxConfig.Okta.IDX.Issuer,
+		ClaimsToValidate: map[string]string{"aud": idxConfig.Okta.IDX.ClientID},
+	}
+	result, err := jv.New().VerifyIdToken(idToken)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("id token could not be verified")
+	}
+	return result.Claims, nil
+}
+
+// groupsFromClaim lists the group names of a claim holding an array of
+// strings. Anything else, including a missing claim, lists none.
+func groupsFromClaim(claims map[string]interface{}, claim string) []string {
+	values, ok := claims[claim].([]interface{})
+	if !ok {
+		return nil
+	}
+	var groups []string
+	for _, v := range values {
+		if name, ok := v.(string); ok && name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups
+}
+
+// groupsClaim is the ID token claim the profile page lists groups from.
+func (s *Server) groupsClaim() string {
+	if s.config != nil && s.config.GroupsClaim != "" {
+		return s.config.GroupsClaim
+	}
+	return defaultGroupsClaim
+}
+
+// getProfileGroups lists the groups named in the signed in user's ID token,
+// none when the token doesn't carry the groups claim.
+func (s *Server) getProfileGroups(r *http.Request) []string {
+	session, err := sessionStore.Get(r, "direct-auth")
+	if err != nil {
+		return nil
+	}
+	idToken, _ := session.Values["id_token"].(string)
+	if idToken == "" {
+		return nil
+	}
+	claims, err := s.idTokenClaims(idToken)
+	if err != nil {
+		return nil
+	}
+	return groupsFromClaim(claims, s.groupsClaim())
+}
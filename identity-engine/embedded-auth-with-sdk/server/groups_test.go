@@ -0,0 +1,76 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
+)
+
+func TestGroupsFromClaim(t *testing.T) {
+	claims := map[string]interface{}{
+		"sub":    "00u1",
+		"groups": []interface{}{"Everyone", "Admins", 7.0},
+		"roles":  "Admins",
+	}
+	if groups := groupsFromClaim(claims, "groups"); !reflect.DeepEqual(groups, []string{"Everyone", "Admins"}) {
+		t.Errorf("expected the string groups, got %q", groups)
+	}
+	if groups := groupsFromClaim(claims, "roles"); groups != nil {
+		t.Errorf("expected a claim that isn't an array to list no groups, got %q", groups)
+	}
+	if groups := groupsFromClaim(claims, "missing"); groups != nil {
+		t.Errorf("expected a missing claim to list no groups, got %q", groups)
+	}
+}
+
+func TestGetProfileGroups(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	session, err := sessionStore.Get(r, "direct-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["id_token"] = "valid-id-token"
+
+	s := &Server{config: &config.Config{}}
+	s.idTokenClaims = func(idToken string) (map[string]interface{}, error) {
+		if idToken != "valid-id-token" {
+			return nil, errors.New("invalid id token")
+		}
+		return map[string]interface{}{
+			"groups": []interface{}{"Everyone"},
+			"teams":  []interface{}{"Blue"},
+		}, nil
+	}
+	if groups := s.getProfileGroups(r); !reflect.DeepEqual(groups, []string{"Everyone"}) {
+		t.Errorf("expected the groups claim by default, got %q", groups)
+	}
+	s.config.GroupsClaim = "teams"
+	if groups := s.getProfileGroups(r); !reflect.DeepEqual(groups, []string{"Blue"}) {
+		t.Errorf("expected the configured claim, got %q", groups)
+	}
+
+	session.Values["id_token"] = "forged-id-token"
+	if groups := s.getProfileGroups(r); groups != nil {
+		t.Errorf("expected no groups from a token that fails verification, got %q", groups)
+	}
+}
@@ -0,0 +1,56 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
+	"github.com/okta/samples-golang/identity-engine/internal/idxhttp"
+)
+
+func TestIDXClientTimesOutOnSlowEndpoint(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	client := newIDXHTTPClient(&config.Config{IDXTimeout: 50 * time.Millisecond, IDXRetries: 2})
+	start := time.Now()
+	_, err := client.Get(ts.URL)
+	if err == nil {
+		t.Fatal("expected the slow call to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the timeout to bound retries too, took %s", elapsed)
+	}
+}
+
+func TestIDXClientDefaultTimeout(t *testing.T) {
+	client := newIDXHTTPClient(&config.Config{})
+	if client.Timeout != idxhttp.DefaultTimeout {
+		t.Fatalf("expected timeout %s, got %s", idxhttp.DefaultTimeout, client.Timeout)
+	}
+}
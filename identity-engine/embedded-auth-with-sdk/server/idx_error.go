@@ -0,0 +1,70 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+// hasIDXErrorKey reports whether err is an IDX error carrying a message with
+// one of keys. Only the key is trusted; the message is localized.
+func hasIDXErrorKey(err error, keys ...string) bool {
+	var idxErr *idx.ErrorResponse
+	if !errors.As(err, &idxErr) {
+		return false
+	}
+	for _, v := range idxErr.Message.Values {
+		for _, key := range keys {
+			if v.I18N.Key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasFormErrorKey reports whether a field of resp's remediation forms carries
+// a message with key, e.g. an enroll-profile email that is already taken.
+func hasFormErrorKey(resp *idx.Response, key string) bool {
+	if resp == nil || resp.Remediation == nil {
+		return false
+	}
+	for _, ro := range resp.Remediation.RemediationOptions {
+		if formValuesHaveKey(ro.FormValues, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func formValuesHaveKey(values []idx.FormValue, key string) bool {
+	for _, fv := range values {
+		if fv.Message != nil {
+			for _, v := range fv.Message.Values {
+				if v.I18N.Key == key {
+					return true
+				}
+			}
+		}
+		if fv.Form != nil && formValuesHaveKey(fv.Form.FormValues, key) {
+			return true
+		}
+	}
+	return false
+}
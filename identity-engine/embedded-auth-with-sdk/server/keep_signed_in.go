@@ -0,0 +1,68 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// KeepSignedInMaxAge is how long the session cookie lasts when "keep me
+	// signed in" is checked on the login form.
+	KeepSignedInMaxAge = 30 * 24 * time.Hour
+
+	keepSignedInKey = "KeepSignedIn"
+)
+
+// keepSignedInStore is a cookie store whose cookies end with the browser
+// session unless the sign in asked to be kept, in which case they last
+// KeepSignedInMaxAge. The choice is held in the session so every later save,
+// e.g. of the tokens after MFA, keeps the same lifetime.
+type keepSignedInStore struct {
+	*sessions.CookieStore
+}
+
+func (s *keepSignedInStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *keepSignedInStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session, err := s.CookieStore.New(r, name)
+	applyKeepSignedIn(session)
+	return session, err
+}
+
+// setKeepSignedIn records the login form's "keep me signed in" choice.
+func setKeepSignedIn(session *sessions.Session, keep bool) {
+	if keep {
+		session.Values[keepSignedInKey] = true
+	} else {
+		delete(session.Values, keepSignedInKey)
+	}
+	applyKeepSignedIn(session)
+}
+
+func applyKeepSignedIn(session *sessions.Session) {
+	if keep, _ := session.Values[keepSignedInKey].(bool); keep {
+		session.Options.MaxAge = int(KeepSignedInMaxAge.Seconds())
+	} else {
+		session.Options.MaxAge = 0
+	}
+}
@@ -0,0 +1,57 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeepSignedInSetsCookieLifetime(t *testing.T) {
+	tests := map[bool]int{
+		true:  int(KeepSignedInMaxAge.Seconds()),
+		false: 0,
+	}
+	for keep, maxAge := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		rec := httptest.NewRecorder()
+		session, err := sessionStore.Get(req, "direct-auth")
+		if err != nil {
+			t.Fatal(err)
+		}
+		setKeepSignedIn(session, keep)
+		if err := session.Save(req, rec); err != nil {
+			t.Fatal(err)
+		}
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].MaxAge != maxAge {
+			t.Fatalf("keep %v: expected a cookie with Max-Age %d, got %v", keep, maxAge, cookies)
+		}
+
+		// later requests, e.g. saving the tokens after MFA, keep the lifetime
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookies[0])
+		session, err = sessionStore.Get(req, "direct-auth")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session.Options.MaxAge != maxAge {
+			t.Errorf("keep %v: expected Max-Age %d on the next request, got %d", keep, maxAge, session.Options.MaxAge)
+		}
+	}
+}
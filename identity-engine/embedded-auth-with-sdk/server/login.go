@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gorilla/sessions"
 	idx "github.com/okta/okta-idx-golang"
 )
 
@@ -32,10 +33,32 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 		return len(idps)
 	}
 
+	// a failed sign in shows the username again, never the password
+	session, _ := sessionStore.Get(r, "direct-auth")
+	s.ViewData["Identifier"] = takeIdentifier(session)
+	if s.ViewData["Identifier"] != "" {
+		session.Save(r, w)
+	}
+
 	// Render the login page
 	s.render("login.gohtml", w, r)
 }
 
+// identifierKey keeps the username of a failed sign in so the login form
+// can show it again.
+const identifierKey = "Identifier"
+
+// takeIdentifier returns the username a failed sign in left in the session
+// and removes it, so it's only shown once.
+func takeIdentifier(session *sessions.Session) string {
+	if session == nil {
+		return ""
+	}
+	identifier, _ := session.Values[identifierKey].(string)
+	delete(session.Values, identifierKey)
+	return identifier
+}
+
 // logout revokes the oauth2 token server side
 func (s *Server) logout(r *http.Request) {
 	session, err := sessionStore.Get(r, "direct-auth")
@@ -61,8 +84,7 @@ func (s *Server) logout(r *http.Request) {
 	h.Add("Accept", "application/json")
 	h.Add("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: time.Second * 30}
-	resp, err := client.Do(req)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		body, _ := ioutil.ReadAll(resp.Body)
 		fmt.Printf("revoke error; status: %s, body: %s\n", resp.Status, string(body))
@@ -91,12 +113,20 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	lr, err = lr.Identify(context.TODO(), ir)
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
+		session.Values[identifierKey] = ir.Identifier
 		session.Save(r, w)
 		http.Redirect(w, r, "/login", http.StatusFound)
 		return
 	}
+	// a new sign in gets the full number of verification attempts
+	delete(session.Values, codeAttemptsKey)
+	setKeepSignedIn(session, r.FormValue("keep_signed_in") == "on")
+	session.Save(r, w)
 
 	// If we have tokens we have success, so lets store tokens
 	if lr.Token() != nil {
@@ -129,20 +159,89 @@ func (s *Server) handleLoginSecondaryFactors(w http.ResponseWriter, r *http.Requ
 	} else {
 		s.ViewData["FactorPhone"] = false
 	}
+	if factor := s.autoSelectedFactor(s.ViewData["FactorEmail"].(bool), s.ViewData["FactorPhone"].(bool), lr.HasStep(idx.LoginStepSkip)); factor != "" {
+		http.Redirect(w, r, loginFactorPath(factor), http.StatusFound)
+		return
+	}
+	resp := s.remediations.latest()
+	factors := orderFactors(resp, s.ViewData["FactorEmail"].(bool), s.ViewData["FactorPhone"].(bool))
+	s.ViewData["Factors"] = factors
+	s.ViewData["PreselectedFactor"] = preselectedFactor(resp, factors)
 	s.render("loginSecondaryFactors.gohtml", w, r)
 }
 
-func (s *Server) handleLoginSecondaryFactorsProceed(w http.ResponseWriter, r *http.Request) {
-	delete(s.ViewData, "InvalidEmailCode")
-	pushFactor := r.FormValue("push_factor")
-	if pushFactor == "push_email" {
-		http.Redirect(w, r, "/login/factors/email", http.StatusFound)
-		return
+// autoSelectedFactor is the push_factor value of the only authenticator
+// offered when AutoSelectAuthenticator is on, so the list is skipped. A
+// skip option keeps the list, as the user may not want to verify at all.
+func (s *Server) autoSelectedFactor(email, phone, skip bool) string {
+	if !s.liveConfig().AutoSelectAuthenticator || skip || email == phone {
+		return ""
 	}
-	if pushFactor == "push_phone" {
-		http.Redirect(w, r, "/login/factors/phone/method", http.StatusFound)
-		return
+	if email {
+		return "push_email"
 	}
+	return "push_phone"
+}
+
+// loginFactorPath is where the challenge of a push_factor value starts, or
+// the list of authenticators for an unknown one.
+func loginFactorPath(pushFactor string) string {
+	switch pushFactor {
+	case "push_email":
+		return "/login/factors/email"
+	case "push_phone":
+		return "/login/factors/phone/method"
+	}
+	return "/login/factors"
+}
+
+// preselectedFactor is the push_factor value checked on the verification
+// form: the authenticator resp prefers when it is offered, otherwise the
+// first one listed.
+func preselectedFactor(resp *idx.Response, factors []string) string {
+	preferred := preferredFactor(resp)
+	for _, factor := range factors {
+		if factor == preferred {
+			return factor
+		}
+	}
+	if len(factors) > 0 {
+		return factors[0]
+	}
+	return ""
+}
+
+func (s *Server) handleLoginSecondaryFactorsProceed(w http.ResponseWriter, r *http.Request) {
+	delete(s.ViewData, "InvalidEmailCode")
+	http.Redirect(w, r, loginFactorPath(r.FormValue("push_factor")), http.StatusFound)
+}
+
+// canSwitchAuthenticator reports whether Okta offers an authenticator other
+// than current, a push_factor value, during its challenge, i.e. whether to
+// show the "Verify with something else" link.
+func canSwitchAuthenticator(lr *idx.LoginResponse, current string) bool {
+	return otherAuthenticatorOffered(
+		lr.HasStep(idx.LoginStepEmailVerification),
+		lr.HasStep(idx.LoginStepPhoneVerification) || lr.HasStep(idx.LoginStepPhoneInitialVerification),
+		current)
+}
+
+func otherAuthenticatorOffered(email, phone bool, current string) bool {
+	switch current {
+	case "push_email":
+		return phone
+	case "push_phone":
+		return email
+	}
+	return email || phone
+}
+
+// handleLoginSwitchAuthenticator follows Okta's "verify with something else"
+// remediation: the challenge in progress is left and the user picks another
+// authenticator from the list again.
+func (s *Server) handleLoginSwitchAuthenticator(w http.ResponseWriter, r *http.Request) {
+	delete(s.ViewData, "InvalidEmailCode")
+	delete(s.ViewData, "InvalidPhoneCode")
 	http.Redirect(w, r, "/login/factors", http.StatusFound)
 }
 
@@ -155,13 +254,18 @@ func (s *Server) handleLoginEmailVerification(w http.ResponseWriter, r *http.Req
 	}
 	invCode, ok := s.ViewData["InvalidEmailCode"]
 	if !ok || !invCode.(bool) {
-		lr, err := lr.VerifyEmail(r.Context())
+		var err error
+		lr, err = lr.VerifyEmail(r.Context())
+		if s.flowExpired(w, r, err) {
+			return
+		}
 		if err != nil {
 			http.Redirect(w, r, "/login", http.StatusFound)
 			return
 		}
 		s.cache.Set("loginResponse", lr, time.Minute*5)
 	}
+	s.ViewData["CanSwitchAuthenticator"] = canSwitchAuthenticator(lr, "push_email")
 	s.render("loginFactorEmail.gohtml", w, r)
 }
 
@@ -177,7 +281,14 @@ func (s *Server) handleLoginEmailConfirmation(w http.ResponseWriter, r *http.Req
 		log.Fatalf("could not get store: %s", err)
 	}
 	lr, err = lr.ConfirmEmail(r.Context(), r.FormValue("code"))
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
+		if s.recordFailedCodeAttempt(session, err) {
+			s.abandonLogin(w, r, session)
+			return
+		}
 		s.ViewData["InvalidEmailCode"] = true
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -204,6 +315,9 @@ func (s *Server) handleLoginEmailConfirmation(w http.ResponseWriter, r *http.Req
 		return
 	}
 	lr, err = lr.WhereAmI(r.Context())
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -248,6 +362,9 @@ func (s *Server) handleLoginPhoneVerification(w http.ResponseWriter, r *http.Req
 			} else {
 				lr, err = lr.VerifyPhone(r.Context(), idx.PhoneMethodSMS)
 			}
+			if s.flowExpired(w, r, err) {
+				return
+			}
 			if err != nil {
 				session.Values["Errors"] = err.Error()
 				session.Save(r, w)
@@ -256,6 +373,7 @@ func (s *Server) handleLoginPhoneVerification(w http.ResponseWriter, r *http.Req
 			}
 			s.cache.Set("loginResponse", lr, time.Minute*5)
 		}
+		s.ViewData["CanSwitchAuthenticator"] = canSwitchAuthenticator(lr, "push_phone")
 		s.render("loginFactorPhone.gohtml", w, r)
 		return
 	}
@@ -275,7 +393,14 @@ func (s *Server) handleLoginPhoneConfirmation(w http.ResponseWriter, r *http.Req
 		log.Fatalf("could not get store: %s", err)
 	}
 	lr, err = lr.ConfirmPhone(r.Context(), r.FormValue("code"))
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
+		if s.recordFailedCodeAttempt(session, err) {
+			s.abandonLogin(w, r, session)
+			return
+		}
 		s.ViewData["InvalidPhoneCode"] = true
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -300,6 +425,9 @@ func (s *Server) handleLoginPhoneConfirmation(w http.ResponseWriter, r *http.Req
 		return
 	}
 	lr, err = lr.WhereAmI(r.Context())
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -322,6 +450,9 @@ func (s *Server) handleLoginCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	lr, err = lr.WhereAmI(context.TODO())
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		log.Fatalf("could not tell where I am: %s", err)
 	}
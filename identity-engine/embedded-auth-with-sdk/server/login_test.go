@@ -0,0 +1,108 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	idx "github.com/okta/okta-idx-golang"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
+)
+
+func TestTakeIdentifier(t *testing.T) {
+	session := sessions.NewSession(sessions.NewCookieStore([]byte("key")), "direct-auth")
+	session.Values[identifierKey] = "mary@example.com"
+
+	if got := takeIdentifier(session); got != "mary@example.com" {
+		t.Errorf("expected the failed sign in's username, got %q", got)
+	}
+	if got := takeIdentifier(session); got != "" {
+		t.Errorf("expected the username to be shown only once, got %q", got)
+	}
+	if got := takeIdentifier(nil); got != "" {
+		t.Errorf("expected no username without a session, got %q", got)
+	}
+}
+
+func TestPreselectedFactor(t *testing.T) {
+	var emailCurrent idx.Response
+	if err := json.Unmarshal([]byte(`{"currentAuthenticatorEnrollment":{"type":"object","value":{"type":"email","key":"okta_email"}}}`), &emailCurrent); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		resp     *idx.Response
+		factors  []string
+		expected string
+	}{
+		{nil, []string{"push_email", "push_phone"}, "push_email"},
+		{nil, []string{"push_phone", "push_email"}, "push_phone"},
+		{&emailCurrent, []string{"push_phone", "push_email"}, "push_email"},
+		{&emailCurrent, []string{"push_phone"}, "push_phone"},
+		{&emailCurrent, nil, ""},
+	}
+	for _, test := range tests {
+		if got := preselectedFactor(test.resp, test.factors); got != test.expected {
+			t.Errorf("preferring %q with %v: expected %q, got %q", preferredFactor(test.resp), test.factors, test.expected, got)
+		}
+	}
+}
+
+func TestAutoSelectedFactor(t *testing.T) {
+	tests := []struct {
+		enabled      bool
+		email, phone bool
+		skip         bool
+		expected     string
+	}{
+		{true, true, false, false, "push_email"},
+		{true, false, true, false, "push_phone"},
+		{true, true, true, false, ""},
+		{true, true, false, true, ""},
+		{true, false, false, false, ""},
+		{false, true, false, false, ""},
+	}
+	for _, test := range tests {
+		s := &Server{config: &config.Config{AutoSelectAuthenticator: test.enabled}}
+		if got := s.autoSelectedFactor(test.email, test.phone, test.skip); got != test.expected {
+			t.Errorf("enabled %v with email %v, phone %v and skip %v: expected %q, got %q", test.enabled, test.email, test.phone, test.skip, test.expected, got)
+		}
+	}
+}
+
+func TestOtherAuthenticatorOffered(t *testing.T) {
+	tests := []struct {
+		email, phone bool
+		current      string
+		expected     bool
+	}{
+		{true, true, "push_email", true},
+		{true, true, "push_phone", true},
+		{true, false, "push_email", false},
+		{false, true, "push_phone", false},
+		{true, false, "push_phone", true},
+		{false, false, "push_email", false},
+	}
+	for _, test := range tests {
+		if got := otherAuthenticatorOffered(test.email, test.phone, test.current); got != test.expected {
+			t.Errorf("email %v and phone %v while verifying with %q: expected %v, got %v", test.email, test.phone, test.current, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+// pageTitles names the page each view shows in the browser's title bar.
+var pageTitles = map[string]string{
+	"home.gohtml":                      "Home",
+	"login.gohtml":                     "Login",
+	"loginFactorEmail.gohtml":          "Factor Login",
+	"loginFactorPhone.gohtml":          "Factor Login",
+	"loginFactorPhoneMethod.gohtml":    "Factor Login",
+	"loginSecondaryFactors.gohtml":     "Verification",
+	"register.gohtml":                  "Register",
+	"enroll.gohtml":                    "Factor Enrollment",
+	"enrollEmail.gohtml":               "Factor Enrollment",
+	"enrollPassword.gohtml":            "Factor Enrollment",
+	"enrollPhone.gohtml":               "Factor Enrollment",
+	"enrollPhoneCode.gohtml":           "Factor Enrollment",
+	"enrollPhoneMethod.gohtml":         "Factor Enrollment",
+	"resetPassword.gohtml":             "Reset my Password",
+	"resetPasswordCode.gohtml":         "Reset my Password",
+	"resetPasswordNewPassword.gohtml":  "Reset my Password",
+	"changePassword.gohtml":            "Change my Password",
+	"changePasswordCode.gohtml":        "Change my Password",
+	"changePasswordNewPassword.gohtml": "Change my Password",
+	"verify.gohtml":                    "Verification",
+	"verifyEmailCode.gohtml":           "Verification",
+	"flowExpired.gohtml":               "Session Expired",
+}
+
+// pageTitle is the title of view t, signed in users see their profile on the
+// home view. Views that aren't listed keep the sample's plain title.
+func pageTitle(t string, authenticated bool) string {
+	if t == "home.gohtml" && authenticated {
+		return "Profile"
+	}
+	return pageTitles[t]
+}
@@ -0,0 +1,142 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PhoneCountry is an option of the enroll phone form's country selector.
+type PhoneCountry struct {
+	Region   string
+	Name     string
+	DialCode string
+}
+
+var phoneCountries = []PhoneCountry{
+	{Region: "US", Name: "United States", DialCode: "+1"},
+	{Region: "CA", Name: "Canada", DialCode: "+1"},
+	{Region: "MX", Name: "Mexico", DialCode: "+52"},
+	{Region: "BR", Name: "Brazil", DialCode: "+55"},
+	{Region: "GB", Name: "United Kingdom", DialCode: "+44"},
+	{Region: "FR", Name: "France", DialCode: "+33"},
+	{Region: "DE", Name: "Germany", DialCode: "+49"},
+	{Region: "ES", Name: "Spain", DialCode: "+34"},
+	{Region: "IN", Name: "India", DialCode: "+91"},
+	{Region: "JP", Name: "Japan", DialCode: "+81"},
+	{Region: "AU", Name: "Australia", DialCode: "+61"},
+}
+
+// phoneCountriesFor lists the known countries with the given regions, in
+// their order. No regions lists every known country.
+func phoneCountriesFor(regions []string) ([]PhoneCountry, error) {
+	if len(regions) == 0 {
+		return phoneCountries, nil
+	}
+	countries := make([]PhoneCountry, 0, len(regions))
+	for _, region := range regions {
+		c, ok := phoneCountry(region)
+		if !ok {
+			return nil, fmt.Errorf("no dial code is known for country %s", region)
+		}
+		countries = append(countries, c)
+	}
+	return countries, nil
+}
+
+func phoneCountry(region string) (PhoneCountry, bool) {
+	for _, c := range phoneCountries {
+		if c.Region == region {
+			return c, true
+		}
+	}
+	return PhoneCountry{}, false
+}
+
+// phoneRegionFromLocale picks the country pre-selected on the enroll phone
+// form from the region of the browser's preferred language, e.g. "en-GB",
+// falling back to the first one offered.
+func phoneRegionFromLocale(acceptLanguage string, countries []PhoneCountry) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag = strings.TrimSpace(strings.SplitN(tag, ";", 2)[0])
+		parts := strings.FieldsFunc(tag, func(r rune) bool { return r == '-' || r == '_' })
+		if len(parts) < 2 {
+			continue
+		}
+		region := strings.ToUpper(parts[len(parts)-1])
+		for _, c := range countries {
+			if c.Region == region {
+				return region
+			}
+		}
+	}
+	if len(countries) == 0 {
+		return ""
+	}
+	return countries[0].Region
+}
+
+// e164PhoneNumber prefixes a national number with the dial code of region.
+// Numbers already in E.164 form, i.e. starting with "+", are kept as is.
+func e164PhoneNumber(region, number string) string {
+	number = strings.TrimSpace(number)
+	if number == "" || strings.HasPrefix(number, "+") {
+		return number
+	}
+	for _, c := range phoneCountries {
+		if c.Region == region {
+			return c.DialCode + strings.TrimLeft(number, "0")
+		}
+	}
+	return number
+}
+
+// DialCode returns the dial code, e.g. "+44", that an E.164 phone number
+// starts with, or "" when it matches none of the form's countries.
+func DialCode(phoneNumber string) string {
+	var code string
+	for _, c := range phoneCountries {
+		if strings.HasPrefix(phoneNumber, c.DialCode) && len(c.DialCode) > len(code) {
+			code = c.DialCode
+		}
+	}
+	return code
+}
+
+const maxPhoneExtension = 15
+
+var (
+	errInvalidPhoneExtension     = errors.New("The extension can only be up to 15 digits")
+	errPhoneExtensionUnsupported = errors.New("Okta can't dial an extension yet. Leave the extension empty to be called on the number itself")
+)
+
+// checkPhoneExtension reports why an extension can't be used for a voice
+// call. Okta only takes an E.164 number when enrolling a phone, so any
+// extension is refused here rather than sent in a form Okta would reject. An
+// empty extension is fine.
+func checkPhoneExtension(ext string) error {
+	ext = strings.TrimSpace(ext)
+	if ext == "" {
+		return nil
+	}
+	if len(ext) > maxPhoneExtension || strings.Trim(ext, "0123456789") != "" {
+		return errInvalidPhoneExtension
+	}
+	return errPhoneExtensionUnsupported
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPhoneRegionFromLocale(t *testing.T) {
+	tests := map[string]string{
+		"":                       "US",
+		"en-GB,en;q=0.9":         "GB",
+		"fr-FR":                  "FR",
+		"en;q=0.9,de-DE;q=0.8":   "DE",
+		"pt_BR":                  "BR",
+		"sw-KE,en-US;q=0.5":      "US",
+		"zh-Hant-TW,zh-TW;q=0.9": "US",
+		"es-419,es;q=0.9":        "US",
+	}
+	for acceptLanguage, expected := range tests {
+		if region := phoneRegionFromLocale(acceptLanguage, phoneCountries); region != expected {
+			t.Errorf("%q: expected %s, got %s", acceptLanguage, expected, region)
+		}
+	}
+
+	restricted, err := phoneCountriesFor([]string{"GB", "FR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region := phoneRegionFromLocale("en-US", restricted); region != "GB" {
+		t.Errorf("expected the first country offered, got %s", region)
+	}
+	if region := phoneRegionFromLocale("fr-FR", restricted); region != "FR" {
+		t.Errorf("expected FR, got %s", region)
+	}
+}
+
+func TestPhoneCountriesFor(t *testing.T) {
+	countries, err := phoneCountriesFor([]string{"GB", "US"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var regions []string
+	for _, c := range countries {
+		regions = append(regions, c.Region)
+	}
+	if expected := []string{"GB", "US"}; !reflect.DeepEqual(regions, expected) {
+		t.Errorf("expected %v, got %v", expected, regions)
+	}
+
+	if countries, _ := phoneCountriesFor(nil); len(countries) != len(phoneCountries) {
+		t.Errorf("expected every country, got %d", len(countries))
+	}
+	if _, err := phoneCountriesFor([]string{"ZZ"}); err == nil {
+		t.Error("expected a country without a known dial code to be refused")
+	}
+}
+
+func TestE164PhoneNumber(t *testing.T) {
+	tests := []struct {
+		region   string
+		number   string
+		expected string
+	}{
+		{"US", "5556667777", "+15556667777"},
+		{"GB", "07700900123", "+447700900123"},
+		{"GB", "+15556667777", "+15556667777"},
+		{"ZZ", "5556667777", "5556667777"},
+	}
+	for _, tt := range tests {
+		if number := e164PhoneNumber(tt.region, tt.number); number != tt.expected {
+			t.Errorf("%s %s: expected %s, got %s", tt.region, tt.number, tt.expected, number)
+		}
+	}
+}
+
+func TestDialCode(t *testing.T) {
+	if code := DialCode("+15556667777"); code != "+1" {
+		t.Errorf("expected +1, got %q", code)
+	}
+	if code := DialCode("+447700900123"); code != "+44" {
+		t.Errorf("expected +44, got %q", code)
+	}
+	if code := DialCode("+9999"); code != "" {
+		t.Errorf("expected no dial code, got %q", code)
+	}
+}
+
+func TestCheckPhoneExtension(t *testing.T) {
+	tests := map[string]error{
+		"":                 nil,
+		"  ":               nil,
+		"123":              errPhoneExtensionUnsupported,
+		" 42 ":             errPhoneExtensionUnsupported,
+		"12a":              errInvalidPhoneExtension,
+		"1234567890123456": errInvalidPhoneExtension,
+	}
+	for ext, expected := range tests {
+		if err := checkPhoneExtension(ext); err != expected {
+			t.Errorf("%q: expected %v, got %v", ext, expected, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+func newProfileTestServer(t *testing.T, issuer string) *Server {
+	t.Helper()
+	idxClient, err := idx.NewClientWithSettings(
+		idx.WithIssuer(issuer),
+		idx.WithClientID("client-id"),
+		idx.WithClientSecret("client-secret"),
+		idx.WithScopes([]string{"openid", "profile"}),
+		idx.WithRedirectURI("http://localhost:8000/login/callback"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{idxClient: idxClient, httpClient: &http.Client{}}
+}
+
+func signedInRequest(t *testing.T) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	session, err := sessionStore.Get(r, "direct-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["access_token"] = "access-token"
+	return r
+}
+
+func TestGetProfileData(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"Mary Acme","email":"mary@acme.com","email_verified":true}`))
+	}))
+	defer okta.Close()
+	s := newProfileTestServer(t, okta.URL+"/oauth2/default")
+
+	profile, err := s.getProfileData(signedInRequest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile["name"] != "Mary Acme" || profile["email"] != "mary@acme.com" {
+		t.Errorf("unexpected profile %v", profile)
+	}
+}
+
+func TestGetProfileDataFailedRequest(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	okta.Close()
+	s := newProfileTestServer(t, okta.URL+"/oauth2/default")
+
+	profile, err := s.getProfileData(signedInRequest(t))
+	if err == nil {
+		t.Error("expected an error when userinfo can't be reached")
+	}
+	if profile == nil || len(profile) != 0 {
+		t.Errorf("expected an empty profile, got %v", profile)
+	}
+}
+
+func TestGetProfileDataErrorStatus(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_token"}`))
+	}))
+	defer okta.Close()
+	s := newProfileTestServer(t, okta.URL+"/oauth2/default")
+
+	profile, err := s.getProfileData(signedInRequest(t))
+	if err == nil {
+		t.Error("expected an error for a 401 from userinfo")
+	}
+	if len(profile) != 0 {
+		t.Errorf("expected an empty profile, got %v", profile)
+	}
+}
+
+func TestGetProfileDataNoClaims(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer okta.Close()
+	s := newProfileTestServer(t, okta.URL+"/oauth2/default")
+
+	if _, err := s.getProfileData(signedInRequest(t)); err == nil {
+		t.Error("expected an error for a userinfo response without claims")
+	}
+}
+
+func TestGetProfileDataSignedOut(t *testing.T) {
+	s := newProfileTestServer(t, "https://example.okta.com/oauth2/default")
+
+	profile, err := s.getProfileData(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil || len(profile) != 0 {
+		t.Errorf("expected an empty profile without error, got %v, %v", profile, err)
+	}
+}
@@ -0,0 +1,87 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+func TestRegistrationFieldErrors(t *testing.T) {
+	if fieldErrors := registrationFieldErrors(&idx.UserProfile{FirstName: "Mary", LastName: "Acme", Email: "mary@example.com"}); fieldErrors != nil {
+		t.Errorf("expected no field errors, got %v", fieldErrors)
+	}
+
+	fieldErrors := registrationFieldErrors(&idx.UserProfile{FirstName: " ", Email: "mary@example.com"})
+	if len(fieldErrors) != 2 || fieldErrors["firstName"] == "" || fieldErrors["lastName"] == "" {
+		t.Errorf("expected first and last name errors, got %v", fieldErrors)
+	}
+
+	fieldErrors = registrationFieldErrors(&idx.UserProfile{})
+	for _, field := range []string{"firstName", "lastName", "email"} {
+		if fieldErrors[field] != "This field cannot be left blank" {
+			t.Errorf("expected %s to be required, got %q", field, fieldErrors[field])
+		}
+	}
+}
+
+func TestIsAccountExistsError(t *testing.T) {
+	// Okta reports a duplicate on the enroll-profile email field.
+	const exists = `{"version":"1.0.0","remediation":{"type":"array","value":[{"name":"enroll-profile","value":[{"name":"userProfile","form":{"value":[{"name":"email","messages":{"type":"array","value":[{"message":"A user with this Email already exists","i18n":{"key":"registration.error.notUniqueWithinOrg","params":["Email"]},"class":"ERROR"}]}}]}}]}]}}`
+	status, body := http.StatusBadRequest, exists
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	defer okta.Close()
+	s := &Server{remediations: &remediationRecorder{}}
+	client := &http.Client{Transport: s.remediations}
+	enroll := func(code int, response string) error {
+		status, body = code, response
+		resp, err := client.Post(okta.URL+"/idp/idx/enroll/new", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return fmt.Errorf("enroll: %w", &idx.ErrorResponse{})
+	}
+
+	if err := enroll(http.StatusBadRequest, exists); !s.isAccountExistsError(err) {
+		t.Error("expected the taken email to be an existing account")
+	}
+	if s.isAccountExistsError(errors.New("already exists")) {
+		t.Error("expected a plain error not to be an existing account")
+	}
+	if err := enroll(http.StatusBadRequest, `{"version":"1.0.0"}`); s.isAccountExistsError(err) {
+		t.Error("expected another refusal not to be an existing account")
+	}
+	enroll(http.StatusBadRequest, exists)
+	if err := enroll(http.StatusOK, `{"version":"1.0.0"}`); s.isAccountExistsError(err) {
+		t.Error("expected a later successful call to clear the refusal")
+	}
+
+	keyed := &idx.ErrorResponse{}
+	keyed.Message.Values = []idx.MessageValue{{I18N: idx.MessageValueI18N{Key: accountExistsI18NKey}}}
+	if !s.isAccountExistsError(keyed) {
+		t.Error("expected the key on the error itself to be an existing account")
+	}
+}
@@ -0,0 +1,173 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+// remediationRecorder keeps the last IDX response the sample got. The SDK's
+// LoginResponse only tells which steps are available, not in which order
+// Okta offered them, so pages that follow Okta's order read it from here.
+// A refused IDX call is kept apart, as its form carries the field errors the
+// SDK's error leaves out.
+type remediationRecorder struct {
+	Base http.RoundTripper
+
+	mu     sync.Mutex
+	last   *idx.Response
+	failed *idx.Response
+}
+
+func (t *remediationRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || !strings.Contains(req.URL.Path, "/idp/idx/") {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var recorded *idx.Response
+	if json.Unmarshal(body, &recorded) != nil {
+		recorded = nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if resp.StatusCode != http.StatusOK {
+		t.failed = recorded
+	} else if recorded != nil {
+		t.last, t.failed = recorded, nil
+	}
+	return resp, nil
+}
+
+// latest is the last IDX response, or nil before the first one.
+func (t *remediationRecorder) latest() *idx.Response {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+// latestFailure is the response of the last IDX call if Okta refused it, or
+// nil if that call succeeded.
+func (t *remediationRecorder) latestFailure() *idx.Response {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.failed
+}
+
+// authenticatorFactors maps the labels of the authenticators Okta offers to
+// the push_factor values of the verification form.
+var authenticatorFactors = map[string]string{
+	"Email": "push_email",
+	"Phone": "push_phone",
+}
+
+// offeredFactors is the push_factor values of the authenticators resp offers
+// to verify with, in the order Okta lists them.
+func offeredFactors(resp *idx.Response) []string {
+	if resp == nil || resp.Remediation == nil {
+		return nil
+	}
+	var factors []string
+	seen := map[string]bool{}
+	for _, ro := range resp.Remediation.RemediationOptions {
+		if ro.Name != "select-authenticator-authenticate" && ro.Name != "select-authenticator-enroll" {
+			continue
+		}
+		for _, fv := range ro.FormValues {
+			if fv.Name != "authenticator" {
+				continue
+			}
+			for _, option := range fv.Options {
+				if factor, ok := authenticatorFactors[option.Label]; ok && !seen[factor] {
+					seen[factor] = true
+					factors = append(factors, factor)
+				}
+			}
+		}
+	}
+	return factors
+}
+
+// authenticatorTypeFactors maps the types of IDX authenticators to the
+// push_factor values of the verification form.
+var authenticatorTypeFactors = map[string]string{
+	"email": "push_email",
+	"phone": "push_phone",
+}
+
+// preferredFactor is the push_factor value of the authenticator resp points
+// the user at, the one the flow was last verifying with, or "" when it points
+// at none the form offers. Okta lists the others in the policy's priority.
+func preferredFactor(resp *idx.Response) string {
+	if resp == nil {
+		return ""
+	}
+	for _, current := range []*idx.CurrentAuthenticatorEnrollment{resp.CurrentAuthenticatorEnrollment, resp.CurrentAuthenticator} {
+		if current != nil {
+			if factor, ok := authenticatorTypeFactors[current.Value.Type]; ok {
+				return factor
+			}
+		}
+	}
+	return ""
+}
+
+// orderFactors lists the push_factor values offered, email and phone, in
+// Okta's order. Factors Okta's response doesn't list, e.g. when there is no
+// response to go by, follow in the form's usual order.
+func orderFactors(resp *idx.Response, email, phone bool) []string {
+	offered := map[string]bool{"push_email": email, "push_phone": phone}
+	var factors []string
+	for _, factor := range append(offeredFactors(resp), "push_email", "push_phone") {
+		if offered[factor] {
+			factors = append(factors, factor)
+			offered[factor] = false
+		}
+	}
+	return factors
+}
+
+// namesAccount reports whether resp says which user the flow is for. Okta
+// leaves the user out when it carries on with an email that has no account,
+// so as not to reveal whether the account exists.
+func namesAccount(resp *idx.Response) bool {
+	return resp != nil && resp.User.Value.ID != ""
+}
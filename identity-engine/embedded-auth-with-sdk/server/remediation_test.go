@@ -0,0 +1,153 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+const phoneThenEmail = `{"remediation":{"type":"array","value":[{"name":"select-authenticator-authenticate","value":[{"name":"authenticator","type":"object","options":[{"label":"Phone","value":{"form":{"value":[{"name":"id","value":"aut2"}]}}},{"label":"Email","value":{"form":{"value":[{"name":"id","value":"aut1"}]}}}]}]}]}}`
+
+func TestRemediationRecorderKeepsLastIDXResponse(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/default/v1/token" {
+			fmt.Fprint(w, `{"access_token":"at"}`)
+			return
+		}
+		fmt.Fprint(w, phoneThenEmail)
+	}))
+	defer okta.Close()
+
+	recorder := &remediationRecorder{}
+	client := &http.Client{Transport: recorder}
+	if recorder.latest() != nil {
+		t.Fatal("expected no response before the first call")
+	}
+
+	resp, err := client.Post(okta.URL+"/idp/idx/identify", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != phoneThenEmail {
+		t.Errorf("expected the SDK to still read the body, got %s", body)
+	}
+
+	resp, err = client.Post(okta.URL+"/oauth2/default/v1/token", "application/x-www-form-urlencoded", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := offeredFactors(recorder.latest()); !reflect.DeepEqual(got, []string{"push_phone", "push_email"}) {
+		t.Errorf("expected the identify response to be kept, got %v", got)
+	}
+}
+
+func TestRemediationRecorderKeepsFailuresApart(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/idp/idx/challenge/answer" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		fmt.Fprint(w, phoneThenEmail)
+	}))
+	defer okta.Close()
+
+	recorder := &remediationRecorder{}
+	client := &http.Client{Transport: recorder}
+	for _, path := range []string{"/idp/idx/identify", "/idp/idx/challenge/answer"} {
+		resp, err := client.Post(okta.URL+path, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if recorder.latest() == nil || recorder.latestFailure() == nil || recorder.latest() == recorder.latestFailure() {
+		t.Error("expected the refused call to be kept apart from the last response")
+	}
+}
+
+func TestOrderFactors(t *testing.T) {
+	var resp idx.Response
+	if err := json.Unmarshal([]byte(phoneThenEmail), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		resp         *idx.Response
+		email, phone bool
+		expected     []string
+	}{
+		{&resp, true, true, []string{"push_phone", "push_email"}},
+		{&resp, true, false, []string{"push_email"}},
+		{nil, true, true, []string{"push_email", "push_phone"}},
+		{&idx.Response{}, false, true, []string{"push_phone"}},
+		{nil, false, false, nil},
+	}
+	for _, test := range tests {
+		if got := orderFactors(test.resp, test.email, test.phone); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("email %v and phone %v: expected %v, got %v", test.email, test.phone, test.expected, got)
+		}
+	}
+}
+
+func TestPreferredFactor(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{phoneThenEmail, ""},
+		{`{"currentAuthenticatorEnrollment":{"type":"object","value":{"type":"phone","key":"phone_number"}}}`, "push_phone"},
+		{`{"currentAuthenticator":{"type":"object","value":{"type":"email","key":"okta_email"}}}`, "push_email"},
+		{`{"currentAuthenticatorEnrollment":{"type":"object","value":{"type":"app","key":"okta_verify"}}}`, ""},
+	}
+	for _, test := range tests {
+		var resp idx.Response
+		if err := json.Unmarshal([]byte(test.body), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if got := preferredFactor(&resp); got != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.body, test.expected, got)
+		}
+	}
+	if got := preferredFactor(nil); got != "" {
+		t.Errorf("expected no preference without a response, got %q", got)
+	}
+}
+
+func TestNamesAccount(t *testing.T) {
+	var known, unknown idx.Response
+	if err := json.Unmarshal([]byte(`{"user":{"type":"object","value":{"id":"00u1"}}}`), &known); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(phoneThenEmail), &unknown); err != nil {
+		t.Fatal(err)
+	}
+	if !namesAccount(&known) || namesAccount(&unknown) || namesAccount(nil) {
+		t.Error("expected only a response naming its user to name the account")
+	}
+}
@@ -0,0 +1,93 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderBrokenTemplate(t *testing.T) {
+	s := &Server{
+		tpl: template.Must(template.New("broken.gohtml").Parse(
+			`<p>partial</p>{{ len .Missing }}{{define "error.gohtml"}}<h1>{{.Title}}</h1>{{.Errors}}{{end}}`)),
+		ViewData: ViewData{"Errors": "Something went wrong"},
+	}
+
+	rec := httptest.NewRecorder()
+	s.render("broken.gohtml", rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected a 500, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "partial") {
+		t.Errorf("expected no partial output, got %s", rec.Body.String())
+	}
+	if expected := "<h1>Internal Server Error</h1>This page could not be shown, please try again."; rec.Body.String() != expected {
+		t.Errorf("expected the error view, got %s", rec.Body.String())
+	}
+	if s.ViewData["Errors"] != "" {
+		t.Errorf("expected the errors to be cleared, got %v", s.ViewData["Errors"])
+	}
+}
+
+func TestRenderBrokenErrorTemplate(t *testing.T) {
+	s := &Server{
+		tpl: template.Must(template.New("broken.gohtml").Parse(
+			`<p>partial</p>{{ len .Missing }}{{define "error.gohtml"}}<p>error</p>{{ len .Missing }}{{end}}`)),
+		ViewData: ViewData{},
+	}
+
+	rec := httptest.NewRecorder()
+	s.render("broken.gohtml", rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected a 500, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "<p>") {
+		t.Errorf("expected no partial output, got %s", body)
+	}
+}
+
+func TestRenderPageTitle(t *testing.T) {
+	s := &Server{
+		tpl:      template.Must(template.New("login.gohtml").Parse(`<title>{{.Title}}</title>`)),
+		ViewData: ViewData{},
+	}
+
+	rec := httptest.NewRecorder()
+	s.render("login.gohtml", rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+	if body := rec.Body.String(); body != "<title>Login</title>" {
+		t.Errorf("expected the Login title, got %s", body)
+	}
+}
+
+func TestPageTitle(t *testing.T) {
+	if title := pageTitle("home.gohtml", false); title != "Home" {
+		t.Errorf("expected Home, got %q", title)
+	}
+	if title := pageTitle("home.gohtml", true); title != "Profile" {
+		t.Errorf("expected Profile once signed in, got %q", title)
+	}
+	if title := pageTitle("unknown.gohtml", false); title != "" {
+		t.Errorf("expected no title, got %q", title)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/ioutil"
@@ -28,6 +29,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -38,56 +40,93 @@ import (
 
 	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
 	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/views"
+	"github.com/okta/samples-golang/identity-engine/internal/scopes"
 )
 
 type Server struct {
-	config    *config.Config
-	tpl       *template.Template
-	idxClient *idx.Client
-	session   *sessions.CookieStore
-	view      *views.ViewConfig
-	ViewData  ViewData
-	cache     *cache.Cache
-	svc       *http.Server
-	address   string
+	config *config.Config
+	// configMu guards the config fields a running server can be changed
+	// through UpdateConfig, e.g. by the test harness between scenarios.
+	configMu   sync.RWMutex
+	tpl        *template.Template
+	idxClient  *idx.Client
+	httpClient *http.Client
+	session    *sessions.CookieStore
+	view       *views.ViewConfig
+	ViewData   ViewData
+	cache      *cache.Cache
+	svc        *http.Server
+	address    string
+	// remediations holds the last IDX response, for the details the SDK
+	// doesn't expose, e.g. the order authenticators are offered in.
+	remediations *remediationRecorder
+	// idTokenClaims verifies an ID token and returns its claims.
+	idTokenClaims func(idToken string) (map[string]interface{}, error)
 }
 
 type ViewData map[string]interface{}
 
-var sessionStore = sessions.NewCookieStore([]byte("okta-direct-auth-session-store"))
+var sessionStore = &keepSignedInStore{sessions.NewCookieStore([]byte("okta-direct-auth-session-store"))}
 
 func NewServer(c *config.Config) *Server {
 	idx, err := idx.NewClient()
 	if err != nil {
 		log.Fatalf("new client error: %+v", err)
 	}
+	scopes.WarnMissing(idx.Config().Okta.IDX.Scopes)
+	if _, err := phoneCountriesFor(c.PhoneCountries); err != nil {
+		log.Fatalf("PHONE_COUNTRIES error: %+v", err)
+	}
 
 	// NOTE: The cucumber testing harness Okta uses to ensure the golang samples
 	// remain operational needs to be throttled so it doesn't get rate limited
 	// by too many concurrent requests in tests. The idx client allows the
 	// ability to set a custom http client and we make use of that feature here.
-	if c.HttpClient != nil {
-		idx = idx.WithHTTPClient(c.HttpClient)
-	}
-
-	return &Server{
-		config:    c,
-		idxClient: idx,
-		session:   sessionStore,
-		cache:     cache.New(5*time.Minute, 10*time.Minute),
+	httpClient := newIDXHTTPClient(c)
+	remediations := &remediationRecorder{Base: httpClient.Transport}
+	httpClient.Transport = remediations
+	idx = idx.WithHTTPClient(httpClient)
+
+	s := &Server{
+		config:       c,
+		idxClient:    idx,
+		httpClient:   httpClient,
+		session:      sessionStore.CookieStore,
+		cache:        cache.New(5*time.Minute, 10*time.Minute),
+		remediations: remediations,
 		ViewData: map[string]interface{}{
 			"Authenticated": false,
 			"Errors":        "",
+			"Success":       "",
+			"ProfileError":  "",
 		},
 	}
+	s.idTokenClaims = s.verifyIDToken
+	return s
 }
 
 func (s *Server) Config() *config.Config {
 	return s.config
 }
 
+// UpdateConfig changes the config of a running server without racing the
+// handlers that read it.
+func (s *Server) UpdateConfig(update func(c *config.Config)) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	update(s.config)
+}
+
+// liveConfig is a copy of the config taken under configMu, for the fields
+// UpdateConfig may change while a request is handled.
+func (s *Server) liveConfig() config.Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return *s.config
+}
+
 func (s *Server) Session() *sessions.CookieStore {
-	return sessionStore
+	return sessionStore.CookieStore
 }
 
 func (s *Server) Address() string {
@@ -108,6 +147,7 @@ func (s *Server) Run() {
 	r.HandleFunc("/login", s.handleLogin).Methods("POST")
 	r.HandleFunc("/login/factors", s.handleLoginSecondaryFactors).Methods("GET")
 	r.HandleFunc("/login/factors/proceed", s.handleLoginSecondaryFactorsProceed).Methods("POST")
+	r.HandleFunc("/login/factors/switch", s.handleLoginSwitchAuthenticator).Methods("GET")
 	r.HandleFunc("/login/factors/email", s.handleLoginEmailVerification).Methods("GET")
 	r.HandleFunc("/login/factors/email", s.handleLoginEmailConfirmation).Methods("POST")
 	r.HandleFunc("/login/factors/phone/method", s.handleLoginPhoneVerificationMethod).Methods("GET")
@@ -127,6 +167,8 @@ func (s *Server) Run() {
 	r.HandleFunc("/enrollPhone", s.enrollPhoneMethod).Methods("POST")
 	r.HandleFunc("/enrollPhone/method", s.handleEnrollPhoneMethod).Methods("GET")
 	r.HandleFunc("/enrollPhone/code", s.handleEnrollPhoneCode).Methods("POST")
+	r.HandleFunc("/flowExpired", s.flowExpiredView).Methods("GET")
+	r.HandleFunc("/flowExpired/restart", s.restartFlow).Methods("GET")
 	r.HandleFunc("/enrollPassword", s.enrollPassword).Methods("GET")
 	r.HandleFunc("/enrollPassword", s.handleEnrollPassword).Methods("POST")
 
@@ -137,6 +179,13 @@ func (s *Server) Run() {
 	r.HandleFunc("/passwordRecovery/newPassword", s.passwordResetNewPassword).Methods("GET")
 	r.HandleFunc("/passwordRecovery/newPassword", s.handlePasswordResetNewPassword).Methods("POST")
 
+	r.HandleFunc("/settings/password", s.changePassword).Methods("GET")
+	r.HandleFunc("/settings/password", s.handleChangePassword).Methods("POST")
+	r.HandleFunc("/settings/password/code", s.changePasswordCode).Methods("GET")
+	r.HandleFunc("/settings/password/code", s.handleChangePasswordCode).Methods("POST")
+	r.HandleFunc("/settings/password/newPassword", s.changePasswordNewPassword).Methods("GET")
+	r.HandleFunc("/settings/password/newPassword", s.handleChangePasswordNewPassword).Methods("POST")
+
 	// General Pages
 	r.HandleFunc("/", s.home)
 	r.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
@@ -146,6 +195,7 @@ func (s *Server) Run() {
 			delete(session.Values, "id_token")
 			delete(session.Values, "access_token")
 			delete(session.Values, "Errors")
+			setKeepSignedIn(session, false)
 			session.Save(r, w)
 		}
 		s.cache.Flush()
@@ -161,7 +211,7 @@ func (s *Server) Run() {
 		http.Redirect(w, r, "/", http.StatusFound)
 	}).Methods("GET")
 	r.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
-		s.ViewData["Profile"] = s.getProfileData(r)
+		s.setProfileViewData(r)
 		s.render("profile.gohtml", w, r)
 	}).Methods("GET")
 
@@ -199,9 +249,53 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 }
 
 func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	accountExists := false
+	if session, err := sessionStore.Get(r, "direct-auth"); err == nil && session.Values["AccountExists"] != nil {
+		accountExists = true
+		delete(session.Values, "AccountExists")
+		session.Save(r, w)
+	}
+	s.ViewData["AccountExists"] = accountExists
+
 	s.render("register.gohtml", w, r)
 }
 
+// accountExistsI18NKey is the IDX error key Okta puts on the enroll-profile
+// identifier field when a user with that identifier is already registered.
+const accountExistsI18NKey = "registration.error.notUniqueWithinOrg"
+
+// isAccountExistsError reports whether enrolling a profile failed because a
+// user with the given identifier is already registered. Okta puts the key
+// on the form field rather than the error, so the remediation of the failed
+// IDX response is read as well.
+func (s *Server) isAccountExistsError(err error) bool {
+	if hasIDXErrorKey(err, accountExistsI18NKey) {
+		return true
+	}
+	var idxErr *idx.ErrorResponse
+	return errors.As(err, &idxErr) && hasFormErrorKey(s.remediations.latestFailure(), accountExistsI18NKey)
+}
+
+// registrationFieldErrors reports the required registration fields left
+// empty, keyed by form field name, so they can be shown without calling Okta.
+func registrationFieldErrors(profile *idx.UserProfile) map[string]string {
+	const blank = "This field cannot be left blank"
+	fieldErrors := map[string]string{}
+	if strings.TrimSpace(profile.FirstName) == "" {
+		fieldErrors["firstName"] = blank
+	}
+	if strings.TrimSpace(profile.LastName) == "" {
+		fieldErrors["lastName"] = blank
+	}
+	if strings.TrimSpace(profile.Email) == "" {
+		fieldErrors["email"] = blank
+	}
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	return fieldErrors
+}
+
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	profile := &idx.UserProfile{
 		FirstName: r.FormValue("firstName"),
@@ -209,6 +303,16 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		Email:     r.FormValue("email"),
 	}
 
+	if fieldErrors := registrationFieldErrors(profile); fieldErrors != nil {
+		s.ViewData["AccountExists"] = false
+		s.ViewData["FieldErrors"] = fieldErrors
+		s.ViewData["Registration"] = profile
+		s.render("register.gohtml", w, r)
+		delete(s.ViewData, "FieldErrors")
+		delete(s.ViewData, "Registration")
+		return
+	}
+
 	// Get session store so we can store our tokens
 	session, err := sessionStore.Get(r, "direct-auth")
 	if err != nil {
@@ -217,12 +321,17 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 	enrollResponse, err := s.idxClient.InitProfileEnroll(context.TODO(), profile)
 	if err != nil {
+		if s.isAccountExistsError(err) {
+			session.Values["AccountExists"] = true
+		}
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
 		http.Redirect(w, r, "/register", http.StatusFound)
 		return
 	}
 	s.cache.Set("enrollResponse", enrollResponse, time.Minute*5)
+	session.Values[enrollEmailKey] = profile.Email
+	session.Save(r, w)
 	if enrollResponse.HasStep(idx.EnrollmentStepPasswordSetup) {
 		http.Redirect(w, r, "/enrollPassword", http.StatusFound)
 		return
@@ -271,6 +380,9 @@ func (s *Server) transitionToProfile(er *idx.EnrollmentResponse, w http.Response
 	}
 
 	enrollResponse, err := er.Skip(r.Context())
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -342,6 +454,9 @@ func (s *Server) handleEnrollPassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	enrollResponse, err = enrollResponse.SetNewPassword(context.TODO(), r.FormValue("newPassword"))
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -372,11 +487,18 @@ func (s *Server) handleEnrollPassword(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) enrollPhone(w http.ResponseWriter, r *http.Request) {
+	countries, err := phoneCountriesFor(s.liveConfig().PhoneCountries)
+	if err != nil {
+		log.Printf("phone countries error: %+v", err)
+		countries = phoneCountries
+	}
+	s.ViewData["PhoneCountries"] = countries
+	s.ViewData["PhoneRegion"] = phoneRegionFromLocale(r.Header.Get("Accept-Language"), countries)
 	s.render("enrollPhone.gohtml", w, r)
 }
 
 func (s *Server) enrollPhoneMethod(w http.ResponseWriter, r *http.Request) {
-	s.cache.Set("phoneNumber", r.FormValue("phoneNumber"), time.Minute*5)
+	s.cache.Set("phoneNumber", e164PhoneNumber(r.FormValue("phoneCountry"), r.FormValue("phoneNumber")), time.Minute*5)
 	s.render("enrollPhoneMethod.gohtml", w, r)
 }
 
@@ -389,6 +511,9 @@ func (s *Server) handleEnrollPhoneCode(w http.ResponseWriter, r *http.Request) {
 		log.Fatalf("could not get store: %s", err)
 	}
 	enrollResponse, err = enrollResponse.ConfirmPhone(r.Context(), r.FormValue("code"))
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		s.ViewData["InvalidPhoneCode"] = true
 		session.Values["Errors"] = err.Error()
@@ -414,6 +539,9 @@ func (s *Server) handleEnrollPhoneCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	enrollResponse, err = enrollResponse.WhereAmI(r.Context())
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -450,14 +578,27 @@ func (s *Server) handleEnrollPhoneMethod(w http.ResponseWriter, r *http.Request)
 		http.Redirect(w, r, "/enrollPhone/method", http.StatusFound)
 		return
 	}
+	// the extension is only asked for with the voice method, a later visit
+	// without the form, e.g. to resend the code, has already been checked
+	if spm == nil && pm == idx.PhoneMethodVoiceCall {
+		if err := checkPhoneExtension(r.FormValue("extension")); err != nil {
+			s.ViewData["Errors"] = err.Error()
+			s.render("enrollPhoneMethod.gohtml", w, r)
+			return
+		}
+	}
 	s.cache.Set("phoneMethod", pm, time.Minute*6)
+	number := pn.(string)
 
 	cer, _ := s.cache.Get("enrollResponse")
 	enrollResponse := cer.(*idx.EnrollmentResponse)
 
 	invCode, ok := s.ViewData["InvalidPhoneCode"]
 	if !ok || !invCode.(bool) {
-		enrollResponse, err = enrollResponse.VerifyPhone(r.Context(), pm, pn.(string))
+		enrollResponse, err = enrollResponse.VerifyPhone(r.Context(), pm, number)
+		if s.flowExpired(w, r, err) {
+			return
+		}
 		if err != nil {
 			s.cache.Set("Errors", err.Error(), time.Minute*5)
 			session.Values["Errors"] = err.Error()
@@ -480,12 +621,17 @@ func (s *Server) enrollEmail(w http.ResponseWriter, r *http.Request) {
 	invCode, ok := s.ViewData["InvalidEmailCode"]
 	if !ok || !invCode.(bool) {
 		enrollResponse, err := enrollResponse.VerifyEmail(r.Context())
+		if s.flowExpired(w, r, err) {
+			return
+		}
 		if err != nil {
 			http.Redirect(w, r, "/enrollFactor", http.StatusFound)
 			return
 		}
 		s.cache.Set("enrollResponse", enrollResponse, time.Minute*5)
 	}
+	session, _ := sessionStore.Get(r, "direct-auth")
+	s.ViewData["EnrollEmailTarget"] = s.enrollEmailTarget(session)
 	s.render("enrollEmail.gohtml", w, r)
 }
 
@@ -501,6 +647,9 @@ func (s *Server) handleEnrollEmail(w http.ResponseWriter, r *http.Request) {
 		log.Fatalf("could not get store: %s", err)
 	}
 	enrollResponse, err = enrollResponse.ConfirmEmail(r.Context(), r.FormValue("code"))
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		s.ViewData["InvalidEmailCode"] = true
 		session.Values["Errors"] = err.Error()
@@ -525,6 +674,9 @@ func (s *Server) handleEnrollEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	enrollResponse, err = enrollResponse.WhereAmI(r.Context())
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -576,6 +728,9 @@ func (s *Server) handlePasswordReset(w http.ResponseWriter, r *http.Request) {
 	s.cache.Set("resetPasswordFlow", rpr, time.Minute*5)
 
 	rpr, err = rpr.VerifyEmail(context.TODO())
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		s.ViewData["InvalidEmail"] = true
 		session.Values["Errors"] = err.Error()
@@ -584,6 +739,9 @@ func (s *Server) handlePasswordReset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.ViewData["InvalidEmail"] = false
+	// an org protecting against account enumeration carries on without an
+	// account too, so the code page says the code may not have been sent
+	s.ViewData["RecoveryAccountUnknown"] = !namesAccount(s.remediations.latest())
 	if !rpr.HasStep(idx.ResetPasswordStepEmailConfirmation) {
 		session.Values["Errors"] = "We encountered an unexpected error, please try again"
 		session.Save(r, w)
@@ -608,6 +766,17 @@ func (s *Server) handlePasswordResetCode(w http.ResponseWriter, r *http.Request)
 	}
 
 	rpr, err = rpr.ConfirmEmail(context.TODO(), r.FormValue("code"))
+	if s.flowExpired(w, r, err) {
+		return
+	}
+	if err != nil && isExpiredCodeError(err) {
+		// a stale code can't be retried; the user needs a new email
+		s.cache.Delete("resetPasswordFlow")
+		session.Values["Errors"] = expiredCodeMessage
+		session.Save(r, w)
+		http.Redirect(w, r, "/passwordRecovery", http.StatusFound)
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -658,6 +827,9 @@ func (s *Server) handlePasswordResetNewPassword(w http.ResponseWriter, r *http.R
 	rpr := tmp.(*idx.ResetPasswordResponse)
 
 	rpr, err = rpr.SetNewPassword(context.TODO(), newPassword)
+	if s.flowExpired(w, r, err) {
+		return
+	}
 	if err != nil {
 		session.Values["Errors"] = err.Error()
 		session.Save(r, w)
@@ -688,6 +860,9 @@ func (s *Server) handlePasswordResetNewPassword(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	session.Values["Success"] = "Your password has been reset."
+	session.Save(r, w)
+
 	// redirect the user to /profile
 	http.Redirect(w, r, "/", http.StatusFound)
 	return
@@ -702,16 +877,33 @@ func (s *Server) home(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if s.IsAuthenticated(r) {
-		s.ViewData["Profile"] = s.getProfileData(r)
+		s.setProfileViewData(r)
 	}
 	s.render("home.gohtml", w, r)
 }
 
+// setProfileViewData shows the user's claims, or why they are unavailable
+// rather than an empty table.
+func (s *Server) setProfileViewData(r *http.Request) {
+	profile, err := s.getProfileData(r)
+	s.ViewData["Profile"] = profile
+	s.ViewData["Groups"] = s.getProfileGroups(r)
+	s.ViewData["ProfileError"] = ""
+	if err != nil {
+		log.Printf("userinfo error: %+v\n", err)
+		s.ViewData["ProfileError"] = err.Error()
+	}
+}
+
 func (s *Server) parseTemplates() {
 	var err error
 	t := template.New("")
 
-	s.view = views.NewView(s.idxClient, sessionStore)
+	claimLabels := s.config.ClaimLabels
+	if claimLabels == nil {
+		claimLabels = config.DefaultClaimLabels
+	}
+	s.view = views.NewView(s.idxClient, sessionStore.CookieStore, claimLabels, s.config.WelcomeClaimKey())
 
 	s.tpl, err = t.Funcs(s.view.TemplateFuncs()).ParseGlob("views/*.gohtml")
 
@@ -766,27 +958,65 @@ func (s *Server) render(t string, w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Cache-Control", "no-cache")
 
 	s.ViewData["Authenticated"] = s.IsAuthenticated(r)
+	s.ViewData["Title"] = pageTitle(t, s.ViewData["Authenticated"].(bool))
 
 	if session.Values["Errors"] != nil {
 		s.ViewData["Errors"] = session.Values["Errors"]
 		delete(session.Values, "Errors")
 		session.Save(r, w)
 	}
-
-	if err := s.tpl.ExecuteTemplate(w, t, s.ViewData); err != nil {
-		log.Fatalf("execute templates error: %+v", err)
+	if session.Values["Success"] != nil {
+		s.ViewData["Success"] = session.Values["Success"]
+		delete(session.Values, "Success")
+		session.Save(r, w)
 	}
 
+	// a template that fails part way through, e.g. on a missing key, mustn't
+	// leave a partial page behind a 200
+	var buf bytes.Buffer
+	err := s.tpl.ExecuteTemplate(&buf, t, s.ViewData)
+
 	s.ViewData["Errors"] = ""
+	s.ViewData["Success"] = ""
+
+	if err != nil {
+		log.Printf("execute templates error: %+v", err)
+		s.renderTemplateError(w, s.ViewData["Authenticated"].(bool))
+		return
+	}
+	buf.WriteTo(w)
 }
 
-func (s *Server) getProfileData(r *http.Request) map[string]string {
+// renderTemplateError answers with the error view after a template failed,
+// or plain text if the error view fails as well.
+func (s *Server) renderTemplateError(w http.ResponseWriter, authenticated bool) {
+	status := http.StatusInternalServerError
+	var buf bytes.Buffer
+	err := s.tpl.ExecuteTemplate(&buf, "error.gohtml", ViewData{
+		"Authenticated": authenticated,
+		"Title":         http.StatusText(status),
+		"Errors":        "This page could not be shown, please try again.",
+	})
+	if err != nil {
+		log.Printf("execute error template error: %+v", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// getProfileData reads the signed in user's claims from the userinfo
+// endpoint. Claims that aren't strings are skipped; a failed request or a
+// response without any claims is an error.
+func (s *Server) getProfileData(r *http.Request) (map[string]string, error) {
 	m := make(map[string]string)
 
 	session, err := sessionStore.Get(r, "direct-auth")
 
 	if err != nil || session.Values["access_token"] == nil || session.Values["access_token"] == "" {
-		return m
+		return m, nil
 	}
 
 	var reqUrl string
@@ -802,13 +1032,28 @@ func (s *Server) getProfileData(r *http.Request) map[string]string {
 	h.Add("Authorization", "Bearer "+session.Values["access_token"].(string))
 	h.Add("Accept", "application/json")
 
-	client := &http.Client{Timeout: time.Second * 30}
-	resp, _ := client.Do(req)
-	body, _ := ioutil.ReadAll(resp.Body)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return m, fmt.Errorf("userinfo request failed: %w", err)
+	}
 	defer resp.Body.Close()
-	json.Unmarshal(body, &m)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return m, fmt.Errorf("userinfo response could not be read: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return m, fmt.Errorf("userinfo returned %s", resp.Status)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(body, &m); err != nil && !errors.As(err, &typeErr) {
+		return m, fmt.Errorf("userinfo response is not valid: %w", err)
+	}
+	if len(m) == 0 {
+		return m, errors.New("userinfo returned no claims")
+	}
 
-	return m
+	return m, nil
 }
 
 func (s *Server) showView(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,45 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
+)
+
+// UserAgentTransport identifies the sample's outbound requests in Okta and
+// A18N logs. It prefixes any User-Agent already set, e.g. by the Okta SDK.
+type UserAgentTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *UserAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	userAgent := config.UserAgent()
+	if existing := req.Header.Get("User-Agent"); existing != "" {
+		userAgent = userAgent + " " + existing
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent)
+
+	return base.RoundTrip(req)
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/gorilla/sessions"
 
 	idx "github.com/okta/okta-idx-golang"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-auth-with-sdk/config"
 )
 
 var (
@@ -31,22 +33,38 @@ var (
 )
 
 type ViewConfig struct {
-	session *sessions.CookieStore
+	session      *sessions.CookieStore
+	claimLabels  map[string]string
+	welcomeClaim string
 }
 
-func NewView(c *idx.Client, s *sessions.CookieStore) *ViewConfig {
+func NewView(c *idx.Client, s *sessions.CookieStore, claimLabels map[string]string, welcomeClaim string) *ViewConfig {
 	idxClient = c
 	return &ViewConfig{
-		session: s,
+		session:      s,
+		claimLabels:  claimLabels,
+		welcomeClaim: welcomeClaim,
 	}
 }
 
 func (vc *ViewConfig) TemplateFuncs() template.FuncMap {
 	return template.FuncMap{
 		"configOption": configOption,
+		"claimLabel":   vc.claimLabel,
+		"welcome":      vc.welcome,
 	}
 }
 
+// welcome greets the signed in user by the welcome claim of their profile.
+func (vc *ViewConfig) welcome(profile map[string]string) string {
+	return config.WelcomeText(profile[vc.welcomeClaim])
+}
+
+// claimLabel is the friendly name of a claim key, or empty when it has none.
+func (vc *ViewConfig) claimLabel(key string) string {
+	return vc.claimLabels[key]
+}
+
 func configOption(item string) string {
 	if item == "Scopes" {
 		return strings.Join(idxClient.Config().Okta.IDX.Scopes, ", ")
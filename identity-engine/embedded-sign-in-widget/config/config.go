@@ -16,6 +16,180 @@
 
 package config
 
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultWidgetAssetBase is the Okta CDN location of the sign-in widget's
+// js/ and css/ assets.
+const DefaultWidgetAssetBase = "https://global.oktacdn.com/okta-signin-widget/5.8.1"
+
+// DefaultAccessTokenAudience is the audience of access tokens minted by an
+// org's default authorization server.
+const DefaultAccessTokenAudience = "api://default"
+
+var (
+	responseTypes = map[string]bool{"code": true, "token": true, "id_token": true}
+	responseModes = map[string]bool{"query": true, "fragment": true, "form_post": true}
+	prompts       = map[string]bool{"consent": true, "select_account": true, "login": true}
+)
+
 type Config struct {
 	Testing bool
+	// WidgetAssetBase points at a self-hosted copy of the sign-in widget
+	// assets for networks that can't reach the Okta CDN.
+	WidgetAssetBase string
+	// AccessTokenAudience is the aud claim required of bearer tokens sent
+	// by API clients.
+	AccessTokenAudience string
+	// BasePath is the path prefix the sample is served under, e.g. "/auth"
+	// behind a reverse proxy. Empty serves it from the root.
+	BasePath string
+	// CookieDomain and CookiePath scope the session cookie, e.g. a domain of
+	// "example.com" shares it between app.example.com and auth.example.com.
+	CookieDomain string
+	CookiePath   string
+	// InteractParams are extra form values sent to the interact endpoint,
+	// e.g. activation_token. They never replace the values the sample sets
+	// itself, such as state or code_challenge.
+	InteractParams url.Values
+	// PostLogoutRedirectURI is where Okta's end-session endpoint sends the
+	// user after signing them out of the org. Empty skips that endpoint and
+	// only ends the local session.
+	PostLogoutRedirectURI string
+	// AllowedPostLogoutRedirectURIs mirrors the sign-out redirect URIs
+	// registered on the Okta app. Okta fails silently on a URI that isn't
+	// registered, so PostLogoutRedirectURI must be one of them.
+	AllowedPostLogoutRedirectURIs []string
+	// ResponseType and ResponseMode set the OIDC response_type, e.g.
+	// "code id_token", and response_mode, e.g. "form_post", sent to the
+	// interact endpoint and the widget. Empty leaves Okta's defaults, an
+	// interaction code in the callback's query string.
+	ResponseType string
+	ResponseMode string
+	// Prompt is the OIDC prompt sent to the interact endpoint and the widget
+	// on sign in, "consent", "select_account" or "login", e.g. to show the
+	// account chooser in a demo. Empty sends none.
+	Prompt string
+	// SessionDir keeps sessions in files under this directory instead of in
+	// the cookie, for sessions that outgrow the 4KB cookie limit. The cookie
+	// then only carries the session's id. Empty uses the cookie store.
+	SessionDir string
+	// SessionKeys sign and encrypt sessions, newest first. New sessions use
+	// the first one; sessions from the others are still read, so a key can
+	// be rotated in without signing everyone out. Empty uses the sample's
+	// built-in key.
+	SessionKeys []SessionKey
+	// AllowedIssuers are further authorization servers a request may sign in
+	// against by passing ?issuer=, e.g. for demos spanning several of them.
+	// The app's client ID and secret are used with each, so every one must
+	// have the app assigned. Empty allows only the configured issuer.
+	AllowedIssuers []string
+	// IDXTimeout bounds each call to Okta, retries included. Zero uses the
+	// server's default.
+	IDXTimeout time.Duration
+	// IDXRetries is how many times a call to Okta is retried when it failed
+	// before Okta could act on it, e.g. with a 503.
+	IDXRetries int
+	// AutoRefresh renews a session's access token with its refresh token
+	// when userinfo rejects it. Off, the session ends with its access token
+	// instead, e.g. to show the expiry. Refresh tokens are only issued with
+	// the offline_access scope.
+	AutoRefresh bool
+	// WidgetFeatures are the sign-in widget's feature flags, e.g.
+	// registration or rememberMe, turned on or off in its config. Flags that
+	// aren't set keep the widget's defaults.
+	WidgetFeatures map[string]bool
+}
+
+var cookieDomainPattern = regexp.MustCompile(`^\.?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Validate checks settings that a browser would otherwise reject silently,
+// e.g. a cookie domain with a scheme or port.
+func (c *Config) Validate() error {
+	if c.CookieDomain != "" && !cookieDomainPattern.MatchString(strings.ToLower(c.CookieDomain)) {
+		return fmt.Errorf("invalid cookie domain %q", c.CookieDomain)
+	}
+	if c.CookiePath != "" && !strings.HasPrefix(c.CookiePath, "/") {
+		return fmt.Errorf("cookie path %q must start with /", c.CookiePath)
+	}
+	if c.SessionDir != "" {
+		if info, err := os.Stat(c.SessionDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("session dir %q must be an existing directory", c.SessionDir)
+		}
+	}
+	if err := validateSessionKeys(c.SessionKeys); err != nil {
+		return err
+	}
+	if err := validateWidgetFeatures(c.WidgetFeatures); err != nil {
+		return err
+	}
+	for _, issuer := range c.AllowedIssuers {
+		if u, err := url.Parse(issuer); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("allowed issuer %q must be an https URL", issuer)
+		}
+	}
+	if err := c.validateResponse(); err != nil {
+		return err
+	}
+	if c.Prompt != "" && !prompts[c.Prompt] {
+		return fmt.Errorf("unsupported prompt %q", c.Prompt)
+	}
+	if c.PostLogoutRedirectURI != "" {
+		if u, err := url.Parse(c.PostLogoutRedirectURI); err != nil || !u.IsAbs() || u.Host == "" {
+			return fmt.Errorf("post logout redirect uri %q must be an absolute URL", c.PostLogoutRedirectURI)
+		}
+		if !c.IsAllowedPostLogoutRedirectURI(c.PostLogoutRedirectURI) {
+			return fmt.Errorf("post logout redirect uri %q is not one of the allowed sign-out redirect URIs %q", c.PostLogoutRedirectURI, c.AllowedPostLogoutRedirectURIs)
+		}
+	}
+	return nil
+}
+
+// validateResponse rejects unknown response types and modes, and the
+// combinations the callback can't complete: it exchanges an interaction code
+// it reads from the query string or a form post, and OIDC forbids tokens in
+// the query string, which is also Okta's default mode for a code.
+func (c *Config) validateResponse() error {
+	types := strings.Fields(c.ResponseType)
+	var code, tokens bool
+	for _, t := range types {
+		if !responseTypes[t] {
+			return fmt.Errorf("unsupported response type %q", t)
+		}
+		if t == "code" {
+			code = true
+		} else {
+			tokens = true
+		}
+	}
+	if c.ResponseMode != "" && !responseModes[c.ResponseMode] {
+		return fmt.Errorf("unsupported response mode %q", c.ResponseMode)
+	}
+	if len(types) > 0 && !code {
+		return fmt.Errorf("response type %q must include code for the callback to sign in", c.ResponseType)
+	}
+	if c.ResponseMode == "fragment" {
+		return fmt.Errorf("response mode fragment isn't sent to the callback, use query or form_post")
+	}
+	if tokens && c.ResponseMode != "form_post" {
+		return fmt.Errorf("response type %q can only be returned with response mode form_post", c.ResponseType)
+	}
+	return nil
+}
+
+// IsAllowedPostLogoutRedirectURI reports whether uri is one of the allowed
+// sign-out redirect URIs.
+func (c *Config) IsAllowedPostLogoutRedirectURI(uri string) bool {
+	for _, allowed := range c.AllowedPostLogoutRedirectURIs {
+		if allowed == uri {
+			return true
+		}
+	}
+	return false
 }
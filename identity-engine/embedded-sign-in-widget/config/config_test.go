@@ -0,0 +1,169 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateCookieDomain(t *testing.T) {
+	valid := []string{"", "example.com", ".example.com", "auth.example.com", "localhost"}
+	for _, domain := range valid {
+		if err := (&Config{CookieDomain: domain}).Validate(); err != nil {
+			t.Errorf("expected %q to be valid: %v", domain, err)
+		}
+	}
+
+	invalid := []string{"https://example.com", "example.com:8000", "example.com/", "-example.com", "exa mple.com"}
+	for _, domain := range invalid {
+		if err := (&Config{CookieDomain: domain}).Validate(); err == nil {
+			t.Errorf("expected %q to be invalid", domain)
+		}
+	}
+}
+
+func TestValidateCookiePath(t *testing.T) {
+	if err := (&Config{CookiePath: "auth"}).Validate(); err == nil {
+		t.Error("expected a relative cookie path to be invalid")
+	}
+	if err := (&Config{CookiePath: "/auth"}).Validate(); err != nil {
+		t.Errorf("expected an absolute cookie path to be valid: %v", err)
+	}
+}
+
+func TestValidateSessionDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := (&Config{SessionDir: dir}).Validate(); err != nil {
+		t.Errorf("expected an existing directory to be valid: %v", err)
+	}
+	if err := (&Config{SessionDir: filepath.Join(dir, "missing")}).Validate(); err == nil {
+		t.Error("expected a missing directory to be invalid")
+	}
+}
+
+func TestValidateAllowedIssuers(t *testing.T) {
+	if err := (&Config{AllowedIssuers: []string{"https://acme.okta.com/oauth2/default"}}).Validate(); err != nil {
+		t.Errorf("expected an https issuer to be valid: %v", err)
+	}
+	for _, issuer := range []string{"http://acme.okta.com/oauth2/default", "acme.okta.com", ""} {
+		if err := (&Config{AllowedIssuers: []string{issuer}}).Validate(); err == nil {
+			t.Errorf("expected %q to be invalid", issuer)
+		}
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	valid := []Config{
+		{},
+		{ResponseType: "code", ResponseMode: "query"},
+		{ResponseType: "code id_token", ResponseMode: "form_post"},
+		{ResponseType: "code token id_token", ResponseMode: "form_post"},
+		{ResponseMode: "form_post"},
+	}
+	for _, c := range valid {
+		if err := c.Validate(); err != nil {
+			t.Errorf("expected %q/%q to be valid: %v", c.ResponseType, c.ResponseMode, err)
+		}
+	}
+
+	invalid := []Config{
+		{ResponseType: "code token", ResponseMode: "query"},
+		{ResponseType: "id_token", ResponseMode: "query"},
+		{ResponseType: "code id_token"},
+		{ResponseType: "token", ResponseMode: "form_post"},
+		{ResponseType: "code", ResponseMode: "fragment"},
+		{ResponseType: "interaction_code"},
+		{ResponseMode: "okta_post_message"},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected %q/%q to be invalid", c.ResponseType, c.ResponseMode)
+		}
+	}
+}
+
+func TestValidatePrompt(t *testing.T) {
+	for _, prompt := range []string{"", "consent", "select_account", "login"} {
+		if err := (&Config{Prompt: prompt}).Validate(); err != nil {
+			t.Errorf("expected prompt %q to be valid: %v", prompt, err)
+		}
+	}
+	// none is only for the silent authentication check
+	for _, prompt := range []string{"none", "select_account consent", "Login"} {
+		if err := (&Config{Prompt: prompt}).Validate(); err == nil {
+			t.Errorf("expected prompt %q to be invalid", prompt)
+		}
+	}
+}
+
+func TestSessionKeys(t *testing.T) {
+	c := &Config{SessionKeys: ParseSessionKeys(" new-hash:0123456789abcdef, old-hash ,")}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	pairs := c.KeyPairs()
+	if len(pairs) != 4 || string(pairs[0]) != "new-hash" || string(pairs[1]) != "0123456789abcdef" || string(pairs[2]) != "old-hash" || pairs[3] != nil {
+		t.Errorf("expected the new pair then the old hash key, got %q", pairs)
+	}
+	if pairs := (&Config{}).KeyPairs(); pairs != nil {
+		t.Errorf("expected no key pairs, got %q", pairs)
+	}
+
+	for _, keys := range []string{"hash:too-short", ":0123456789abcdef"} {
+		if err := (&Config{SessionKeys: ParseSessionKeys(keys)}).Validate(); err == nil {
+			t.Errorf("expected session keys %q to be invalid", keys)
+		}
+	}
+}
+
+func TestWidgetFeatures(t *testing.T) {
+	features, err := ParseWidgetFeatures(" registration, rememberMe=false ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 2 || !features["registration"] || features["rememberMe"] {
+		t.Errorf("expected registration on and rememberMe off, got %v", features)
+	}
+	if err = (&Config{WidgetFeatures: features}).Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err = ParseWidgetFeatures("registration=maybe"); err == nil {
+		t.Error("expected a flag that isn't true or false to be invalid")
+	}
+	if err = (&Config{WidgetFeatures: map[string]bool{"registraton": true}}).Validate(); err == nil {
+		t.Error("expected an unknown flag to be invalid")
+	}
+}
+
+func TestValidatePostLogoutRedirectURI(t *testing.T) {
+	allowed := []string{"http://localhost:8000/", "https://app.example.com/signed-out"}
+
+	if err := (&Config{PostLogoutRedirectURI: "https://app.example.com/signed-out", AllowedPostLogoutRedirectURIs: allowed}).Validate(); err != nil {
+		t.Errorf("expected an allowed uri to be valid: %v", err)
+	}
+	if err := (&Config{PostLogoutRedirectURI: "https://evil.example.com/", AllowedPostLogoutRedirectURIs: allowed}).Validate(); err == nil {
+		t.Error("expected a uri missing from the allowlist to be invalid")
+	}
+	if err := (&Config{PostLogoutRedirectURI: "https://app.example.com/signed-out"}).Validate(); err == nil {
+		t.Error("expected a uri with an empty allowlist to be invalid")
+	}
+	if err := (&Config{PostLogoutRedirectURI: "/signed-out", AllowedPostLogoutRedirectURIs: []string{"/signed-out"}}).Validate(); err == nil {
+		t.Error("expected a relative uri to be invalid")
+	}
+}
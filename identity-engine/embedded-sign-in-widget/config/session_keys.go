@@ -0,0 +1,74 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SessionKey signs, and with a BlockKey also encrypts, the session cookie.
+type SessionKey struct {
+	HashKey  []byte
+	BlockKey []byte
+}
+
+// ParseSessionKeys reads session keys written as comma separated
+// hashKey:blockKey pairs, newest first, e.g. "new-hash:new-block,old-hash".
+// The block key is optional; without one the cookie is signed but not
+// encrypted.
+func ParseSessionKeys(s string) []SessionKey {
+	var keys []SessionKey
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		key := SessionKey{HashKey: []byte(parts[0])}
+		if len(parts) == 2 {
+			key.BlockKey = []byte(parts[1])
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// validateSessionKeys rejects keys the session store would fail on with
+// every request instead of at start up.
+func validateSessionKeys(keys []SessionKey) error {
+	for i, key := range keys {
+		if len(key.HashKey) == 0 {
+			return fmt.Errorf("session key %d has no hash key", i+1)
+		}
+		switch len(key.BlockKey) {
+		case 0, 16, 24, 32:
+		default:
+			return fmt.Errorf("session key %d has a %d byte block key, it must be 16, 24 or 32 bytes", i+1, len(key.BlockKey))
+		}
+	}
+	return nil
+}
+
+// KeyPairs flattens the session keys into the hash and block key pairs the
+// gorilla session stores take, newest first.
+func (c *Config) KeyPairs() [][]byte {
+	var pairs [][]byte
+	for _, key := range c.SessionKeys {
+		pairs = append(pairs, key.HashKey, key.BlockKey)
+	}
+	return pairs
+}
@@ -0,0 +1,29 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import "fmt"
+
+// Version is reported in the User-Agent of the sample's outbound requests. It
+// can be set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/config.Version=1.0.0"
+var Version = "dev"
+
+func UserAgent() string {
+	return fmt.Sprintf("okta-samples-golang/%s", Version)
+}
@@ -0,0 +1,86 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// widgetFeatures are the feature flags the sign-in widget understands in its
+// features config.
+var widgetFeatures = map[string]bool{
+	"autoPush":                       true,
+	"callRecovery":                   true,
+	"hideBackToSignInForReset":       true,
+	"hideSignOutLinkInMFA":           true,
+	"idpDiscovery":                   true,
+	"multiOptionalFactorEnroll":      true,
+	"redirectByFormSubmit":           true,
+	"registration":                   true,
+	"rememberMe":                     true,
+	"rememberMyUsernameOnOIE":        true,
+	"restrictRedirectToForeground":   true,
+	"router":                         true,
+	"securityImage":                  true,
+	"selfServiceUnlock":              true,
+	"showIdentifier":                 true,
+	"showPasswordToggleOnSignInPage": true,
+	"smsRecovery":                    true,
+	"webauthn":                       true,
+}
+
+// ParseWidgetFeatures reads widget feature flags written as a comma separated
+// list, e.g. "registration,rememberMe=false". A flag without a value is
+// turned on.
+func ParseWidgetFeatures(s string) (map[string]bool, error) {
+	features := map[string]bool{}
+	for _, flag := range strings.Split(s, ",") {
+		if strings.TrimSpace(flag) == "" {
+			continue
+		}
+		parts := strings.SplitN(flag, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		on := true
+		if len(parts) == 2 {
+			var err error
+			if on, err = strconv.ParseBool(strings.TrimSpace(parts[1])); err != nil {
+				return nil, fmt.Errorf("widget feature %q must be true or false, got %q", name, parts[1])
+			}
+		}
+		features[name] = on
+	}
+	return features, nil
+}
+
+// validateWidgetFeatures rejects flags the widget doesn't know, which it
+// would otherwise ignore without a word.
+func validateWidgetFeatures(features map[string]bool) error {
+	for name := range features {
+		if !widgetFeatures[name] {
+			known := make([]string, 0, len(widgetFeatures))
+			for k := range widgetFeatures {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown widget feature %q, expected one of %s", name, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ Feature: Basic Login with Embedded Sign In Widget
   @8.1.1
   Scenario: 8.1.1 Mary logs in with a Password
     Given Mary navigates to the Embedded Widget View
+    Then she sees the widget is configured for the app
+    And the widget calls the issuer's interaction endpoint
     When she fills in her correct username
     And she fills in her correct password
     And she submits the Login form
@@ -15,4 +17,183 @@ Feature: Basic Login with Embedded Sign In Widget
     And she sees a table with her profile info
     And the cell for the value of "email" is shown and contains her email
     And the cell for the value of "name" is shown and contains her first name and last name
+    And she sees how long until her token expires
 
+
+  @8.1.2
+  Scenario: 8.1.2 Mary navigates back after logging in
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When she navigates back in the browser
+    Then she remains signed in
+
+
+  @8.1.3
+  Scenario: 8.1.3 Mary logs out and her access token is revoked
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    And she holds an access token
+    And her access token works against a protected resource
+    When she clicks the Logout button
+    Then she is redirected to the Root View
+    And her access token is revoked
+    And her tokens are cleared from the server
+
+
+  @8.1.4
+  Scenario: 8.1.4 Mary's session is rotated when she logs in
+    Given Mary navigates to the Embedded Widget View
+    And her session cookie is noted
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    And her session cookie is rotated
+
+
+  @8.1.5
+  Scenario: 8.1.5 Mary returns to the page she deep linked to after logging in
+    Given Mary deep links to the Profile View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Profile View
+
+
+  @8.1.6
+  Scenario: 8.1.6 Mary can't sign in again by replaying the login callback
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When she replays the login callback
+    Then she sees an error page saying the sign in was already completed
+
+
+  @8.1.7
+  Scenario: 8.1.7 Mary and another user signed in from two browsers each see their own profile
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When another user opens a second browser
+    And he navigates to the Embedded Widget View
+    And he fills in his correct username
+    And he fills in his correct password
+    And he submits the Login form
+    Then he is redirected to the Root View
+    When he navigates to the Profile View
+    Then the cell for the value of "email" is shown and contains his email
+    And the cell for the value of "name" is shown and contains his first name and last name
+    When Mary returns to the first browser
+    And she navigates to the Profile View
+    Then the cell for the value of "email" is shown and contains her email
+    And the cell for the value of "name" is shown and contains her first name and last name
+
+
+  @8.1.8
+  Scenario: 8.1.8 Mary's cookies allow the widget's cross-origin calls to Okta
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    And her cookies have the SameSite attributes for the embedded flow
+
+
+  @8.1.9
+  Scenario: 8.1.9 Mary gets a fresh interaction handle each time she logs in
+    Given Mary navigates to the Embedded Widget View
+    And her interaction handle is noted
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When she clicks the Logout button
+    Then she is redirected to the Root View
+    When she navigates to the Embedded Widget View
+    And her interaction handle is noted
+    And she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    And each login got a fresh interaction handle
+
+
+  @8.1.10
+  Scenario: 8.1.10 Mary logs out in one tab and is signed out in the other
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When she opens the app in a second tab
+    Then she remains signed in
+    When she switches to the first tab
+    And she clicks the Logout button
+    Then she is redirected to the Root View
+    When she switches to the second tab
+    And she refreshes the page
+    Then she is signed out
+
+
+  @8.1.11
+  Scenario: 8.1.11 Mary is asked which account to use when the app prompts for it
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    Given the app prompts Okta for "select_account"
+    When her app session is cleared
+    And she navigates to the Embedded Widget View
+    Then she sees the account chooser
+
+
+  @8.1.12
+  Scenario: 8.1.12 Mary lands on the Root View when she logs out
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When she clicks the Logout button
+    Then she lands on the post logout redirect URI
+    And she is signed out
+
+
+  # needs the sample's root, e.g. http://localhost:8000/, among the app's
+  # sign-out redirect URIs in Okta
+  @8.1.13
+  Scenario: 8.1.13 Mary is signed out of Okta and lands on the configured page when she logs out
+    Given the app's post logout redirect URI is "/"
+    And Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When she clicks the Logout button
+    Then she lands on the post logout redirect URI
+    And she is signed out
+
+
+  # needs /unregistered-sign-out to not be among the app's sign-out redirect
+  # URIs in Okta
+  @8.1.14
+  Scenario: 8.1.14 Mary is left on Okta when the post logout redirect URI isn't registered
+    Given the app's post logout redirect URI is "/unregistered-sign-out"
+    And Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When she clicks the Logout button
+    Then she is redirected to the Okta-hosted page matching "/v1/logout$"
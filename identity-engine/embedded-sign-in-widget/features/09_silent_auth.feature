@@ -0,0 +1,21 @@
+@9 @no-ci
+Feature: Silent Authentication Check with prompt=none
+
+  Background:
+    Given there is an existing user
+
+  @9.1.1
+  Scenario: 9.1.1 Mary's silent authentication check finds her existing session
+    Given Mary navigates to the Embedded Widget View
+    When she fills in her correct username
+    And she fills in her correct password
+    And she submits the Login form
+    Then she is redirected to the Root View
+    When she requests a silent authentication check
+    Then the silent authentication check reports she is authenticated
+
+  @9.1.2
+  Scenario: 9.1.2 Mary's silent authentication check without a session
+    Given Mary navigates to the Root View
+    When she requests a silent authentication check
+    Then the silent authentication check reports she is unauthenticated
@@ -0,0 +1,9 @@
+@10
+Feature: Embedded Sign In Widget assets fail to load
+
+  @10.1.1
+  Scenario: 10.1.1 Mary sees a fallback message when the widget can't be loaded
+    Given the sign-in widget assets are unreachable
+    When Mary navigates to the Embedded Widget View
+    Then she sees a message that the sign-in widget could not be loaded
+    And she sees a link to retry
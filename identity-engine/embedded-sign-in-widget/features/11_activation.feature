@@ -0,0 +1,15 @@
+@11 @no-ci
+Feature: 11 Activating an invited user's account
+
+  @11.1
+  Scenario: 11.1 Mary activates her account from her invite
+    Given there is an invited user
+    When she navigates to her activation link
+    And she sets her password
+    Then she is redirected to the Root View
+    And she remains signed in
+
+  @11.2
+  Scenario: 11.2 Mary follows an invalid activation link
+    Given Mary navigates to an activation link with token "not-a-real-token"
+    Then she sees an error that the activation link is invalid
@@ -0,0 +1,8 @@
+@12
+Feature: Misconfigured redirect URI
+
+  @12.1.1
+  Scenario: 12.1.1 Mary sees why signing in can't start when the redirect URI isn't registered
+    Given the app is configured with a wrong redirect URI
+    When Mary navigates to the Embedded Widget View
+    Then she sees an error that the redirect URI is not registered
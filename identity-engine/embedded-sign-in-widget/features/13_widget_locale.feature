@@ -0,0 +1,14 @@
+@13 @no-ci
+Feature: 13 Sign-in widget follows the browser's language
+
+  @13.1.1
+  Scenario: 13.1.1 Mary's browser prefers German
+    Given Mary's browser's language is "de-DE,de"
+    When Mary navigates to the Embedded Widget View
+    Then she sees the widget in "de" titled "Anmelden"
+
+  @13.1.2
+  Scenario: 13.1.2 Mary's browser prefers Spanish
+    Given Mary's browser's language is "es-ES,es"
+    When Mary navigates to the Embedded Widget View
+    Then she sees the widget in "es" titled "Iniciar sesión"
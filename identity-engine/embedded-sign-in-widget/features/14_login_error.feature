@@ -0,0 +1,7 @@
+@14
+Feature: Errors sent back to the login page
+
+  @14.1.1
+  Scenario: 14.1.1 Mary sees why Okta sent her back to the login page
+    Given Mary is sent back to the login page with the error "access_denied" and description "User is not assigned to the client application."
+    Then she sees the login error "access_denied: User is not assigned to the client application."
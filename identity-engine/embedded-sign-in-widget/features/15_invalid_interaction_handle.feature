@@ -0,0 +1,8 @@
+@15
+Feature: The widget is started with an invalid interaction handle
+
+  @15.1.1
+  Scenario: 15.1.1 Mary sees an error instead of a blank page when the interaction handle is invalid
+    When Mary opens the login page with an invalid interaction handle
+    Then she sees that signing in could not be started
+    And the browser log says why the widget failed
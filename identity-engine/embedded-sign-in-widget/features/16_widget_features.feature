@@ -0,0 +1,9 @@
+@16 @no-ci
+Feature: 16 Sign-in widget feature flags
+
+  @16.1.1
+  Scenario: 16.1.1 The widget is given the features the app turns on
+    Given the app turns on the widget's "registration" feature
+    When Mary navigates to the Embedded Widget View
+    Then the widget has the "registration" feature
+    And she sees the widget is configured for the app
@@ -0,0 +1,8 @@
+@17 @no-ci
+Feature: 17 Sign in gives up when Okta keeps requiring interaction
+
+  @17.1.1
+  Scenario: 17.1.1 Mary sees an error instead of an endless redirect loop
+    Given Mary navigates to the Embedded Widget View
+    When the callback keeps getting interaction_required
+    Then she sees an error asking her to try signing in again
@@ -7,11 +7,18 @@ require (
 	github.com/cucumber/messages-go/v10 v10.0.3
 	github.com/gorilla/mux v1.8.0
 	github.com/gorilla/sessions v1.2.1
-	github.com/okta/okta-idx-golang v0.2.1 // indirect
+	github.com/okta/okta-idx-golang v0.2.1
 	github.com/okta/okta-jwt-verifier-golang v1.1.1
 	github.com/okta/okta-sdk-golang/v2 v2.3.1-0.20210519105407-20ace51aad26
+	github.com/okta/samples-golang/identity-engine/internal v0.0.0-00010101000000-000000000000
 	github.com/patrickmn/go-cache v0.0.0-20180815053127-5633e0862627
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.1
 	github.com/tebeka/selenium v0.9.9
+	go.opentelemetry.io/otel v0.20.0
+	go.opentelemetry.io/otel/exporters/otlp v0.20.0
+	go.opentelemetry.io/otel/sdk v0.20.0
+	go.opentelemetry.io/otel/trace v0.20.0
 )
+
+replace github.com/okta/samples-golang/identity-engine/internal => ../internal
@@ -52,13 +52,27 @@ type A18NProfile struct {
 }
 
 type TestHarness struct {
-	server         *server.Server
-	wd             selenium.WebDriver
-	capabilities   selenium.Capabilities
-	currentProfile *A18NProfile
-	httpClient     *http.Client
-	oktaClient     *okta.Client
-	org            orgData
+	server          *server.Server
+	widgetAssetBase string
+	redirectURI     string
+	wd              selenium.WebDriver
+	capabilities    selenium.Capabilities
+	seleniumURL     string
+	currentProfile  *A18NProfile
+	otherWD         selenium.WebDriver
+	otherProfile    *A18NProfile
+	onSecondTab     bool
+	activationToken string
+	totpSecret      string
+	accessToken     string
+	tokenSID        string
+	preLoginCookie  string
+	preLoginSID     string
+	loginHandles    []string
+	httpClient      *http.Client
+	oktaClient      *okta.Client
+	org             orgData
+	sessions        sessionLimiter
 }
 
 type orgData struct {
@@ -70,7 +84,11 @@ type orgData struct {
 
 func NewTestHarness() *TestHarness {
 	return &TestHarness{
-		httpClient: &http.Client{Timeout: time.Second * 30},
+		httpClient: &http.Client{
+			Timeout:   time.Second * 30,
+			Transport: &server.UserAgentTransport{},
+		},
+		sessions: newSessionLimiter(maxSessions()),
 	}
 }
 
@@ -78,7 +96,10 @@ func (th *TestHarness) InitializeTestSuite(ctx *godog.TestSuiteContext) {
 	rand.Seed(time.Now().UnixNano())
 	ctx.BeforeSuite(func() {
 		cfg := &config.Config{
-			Testing: true,
+			Testing:         true,
+			WidgetAssetBase: os.Getenv("WIDGET_ASSET_BASE"),
+			BasePath:        os.Getenv("BASE_PATH"),
+			AutoRefresh:     true,
 		}
 		_, client, err := okta.NewClient(
 			context.Background(),
@@ -91,6 +112,8 @@ func (th *TestHarness) InitializeTestSuite(ctx *godog.TestSuiteContext) {
 
 		srv := server.NewServer(cfg)
 		th.server = srv
+		th.widgetAssetBase = cfg.WidgetAssetBase
+		th.redirectURI = srv.IdxConfig().Okta.IDX.RedirectURI
 
 		th.depopulateMary()
 
@@ -150,15 +173,31 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 		seleniumUrl = fmt.Sprintf("http://%s:%s@ondemand.saucelabs.com/wd/hub", sauceUsername, sauceAccessKey)
 	}
 
+	withBrowserLogging(capabilities)
 	th.capabilities = capabilities
+	th.seleniumURL = seleniumUrl
 
 	ctx.BeforeScenario(func(sc *messages.Pickle) {
 		th.capabilities["name"] = fmt.Sprintf("Golang (%s / %s) Sample App - %q", os.Getenv("TRAVIS_GO_VERSION"), os.Getenv("TRAVIS_REPO_SLUG"), sc.Name)
 		var err error
+		th.sessions.acquire()
+		// AfterScenario releases the slot of a started session; one that
+		// fails to start gives it back here, before the panic unwinds
+		started := false
+		defer func() {
+			if !started {
+				th.sessions.release()
+			}
+		}()
 		th.wd, err = selenium.NewRemote(th.capabilities, seleniumUrl)
 		if err != nil {
 			log.Panic(err)
 		}
+		if err = th.setsDefaultViewport(); err != nil {
+			_ = th.wd.Quit()
+			log.Panic(err)
+		}
+		started = true
 	})
 
 	ctx.AfterScenario(func(sc *messages.Pickle, err error) {
@@ -166,6 +205,24 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 			fmt.Printf("AfterScenario error: %+v\n", err)
 		}
 
+		// always restore the widget assets a scenario may have blocked
+		th.server.Config().WidgetAssetBase = th.widgetAssetBase
+		th.server.IdxConfig().Okta.IDX.RedirectURI = th.redirectURI
+		th.server.Config().Prompt = ""
+		th.server.Config().WidgetFeatures = nil
+		th.server.Config().PostLogoutRedirectURI = ""
+		th.server.Config().AllowedPostLogoutRedirectURIs = nil
+
+		th.activationToken = ""
+		th.totpSecret = ""
+		th.accessToken = ""
+		th.tokenSID = ""
+		th.preLoginCookie = ""
+		th.preLoginSID = ""
+		th.loginHandles = nil
+
+		th.closesSecondBrowser()
+
 		// always reset the given profile
 		err = th.destroyCurrentProfile()
 		if err != nil {
@@ -179,27 +236,90 @@ func (th *TestHarness) InitializeScenario(ctx *godog.ScenarioContext) {
 		if err != nil {
 			fmt.Printf("AfterScenario error quiting web driver: %+v\n", err)
 		}
+		th.sessions.release()
 	})
 
 	ctx.Step(`there is an existing user`, th.existingUser)
 	ctx.Step(`user with a Google account`, th.googleUser)
 	ctx.Step(`user with a Facebook account`, th.facebookUser)
 	ctx.Step(`sleep ([^" ]+)`, th.debugSleep)
+	ctx.Step(`browser window is (\d+)x(\d+)`, th.setsViewport)
+	ctx.Step(`browser window is maximized`, th.maximizesViewport)
 
 	ctx.Step(`navigates to Login with Social IDP`, th.navigateToLogin)
 	ctx.Step(`navigates to the Embedded Widget View`, th.navigateToLogin)
 	ctx.Step(`navigates to the Root View`, th.navigateToTheRootView)
 	ctx.Step(`navigates to the Profile View`, th.navigateToProfileView)
+	ctx.Step(`deep links to the Profile View`, th.deepLinksToProfileView)
 	ctx.Step(`fills in (their|her|his) correct username`, th.fillsInUsername)
 	ctx.Step(`fills in (their|her|his) correct password`, th.fillsInPassword)
 	ctx.Step(`submits the Login form`, th.submitsLoginForm)
 	ctx.Step(`is redirected to the Root View`, th.isRootView)
+	ctx.Step(`is redirected to the Profile View`, th.isProfileView)
+	ctx.Step(`is redirected to the Okta-hosted page matching "([^"]*)"`, th.isOnOktaHostedPage)
 	ctx.Step(`(he|she) sees a table with (her|his) profile info`, th.noop)
 	ctx.Step(`the cell for the value of "([^"]*)" is shown`, th.seesClaimsTableItemAndValueFromCurrentProfile)
+	ctx.Step(`sees how long until (?:her|his|their) token expires`, th.seesTokenExpiryCountdown)
+	ctx.Step(`navigates back in the browser`, th.navigatesBack)
+	ctx.Step(`sees the widget is configured for the app`, th.seesWidgetConfiguredForApp)
+	ctx.Step(`callback keeps getting interaction_required`, th.callbackKeepsGettingInteractionRequired)
+	ctx.Step(`sees an error asking (?:her|him|them) to try signing in again`, th.seesInteractionRequiredLoopError)
+	ctx.Step(`app turns on the widget's "([^"]*)" feature`, th.setsWidgetFeature)
+	ctx.Step(`widget has the "([^"]*)" feature`, th.widgetHasFeature)
+	ctx.Step(`widget calls the issuer's interaction endpoint`, th.widgetCallsInteractionEndpoint)
+	ctx.Step(`browser's language is "([^"]*)"`, th.setsBrowserLanguage)
+	ctx.Step(`app prompts Okta for "(consent|select_account|login)"`, th.setsPrompt)
+	ctx.Step(`(?:her|his|their) app session is cleared`, th.clearsAppSession)
+	ctx.Step(`sees the account chooser`, th.seesAccountChooser)
+	ctx.Step(`sees the widget in "([^"]*)" titled "([^"]*)"`, th.seesWidgetInLanguage)
+	ctx.Step(`remains signed in`, th.remainsSignedIn)
+	ctx.Step(`(?:she|he|they) (?:holds|hold) an access token`, th.holdsAccessToken)
+	ctx.Step(`(?:her|his|their) access token works against a protected resource`, th.accessTokenWorks)
+	ctx.Step(`clicks the Logout button`, th.clicksLogoutButton)
+	ctx.Step(`app's post logout redirect URI is "([^"]*)"`, th.setsPostLogoutRedirectURI)
+	ctx.Step(`(?:she|he|they) (?:lands|land) on the post logout redirect URI`, th.landsOnPostLogoutRedirectURI)
+	ctx.Step(`(?:her|his|their) session cookie is noted`, th.notesSessionCookie)
+	ctx.Step(`(?:her|his|their) session cookie is rotated`, th.sessionCookieIsRotated)
+	ctx.Step(`(?:her|his|their) interaction handle is noted`, th.notesInteractionHandle)
+	ctx.Step(`each login got a fresh interaction handle`, th.eachLoginGotFreshHandle)
+	ctx.Step(`(?:her|his|their) access token is revoked`, th.accessTokenIsRevoked)
+	ctx.Step(`(?:her|his|their) tokens are cleared from the server`, th.tokensAreCleared)
+	ctx.Step(`(?:her|his|their) cookies have the SameSite attributes for the (embedded|redirect) flow`, th.cookiesHaveSameSiteFor)
+	ctx.Step(`another user opens a second browser`, th.opensSecondBrowser)
+	ctx.Step(`returns to the first browser`, th.returnsToFirstBrowser)
+	ctx.Step(`opens the app in a second tab`, th.opensSecondTab)
+	ctx.Step(`switches to the (first|second) tab`, th.switchesToTab)
+	ctx.Step(`refreshes the page`, th.refreshesPage)
+	ctx.Step(`(?:she|he|they) (?:is|are) signed out`, th.isSignedOut)
+	ctx.Step(`replays the login callback`, th.replaysLoginCallback)
+	ctx.Step(`sees an error page saying the sign in was already completed`, th.seesInteractionCodeRejected)
 
 	ctx.Step(`(he|she) clicks the "Sign in with Google" button`, th.clicksSigninWithGoogle)
 	ctx.Step(`(he|she) clicks the "Sign in with Facebook" button`, th.clicksSigninWithFacebook)
 	ctx.Step(`logs in to Google`, th.signsInWithGoogle)
 	ctx.Step(`logs in to Facebook`, th.signsInWithFacebook)
 	ctx.Step(`is redirected back to the Sample App`, th.isRootView)
+
+	ctx.Step(`sign-in widget assets are unreachable`, th.widgetAssetsUnreachable)
+	ctx.Step(`sees a message that the sign-in widget could not be loaded`, th.seesWidgetLoadError)
+	ctx.Step(`opens the login page with an invalid interaction handle`, th.opensLoginWithInvalidInteractionHandle)
+	ctx.Step(`sees that signing in could not be started`, th.seesWidgetError)
+	ctx.Step(`the browser log says why the widget failed`, th.browserLogShowsWidgetError)
+	ctx.Step(`sees a link to retry`, th.seesWidgetRetryLink)
+
+	ctx.Step(`app is configured with a wrong redirect URI`, th.misconfiguresRedirectURI)
+	ctx.Step(`sees an error that the redirect URI is not registered`, th.seesRedirectUriMismatchError)
+
+	ctx.Step(`there is an invited user`, th.invitedUser)
+	ctx.Step(`navigates to (?:her|his|their) activation link`, th.navigatesToInvitedUserActivation)
+	ctx.Step(`navigates to an activation link with token "([^"]*)"`, th.navigatesToActivation)
+	ctx.Step(`is sent back to the login page with the error "([^"]*)" and description "([^"]*)"`, th.isSentBackToLoginWithError)
+	ctx.Step(`sees the login error "([^"]*)"`, th.seesLoginError)
+	ctx.Step(`sets (?:her|his|their) password`, th.setsActivationPassword)
+	ctx.Step(`sees an error that the activation link is invalid`, th.seesActivationError)
+	ctx.Step(`(?:her|his|their) Google Authenticator secret is seeded`, th.seedsTOTPSecret)
+	ctx.Step(`fills in (?:her|his|their) Google Authenticator code`, th.fillsInGoogleAuthenticatorCode)
+
+	ctx.Step(`requests a silent authentication check`, th.requestsSilentAuthentication)
+	ctx.Step(`silent authentication check reports (?:she|he|they) (?:is|are) (authenticated|unauthenticated)`, th.silentAuthenticationReports)
 }
@@ -0,0 +1,137 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/tebeka/selenium"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/server"
+)
+
+// oktaSessionCookie is the cookie Okta keeps its session in. The embedded
+// widget calls Okta cross-origin, so it only works there with SameSite=None.
+const oktaSessionCookie = "idx"
+
+// sameSiteExpectations are the SameSite attributes expected of the sample's
+// session cookie and of Okta's after signing in with the embedded widget or
+// through a redirect to Okta. HARNESS_<FLOW>_APP_SAMESITE and
+// HARNESS_<FLOW>_OKTA_SAMESITE override them, e.g. for an org or browser
+// with other defaults.
+func sameSiteExpectations(flow string) (app, okta string) {
+	switch flow {
+	case "embedded":
+		app, okta = "Lax", "None"
+	default:
+		app, okta = "Lax", "Lax"
+	}
+	prefix := "HARNESS_" + strings.ToUpper(flow)
+	if v := os.Getenv(prefix + "_APP_SAMESITE"); v != "" {
+		app = v
+	}
+	if v := os.Getenv(prefix + "_OKTA_SAMESITE"); v != "" {
+		okta = v
+	}
+	return app, okta
+}
+
+// parseCookieSameSite reads the sameSite of a WebDriver get cookie response.
+func parseCookieSameSite(body []byte) (string, error) {
+	var resp struct {
+		Value struct {
+			Name     string `json:"name"`
+			SameSite string `json:"sameSite"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Value.Name == "" {
+		return "", fmt.Errorf("no cookie in WebDriver response %s", body)
+	}
+	return resp.Value.SameSite, nil
+}
+
+func checkSameSite(name, got, want string) error {
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("expected cookie %s to be SameSite=%s, got %q", name, want, got)
+	}
+	return nil
+}
+
+// cookieSameSite asks WebDriver for the SameSite attribute of a cookie of
+// the current page; selenium.Cookie doesn't carry it.
+func (th *TestHarness) cookieSameSite(name string) (string, error) {
+	prefix := th.seleniumURL
+	if prefix == "" {
+		prefix = selenium.DefaultURLPrefix
+	}
+	cookieURL := fmt.Sprintf("%s/session/%s/cookie/%s", strings.TrimSuffix(prefix, "/"), th.wd.SessionID(), url.PathEscape(name))
+	resp, err := th.httpClient.Get(cookieURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("no %s cookie: WebDriver returned %s", name, resp.Status)
+	}
+	return parseCookieSameSite(body)
+}
+
+// cookiesHaveSameSiteFor checks the SameSite attributes of the sample's
+// session cookie and, on Okta's domain, of Okta's session cookie after
+// signing in with flow, "embedded" or "redirect". The browser is returned to
+// the page it was on.
+func (th *TestHarness) cookiesHaveSameSiteFor(flow string) error {
+	wantApp, wantOkta := sameSiteExpectations(flow)
+
+	got, err := th.cookieSameSite(server.SESSION_STORE_NAME)
+	if err != nil {
+		return err
+	}
+	if err = checkSameSite(server.SESSION_STORE_NAME, got, wantApp); err != nil {
+		return err
+	}
+
+	current, err := th.wd.CurrentURL()
+	if err != nil {
+		return err
+	}
+	// cookies are only readable from a page on their own domain
+	if err = th.wd.Get(th.server.IdxConfig().Okta.IDX.Issuer + "/.well-known/openid-configuration"); err != nil {
+		return err
+	}
+	got, err = th.cookieSameSite(oktaSessionCookie)
+	if err == nil {
+		err = checkSameSite(oktaSessionCookie, got, wantOkta)
+	}
+	if backErr := th.wd.Get(current); err == nil {
+		err = backErr
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSameSiteExpectations(t *testing.T) {
+	if app, okta := sameSiteExpectations("embedded"); app != "Lax" || okta != "None" {
+		t.Errorf("expected Lax and None for the embedded flow, got %s and %s", app, okta)
+	}
+	if app, okta := sameSiteExpectations("redirect"); app != "Lax" || okta != "Lax" {
+		t.Errorf("expected Lax and Lax for the redirect flow, got %s and %s", app, okta)
+	}
+
+	defer os.Setenv("HARNESS_EMBEDDED_APP_SAMESITE", os.Getenv("HARNESS_EMBEDDED_APP_SAMESITE"))
+	os.Setenv("HARNESS_EMBEDDED_APP_SAMESITE", "Strict")
+	if app, _ := sameSiteExpectations("embedded"); app != "Strict" {
+		t.Errorf("expected the override, got %s", app)
+	}
+}
+
+func TestParseCookieSameSite(t *testing.T) {
+	sameSite, err := parseCookieSameSite([]byte(`{"value":{"name":"idx","value":"abc","sameSite":"None","secure":true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = checkSameSite("idx", sameSite, "none"); err != nil {
+		t.Errorf("expected None to match case-insensitively, got %v", err)
+	}
+	if err = checkSameSite("idx", sameSite, "Lax"); err == nil {
+		t.Error("expected None not to match Lax")
+	}
+
+	if _, err = parseCookieSameSite([]byte(`{"value":{"error":"no such cookie"}}`)); err == nil {
+		t.Error("expected an error for a response without a cookie")
+	}
+}
@@ -165,6 +165,101 @@ func (th *TestHarness) deleteProfileFromOrg(userID string) error {
 	return nil
 }
 
+// invitedUser stages an org user the way an admin invite does and keeps the
+// activation token that the invite email would have linked to.
+func (th *TestHarness) invitedUser() error {
+	profile := &A18NProfile{
+		EmailAddress: fmt.Sprintf("mary-%d@a18n.help", time.Now().UnixNano()),
+		Password:     randomString(),
+		GivenName:    "Mary",
+		FamilyName:   "Invitee",
+		DisplayName:  "Mary Invitee",
+	}
+	userProfile := okta.UserProfile{}
+	userProfile["firstName"] = profile.GivenName
+	userProfile["lastName"] = profile.FamilyName
+	userProfile["login"] = profile.EmailAddress
+	userProfile["email"] = profile.EmailAddress
+	u, _, err := th.oktaClient.User.CreateUser(context.Background(), okta.CreateUserRequest{Profile: &userProfile}, query.NewQueryParams(query.WithActivate(false)))
+	if err != nil {
+		return err
+	}
+	profile.UserID = u.Id
+	th.currentProfile = profile
+
+	token, _, err := th.oktaClient.User.ActivateUser(context.Background(), u.Id, query.NewQueryParams(query.WithSendEmail(false)))
+	if err != nil {
+		return err
+	}
+	th.activationToken = token.ActivationToken
+	return nil
+}
+
+// createPasswordUser adds an active org user who can sign in with a password
+// straight away.
+func (th *TestHarness) createPasswordUser(givenName, familyName string) (*A18NProfile, error) {
+	profile := &A18NProfile{
+		EmailAddress: fmt.Sprintf("%s-%d@a18n.help", strings.ToLower(givenName), time.Now().UnixNano()),
+		Password:     randomString(),
+		GivenName:    givenName,
+		FamilyName:   familyName,
+		DisplayName:  fmt.Sprintf("%s %s", givenName, familyName),
+	}
+	userProfile := okta.UserProfile{}
+	userProfile["firstName"] = profile.GivenName
+	userProfile["lastName"] = profile.FamilyName
+	userProfile["login"] = profile.EmailAddress
+	userProfile["email"] = profile.EmailAddress
+	credentials := &okta.UserCredentials{Password: &okta.PasswordCredential{Value: profile.Password}}
+	u, _, err := th.oktaClient.User.CreateUser(context.Background(), okta.CreateUserRequest{Profile: &userProfile, Credentials: credentials}, query.NewQueryParams(query.WithActivate(true)))
+	if err != nil {
+		return nil, err
+	}
+	profile.UserID = u.Id
+	return profile, nil
+}
+
+// seedsTOTPSecret enrolls a Google Authenticator factor for the current user
+// through the management API and keeps its shared secret, so codes can be
+// computed instead of read off the enrollment page.
+func (th *TestHarness) seedsTOTPSecret() error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	if profile.UserID == "" {
+		return fmt.Errorf("current profile %s has no org user to enroll", profile.EmailAddress)
+	}
+
+	factor := okta.NewTotpUserFactor()
+	factor.Provider = "GOOGLE"
+	enrolled, _, err := th.oktaClient.UserFactor.EnrollFactor(context.Background(), profile.UserID, factor, nil)
+	if err != nil {
+		return err
+	}
+	userFactor, ok := enrolled.(*okta.UserFactor)
+	if !ok {
+		return fmt.Errorf("unexpected factor type %T", enrolled)
+	}
+	embedded, _ := userFactor.Embedded.(map[string]interface{})
+	activation, _ := embedded["activation"].(map[string]interface{})
+	secret, _ := activation["sharedSecret"].(string)
+	if secret == "" {
+		return fmt.Errorf("factor %s has no shared secret", userFactor.Id)
+	}
+
+	code, err := totpCode(secret, time.Now())
+	if err != nil {
+		return err
+	}
+	_, _, err = th.oktaClient.UserFactor.ActivateFactor(context.Background(), profile.UserID, userFactor.Id, okta.ActivateFactorRequest{PassCode: code}, okta.NewUserFactor())
+	if err != nil {
+		return err
+	}
+	th.totpSecret = secret
+	return nil
+}
+
 func (th *TestHarness) resetAppSignOnPolicyRule() error {
 	req, err := th.oktaClient.GetRequestExecutor().NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/policies/%s/rules/%s", th.org.policyID, th.org.mfaRuleID), nil)
 	if err != nil {
@@ -0,0 +1,136 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"fmt"
+
+	"github.com/cucumber/godog"
+	"github.com/tebeka/selenium"
+)
+
+// opensSecondBrowser signs a second user in from their own WebDriver
+// session, so a scenario can check two browsers don't share what the server
+// keeps for them. The second browser and user become the current ones until
+// returnsToFirstBrowser swaps them back.
+func (th *TestHarness) opensSecondBrowser() error {
+	if cap(th.sessions) < 2 {
+		// the first browser holds the only session slot
+		return fmt.Errorf("%w: a second browser needs SELENIUM_MAX_SESSIONS of at least 2", godog.ErrPending)
+	}
+	profile, err := th.createPasswordUser("Joe", "Second")
+	if err != nil {
+		return err
+	}
+
+	th.sessions.acquire()
+	wd, err := selenium.NewRemote(th.capabilities, th.seleniumURL)
+	if err != nil {
+		th.sessions.release()
+		th.deleteProfileFromOrg(profile.UserID)
+		return err
+	}
+	th.otherWD, th.otherProfile = wd, profile
+	th.swapBrowsers()
+	return th.setsDefaultViewport()
+}
+
+// opensSecondTab opens the app in another WebDriver session holding the
+// current browser's cookies, i.e. a second tab signed in with the same
+// session. Unlike a second browser it keeps the current user. The new tab
+// becomes current.
+func (th *TestHarness) opensSecondTab() error {
+	if cap(th.sessions) < 2 {
+		return fmt.Errorf("%w: a second tab needs SELENIUM_MAX_SESSIONS of at least 2", godog.ErrPending)
+	}
+	cookies, err := th.wd.GetCookies()
+	if err != nil {
+		return err
+	}
+
+	th.sessions.acquire()
+	wd, err := selenium.NewRemote(th.capabilities, th.seleniumURL)
+	if err != nil {
+		th.sessions.release()
+		return err
+	}
+	// cookies can only be added from a page of their own domain
+	err = wd.Get(fmt.Sprintf("http://%s%s", th.server.Address(), th.server.Path("/")))
+	for i := 0; err == nil && i < len(cookies); i++ {
+		err = wd.AddCookie(&cookies[i])
+	}
+	if err != nil {
+		wd.Quit()
+		th.sessions.release()
+		return err
+	}
+	th.otherWD = wd
+	if err = th.switchesToTab("second"); err != nil {
+		return err
+	}
+	if err = th.setsDefaultViewport(); err != nil {
+		return err
+	}
+	return th.navigateToTheRootView()
+}
+
+// switchesToTab makes the first or second tab opened by opensSecondTab
+// current.
+func (th *TestHarness) switchesToTab(which string) error {
+	if th.otherWD == nil {
+		return fmt.Errorf("expected a second tab to have been opened")
+	}
+	if second := which == "second"; second != th.onSecondTab {
+		th.wd, th.otherWD = th.otherWD, th.wd
+		th.onSecondTab = second
+	}
+	return nil
+}
+
+// returnsToFirstBrowser makes the first browser and its user current again.
+func (th *TestHarness) returnsToFirstBrowser() error {
+	if th.otherWD == nil {
+		return fmt.Errorf("expected a second browser to have been opened")
+	}
+	th.swapBrowsers()
+	return nil
+}
+
+func (th *TestHarness) swapBrowsers() {
+	th.wd, th.otherWD = th.otherWD, th.wd
+	th.currentProfile, th.otherProfile = th.otherProfile, th.currentProfile
+}
+
+// closesSecondBrowser quits whichever browser isn't current and removes its
+// user, after a scenario that opened one.
+func (th *TestHarness) closesSecondBrowser() {
+	if th.otherWD == nil {
+		return
+	}
+	_, _ = th.otherWD.ExecuteScript(`var xhr = new XMLHttpRequest(); xhr.open("POST", arguments[0], false); xhr.send("");`, []interface{}{th.server.Path("/logout")})
+	if err := th.otherWD.Quit(); err != nil {
+		fmt.Printf("AfterScenario error quiting second web driver: %+v\n", err)
+	}
+	th.sessions.release()
+	if th.otherProfile != nil {
+		if err := th.deleteProfileFromOrg(th.otherProfile.UserID); err != nil {
+			fmt.Printf("AfterScenario error destroying second profile: %+v\n", err)
+		}
+	}
+	th.otherWD, th.otherProfile = nil, nil
+	th.onSecondTab = false
+}
@@ -0,0 +1,51 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"os"
+	"strconv"
+)
+
+// sessionLimiter bounds how many WebDriver sessions the harness holds open at
+// once, independent of godog's scenario concurrency, so a shared Selenium
+// Grid isn't overloaded. acquire blocks until a session slot is free.
+type sessionLimiter chan struct{}
+
+func newSessionLimiter(max int) sessionLimiter {
+	if max < 1 {
+		max = 1
+	}
+	return make(sessionLimiter, max)
+}
+
+func (l sessionLimiter) acquire() {
+	l <- struct{}{}
+}
+
+func (l sessionLimiter) release() {
+	<-l
+}
+
+func maxSessions() int {
+	// SELENIUM_MAX_SESSIONS defaults to a single session at a time
+	max, err := strconv.Atoi(os.Getenv("SELENIUM_MAX_SESSIONS"))
+	if err != nil || max < 1 {
+		return 1
+	}
+	return max
+}
@@ -0,0 +1,110 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// protectedResourceURL is where the held access token is tried: the
+// issuer's userinfo endpoint, or HARNESS_PROTECTED_RESOURCE_URL, e.g. an API
+// of the org's own that accepts the token.
+func protectedResourceURL(issuer string) string {
+	if u := os.Getenv("HARNESS_PROTECTED_RESOURCE_URL"); u != "" {
+		return u
+	}
+	issuer = strings.TrimSuffix(issuer, "/")
+	if strings.Contains(issuer, "/oauth2/") {
+		return issuer + "/v1/userinfo"
+	}
+	// the org authorization server keeps its endpoints under /oauth2
+	return issuer + "/oauth2/v1/userinfo"
+}
+
+// fetchWithAccessToken GETs url with token as its bearer and decodes the JSON
+// response, failing with the status and body of anything but a 200.
+func fetchWithAccessToken(client *http.Client, url, token string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("expected 200 from %s with the access token, got %s: %s", url, resp.Status, body)
+	}
+	var claims map[string]interface{}
+	if err = json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("expected JSON from %s: %w", url, err)
+	}
+	return claims, nil
+}
+
+// checkUserinfoClaims checks userinfo describes the signed in user.
+func checkUserinfoClaims(claims map[string]interface{}, email string) error {
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return errors.New("expected userinfo to name the user's sub")
+	}
+	if email == "" {
+		return nil
+	}
+	got, _ := claims["email"].(string)
+	if !strings.EqualFold(got, email) {
+		return fmt.Errorf("expected userinfo email %q, got %q", email, got)
+	}
+	return nil
+}
+
+// accessTokenWorks calls the protected resource with the access token held
+// for the browser's session, proving the token is usable and not just that
+// the page looks signed in.
+func (th *TestHarness) accessTokenWorks() error {
+	if th.accessToken == "" {
+		if err := th.holdsAccessToken(); err != nil {
+			return err
+		}
+	}
+	url := protectedResourceURL(th.server.IdxConfig().Okta.IDX.Issuer)
+	claims, err := fetchWithAccessToken(th.httpClient, url, th.accessToken)
+	if err != nil {
+		return err
+	}
+	if os.Getenv("HARNESS_PROTECTED_RESOURCE_URL") != "" {
+		// an API of the org's own answers with whatever it likes
+		return nil
+	}
+	var email string
+	if th.currentProfile != nil {
+		email = th.currentProfile.EmailAddress
+	}
+	return checkUserinfoClaims(claims, email)
+}
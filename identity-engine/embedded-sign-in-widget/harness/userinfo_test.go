@@ -0,0 +1,72 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProtectedResourceURL(t *testing.T) {
+	defer os.Setenv("HARNESS_PROTECTED_RESOURCE_URL", os.Getenv("HARNESS_PROTECTED_RESOURCE_URL"))
+	os.Setenv("HARNESS_PROTECTED_RESOURCE_URL", "")
+	for issuer, expected := range map[string]string{
+		"https://example.okta.com/oauth2/default":  "https://example.okta.com/oauth2/default/v1/userinfo",
+		"https://example.okta.com/oauth2/default/": "https://example.okta.com/oauth2/default/v1/userinfo",
+		"https://example.okta.com":                 "https://example.okta.com/oauth2/v1/userinfo",
+	} {
+		if got := protectedResourceURL(issuer); got != expected {
+			t.Errorf("issuer %q: expected %q, got %q", issuer, expected, got)
+		}
+	}
+
+	os.Setenv("HARNESS_PROTECTED_RESOURCE_URL", "https://api.example.com/me")
+	if got := protectedResourceURL("https://example.okta.com"); got != "https://api.example.com/me" {
+		t.Errorf("expected the configured URL, got %q", got)
+	}
+}
+
+func TestFetchWithAccessToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_token"}`))
+			return
+		}
+		w.Write([]byte(`{"sub":"00u1","email":"Mary@example.com"}`))
+	}))
+	defer ts.Close()
+
+	claims, err := fetchWithAccessToken(ts.Client(), ts.URL, "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = checkUserinfoClaims(claims, "mary@example.com"); err != nil {
+		t.Error(err)
+	}
+	if err = checkUserinfoClaims(claims, "joe@example.com"); err == nil {
+		t.Error("expected another user's email to fail")
+	}
+
+	_, err = fetchWithAccessToken(ts.Client(), ts.URL, "bad")
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("expected the status and body of the rejection, got %v", err)
+	}
+}
@@ -17,16 +17,22 @@
 package harness
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/tebeka/selenium"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/server"
 )
 
 const (
@@ -41,6 +47,24 @@ func defaultInterval() time.Duration {
 	return time.Duration(time.Second * 3)
 }
 
+func keyDelay() time.Duration {
+	// HARNESS_KEY_DELAY spaces out keys typed one at a time, e.g. 100ms
+	delay, err := time.ParseDuration(os.Getenv("HARNESS_KEY_DELAY"))
+	if err != nil || delay < 0 {
+		return 25 * time.Millisecond
+	}
+	return delay
+}
+
+func defaultViewport() string {
+	// SELENIUM_VIEWPORT is either "maximize" or WIDTHxHEIGHT e.g. 1440x900
+	viewport := os.Getenv("SELENIUM_VIEWPORT")
+	if viewport == "" {
+		viewport = "1440x900"
+	}
+	return viewport
+}
+
 func claims() map[string]string {
 	claimsJSON := os.Getenv("OKTA_IDX_CLAIMS")
 	claims := map[string]string{}
@@ -96,7 +120,11 @@ func (th *TestHarness) clicksSigninWithFacebook() error {
 }
 
 func (th *TestHarness) signsInWithGoogle() error {
-	if err := th.fillsInFormValue(`input[name="identifier"]`, th.currentProfile.EmailAddress, th.waitForGenericForm); err != nil {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	if err := th.fillsInFormValue(`input[name="identifier"]`, profile.EmailAddress, th.waitForGenericForm); err != nil {
 		return err
 	}
 
@@ -104,7 +132,7 @@ func (th *TestHarness) signsInWithGoogle() error {
 		return nil
 	}
 
-	if err := th.fillsInFormValue(`input[name="password"]`, th.currentProfile.Password, th.waitForGenericForm); err != nil {
+	if err := th.fillsInFormValue(`input[name="password"]`, profile.Password, th.waitForGenericForm); err != nil {
 		return err
 	}
 
@@ -116,11 +144,15 @@ func (th *TestHarness) signsInWithGoogle() error {
 }
 
 func (th *TestHarness) signsInWithFacebook() error {
-	if err := th.fillsInFormValue(`input[name="email"]`, th.currentProfile.EmailAddress, th.waitForGenericForm); err != nil {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	if err := th.fillsInFormValue(`input[name="email"]`, profile.EmailAddress, th.waitForGenericForm); err != nil {
 		return err
 	}
 
-	if err := th.fillsInFormValue(`input[name="pass"]`, th.currentProfile.Password, th.waitForGenericForm); err != nil {
+	if err := th.fillsInFormValue(`input[name="pass"]`, profile.Password, th.waitForGenericForm); err != nil {
 		return err
 	}
 
@@ -162,7 +194,7 @@ func (th *TestHarness) clicksButtonWithText(selector, text string) error {
 }
 
 func (th *TestHarness) navigateToTheRootView() error {
-	rootURL := fmt.Sprintf("http://%s/", th.server.Address())
+	rootURL := fmt.Sprintf("http://%s%s", th.server.Address(), th.server.Path("/"))
 	err := th.wd.Get(rootURL)
 	if err != nil {
 		return err
@@ -175,7 +207,25 @@ func (th *TestHarness) isRootView() error {
 	if err := th.seesElementWithText(`h1`, ROOT_VIEW_H1); err != nil {
 		return err
 	}
-	return th.isView("/")
+	return th.isView(th.server.Path("/"))
+}
+
+// deepLinksToProfileView opens the Profile View by its URL, as a bookmark
+// would, rather than through the navigation links.
+func (th *TestHarness) deepLinksToProfileView() error {
+	profileURL := fmt.Sprintf("http://%s%s", th.server.Address(), th.server.Path("/profile"))
+	if err := th.wd.Get(profileURL); err != nil {
+		return err
+	}
+
+	return th.waitForPageRender()
+}
+
+func (th *TestHarness) isProfileView() error {
+	if err := th.seesElementWithText(`h1`, "My Profile"); err != nil {
+		return err
+	}
+	return th.isView(th.server.Path("/profile"))
 }
 
 func (th *TestHarness) isView(path string) error {
@@ -190,10 +240,97 @@ func (th *TestHarness) isView(path string) error {
 	return nil
 }
 
+// isOktaHostedURL reports whether rawURL is on the host of the Okta issuer
+// and, when pathPattern is set, whether its path matches it. Matching the
+// path by pattern tolerates the differences between orgs, e.g. custom
+// authorization server ids.
+func isOktaHostedURL(rawURL, issuer string, pathPattern *regexp.Regexp) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	iss, err := url.Parse(issuer)
+	if err != nil || iss.Host == "" {
+		return false
+	}
+	if !strings.EqualFold(u.Hostname(), iss.Hostname()) {
+		return false
+	}
+	return pathPattern == nil || pathPattern.MatchString(u.Path)
+}
+
+// isOnOktaHostedPage waits for the browser to be handed off to a page on the
+// Okta org whose path matches pattern.
+func (th *TestHarness) isOnOktaHostedPage(pattern string) error {
+	var pathPattern *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if pathPattern, err = regexp.Compile(pattern); err != nil {
+			return err
+		}
+	}
+
+	issuer := th.server.IdxConfig().Okta.IDX.Issuer
+	var currentURL string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		var err error
+		if currentURL, err = th.wd.CurrentURL(); err != nil {
+			return false, nil
+		}
+		return isOktaHostedURL(currentURL, issuer, pathPattern), nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected an Okta-hosted page of %s matching %q, finds %q url", issuer, pattern, currentURL)
+	}
+	return nil
+}
+
 func (th *TestHarness) waitForPageRender() error {
 	return th.seesElement(`html body`)
 }
 
+func (th *TestHarness) navigatesBack() error {
+	if err := th.wd.Back(); err != nil {
+		return err
+	}
+	return th.waitForPageRender()
+}
+
+// remainsSignedIn checks the browser shows the signed in welcome and that no
+// sign-in widget form was re-exposed, e.g. from a cached login page.
+func (th *TestHarness) remainsSignedIn() error {
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elems, err := th.wd.FindElements(selenium.ByCSSSelector, `#content p`)
+		if err != nil {
+			return false, nil
+		}
+
+		for _, elem := range elems {
+			text, err := elem.Text()
+			if err != nil {
+				return false, nil
+			}
+			if strings.HasPrefix(strings.TrimSpace(text), "Welcome back") {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected the signed in welcome: %v", err)
+	}
+
+	forms, err := th.wd.FindElements(selenium.ByCSSSelector, `#okta-signin-widget-container form`)
+	if err != nil {
+		return err
+	}
+	if len(forms) > 0 {
+		return errors.New("the login form is shown again after navigating back")
+	}
+	return nil
+}
+
 func (th *TestHarness) waitForLoginForm() error {
 	err := th.seesElement(`#okta-signin-widget-container`)
 	if err != nil {
@@ -232,13 +369,17 @@ func (th *TestHarness) seesElementWithText(selector, text string) error {
 }
 
 func (th *TestHarness) seesClaimsTableItemAndValueFromCurrentProfile(key string) error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
 	keyID := fmt.Sprintf("%s-value", key)
 	var value string
 	switch {
 	case key == "name":
-		value = th.currentProfile.DisplayName
+		value = profile.DisplayName
 	case key == "email":
-		value = th.currentProfile.EmailAddress
+		value = profile.EmailAddress
 	}
 
 	return th.seesElementIDWithValue(keyID, value)
@@ -271,6 +412,817 @@ func (th *TestHarness) navigateToLogin() error {
 	return th.waitForPageRender()
 }
 
+// isSentBackToLoginWithError opens the login page the way Okta redirects
+// back to it when something goes wrong upstream.
+func (th *TestHarness) isSentBackToLoginWithError(code, description string) error {
+	q := url.Values{"error": {code}, "error_description": {description}}
+	loginURL := fmt.Sprintf("http://%s%s?%s", th.server.Address(), th.server.Path("/login"), q.Encode())
+	if err := th.wd.Get(loginURL); err != nil {
+		return err
+	}
+
+	return th.waitForPageRender()
+}
+
+func (th *TestHarness) seesLoginError(message string) error {
+	return th.seesElementWithText(`#login-error`, message)
+}
+
+func (th *TestHarness) navigatesToActivation(token string) error {
+	activateURL := fmt.Sprintf("http://%s%s?token=%s", th.server.Address(), th.server.Path("/activate"), url.QueryEscape(token))
+	if err := th.wd.Get(activateURL); err != nil {
+		return err
+	}
+
+	return th.waitForPageRender()
+}
+
+func (th *TestHarness) navigatesToInvitedUserActivation() error {
+	if th.activationToken == "" {
+		return errors.New("test harness doesn't have an activation token")
+	}
+	return th.navigatesToActivation(th.activationToken)
+}
+
+// setsActivationPassword picks the password authenticator if the widget
+// offers a choice, then fills in and submits the new password.
+func (th *TestHarness) setsActivationPassword() error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+
+	err = th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		if _, err := th.wd.FindElement(selenium.ByCSSSelector, `input[name="confirmPassword"]`); err == nil {
+			return true, nil
+		}
+		if elem, err := th.wd.FindElement(selenium.ByCSSSelector, `[data-se="okta_password"] .select-factor`); err == nil {
+			_ = elem.Click()
+		}
+		return false, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return err
+	}
+
+	// the widget checks the password against its policy as it's typed
+	if err = th.entersTextSlowly(`input[name="credentials.passcode"]`, profile.Password, keyDelay()); err != nil {
+		return err
+	}
+	if err = th.entersTextSlowly(`input[name="confirmPassword"]`, profile.Password, keyDelay()); err != nil {
+		return err
+	}
+	return th.submitsForm(`input[type="submit"]`, "Next")
+}
+
+var tokenExpiryPattern = regexp.MustCompile(`^(\d+)m (\d+)s$`)
+
+// checkTokenExpiryCountdown checks the text of the token expiry countdown is
+// a plausible time left on a token Okta issued, i.e. up to a day.
+func checkTokenExpiryCountdown(text string) error {
+	m := tokenExpiryPattern.FindStringSubmatch(text)
+	if m == nil {
+		return fmt.Errorf("expected the token expiry as \"Xm Ys\", got %q", text)
+	}
+	minutes, _ := strconv.Atoi(m[1])
+	seconds, _ := strconv.Atoi(m[2])
+	d := time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	if seconds >= 60 || d <= 0 || d > 24*time.Hour {
+		return fmt.Errorf("expected a plausible token expiry, got %q", text)
+	}
+	return nil
+}
+
+func (th *TestHarness) seesTokenExpiryCountdown() error {
+	if err := th.seesElement("#token-expires-in"); err != nil {
+		return err
+	}
+	elem, err := th.wd.FindElement(selenium.ByCSSSelector, "#token-expires-in")
+	if err != nil {
+		return err
+	}
+	text, err := elem.Text()
+	if err != nil {
+		return err
+	}
+	return checkTokenExpiryCountdown(strings.TrimSpace(text))
+}
+
+func (th *TestHarness) seesActivationError() error {
+	return th.seesElement(`#activation-error`)
+}
+
+// fillsInGoogleAuthenticatorCode enters the current TOTP code. The code comes
+// from the seeded secret when there is one, otherwise from the secret the
+// widget shows after "Can't scan?" on the enrollment page.
+func (th *TestHarness) fillsInGoogleAuthenticatorCode() error {
+	secret := th.totpSecret
+	if secret == "" {
+		var err error
+		if secret, err = th.scrapesTOTPSecret(); err != nil {
+			return err
+		}
+	}
+
+	code, err := totpCode(secret, time.Now())
+	if err != nil {
+		return err
+	}
+	if err = th.entersText(`input[name="credentials.passcode"]`, code); err != nil {
+		return err
+	}
+	return th.submitsForm(`input[type="submit"]`, "Verify")
+}
+
+func (th *TestHarness) scrapesTOTPSecret() (string, error) {
+	var secret string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		if elem, err := th.wd.FindElement(selenium.ByCSSSelector, `.shared-key`); err == nil {
+			if text, err := elem.Text(); err == nil && strings.TrimSpace(text) != "" {
+				secret = strings.TrimSpace(text)
+				return true, nil
+			}
+		}
+		if elem, err := th.wd.FindElement(selenium.ByCSSSelector, `.cannot-scan-link`); err == nil {
+			_ = elem.Click()
+		}
+		return false, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return "", fmt.Errorf("no Google Authenticator secret on the page: %w", err)
+	}
+	if elem, err := th.wd.FindElement(selenium.ByCSSSelector, `.button-primary`); err == nil {
+		_ = elem.Click()
+	}
+	return secret, nil
+}
+
+func randomString() string {
+	// Password requirements: at least 8 characters, a lowercase letter, an uppercase letter, a number, no parts of your username
+	digits := "0123456789"
+	lowers := "abcdefghijklmnopqrstuvwxyz"
+	uppers := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	all := "ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+		"abcdefghijklmnopqrstuvwxyz" +
+		digits
+	length := 12
+	buf := make([]byte, length)
+	buf[0] = digits[rand.Intn(len(digits))]
+	buf[1] = lowers[rand.Intn(len(lowers))]
+	buf[2] = uppers[rand.Intn(len(uppers))]
+	for i := 3; i < length; i++ {
+		buf[i] = all[rand.Intn(len(all))]
+	}
+	rand.Shuffle(len(buf), func(i, j int) {
+		buf[i], buf[j] = buf[j], buf[i]
+	})
+	return string(buf)
+}
+
+func (th *TestHarness) requestsSilentAuthentication() error {
+	silentURL := fmt.Sprintf("http://%s%s", th.server.Address(), th.server.Path("/login/silent"))
+	if err := th.wd.Get(silentURL); err != nil {
+		return err
+	}
+
+	return th.waitForPageRender()
+}
+
+func (th *TestHarness) silentAuthenticationReports(state string) error {
+	elem, err := th.wd.FindElement(selenium.ByCSSSelector, `html body`)
+	if err != nil {
+		return err
+	}
+	text, err := elem.Text()
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Authenticated bool   `json:"authenticated"`
+		Error         string `json:"error"`
+	}
+	if err = json.Unmarshal([]byte(text), &result); err != nil {
+		return fmt.Errorf("silent authentication check didn't return JSON %q: %v", text, err)
+	}
+
+	expected := state == "authenticated"
+	if result.Authenticated != expected {
+		return fmt.Errorf("expected silent authentication check to report %s, got %q", state, text)
+	}
+	if !expected && result.Error == "" {
+		return fmt.Errorf("expected silent authentication check to report an error, got %q", text)
+	}
+	return nil
+}
+
+func (th *TestHarness) widgetAssetsUnreachable() error {
+	// nothing listens on the discard port so the asset requests fail fast
+	th.server.Config().WidgetAssetBase = "http://127.0.0.1:9/okta-signin-widget"
+	return nil
+}
+
+// widgetConfigJSON is the widget config embedded in the login page.
+func (th *TestHarness) widgetConfigJSON() (string, error) {
+	if err := th.seesElement(`#okta-signin-widget-config`); err != nil {
+		return "", err
+	}
+	blob, err := th.wd.ExecuteScript(`return document.getElementById("okta-signin-widget-config").textContent;`, nil)
+	if err != nil {
+		return "", err
+	}
+	text, _ := blob.(string)
+	return text, nil
+}
+
+// setsPrompt makes the sign ins of the rest of the scenario send prompt to
+// Okta.
+func (th *TestHarness) setsPrompt(prompt string) error {
+	th.server.Config().Prompt = prompt
+	return nil
+}
+
+// setsWidgetFeature turns a widget feature flag on for the sign ins of the
+// rest of the scenario.
+func (th *TestHarness) setsWidgetFeature(name string) error {
+	cfg := th.server.Config()
+	if cfg.WidgetFeatures == nil {
+		cfg.WidgetFeatures = map[string]bool{}
+	}
+	cfg.WidgetFeatures[name] = true
+	return cfg.Validate()
+}
+
+// checkWidgetFeature checks the widget config turns the feature flag on.
+func checkWidgetFeature(configJSON, name string) error {
+	var widgetConfig struct {
+		Features map[string]bool `json:"features"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &widgetConfig); err != nil {
+		return fmt.Errorf("widget config is not JSON %q: %v", configJSON, err)
+	}
+	if on, ok := widgetConfig.Features[name]; !ok || !on {
+		return fmt.Errorf("expected the widget's %q feature to be on, its features are %v", name, widgetConfig.Features)
+	}
+	return nil
+}
+
+// widgetHasFeature checks the widget config embedded in the login page turns
+// the feature flag on.
+func (th *TestHarness) widgetHasFeature(name string) error {
+	text, err := th.widgetConfigJSON()
+	if err != nil {
+		return err
+	}
+	return checkWidgetFeature(text, name)
+}
+
+// clearsAppSession drops the sample's session cookie but keeps Okta's, as if
+// the sample's session had ended while the user is still signed in to Okta.
+func (th *TestHarness) clearsAppSession() error {
+	return th.wd.DeleteCookie(server.SESSION_STORE_NAME)
+}
+
+// accountChooserIdentifier is where the widget names the account of the Okta
+// session it offers to continue with. A fresh sign in form doesn't name one.
+const accountChooserIdentifier = `#okta-signin-widget-container .identifier-container .identifier`
+
+// seesAccountChooser checks the login page asked the widget to let the user
+// pick an account, and the widget offers the Okta session's account instead
+// of signing it straight back in.
+func (th *TestHarness) seesAccountChooser() error {
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
+	}
+	text, err := th.widgetConfigJSON()
+	if err != nil {
+		return err
+	}
+	var widgetConfig struct {
+		AuthParams struct {
+			Prompt string `json:"prompt"`
+		} `json:"authParams"`
+	}
+	if err = json.Unmarshal([]byte(text), &widgetConfig); err != nil {
+		return fmt.Errorf("widget config is not JSON %q: %v", text, err)
+	}
+	if widgetConfig.AuthParams.Prompt != "select_account" {
+		return fmt.Errorf("expected the widget to be asked for the account chooser, its prompt is %q", widgetConfig.AuthParams.Prompt)
+	}
+	if err = th.seesElementWithText(accountChooserIdentifier, profile.EmailAddress); err != nil {
+		return fmt.Errorf("expected the widget's account chooser to offer %s: %v", profile.EmailAddress, err)
+	}
+	return th.isView(th.server.Path("/login"))
+}
+
+// setsBrowserLanguage restarts the scenario's browser so it sends lang as
+// its Accept-Language; Chrome only reads the preference at startup.
+func (th *TestHarness) setsBrowserLanguage(lang string) error {
+	capabilities := selenium.Capabilities{}
+	for k, v := range th.capabilities {
+		capabilities[k] = v
+	}
+	chromeOptions := map[string]interface{}{}
+	if existing, ok := capabilities["goog:chromeOptions"].(map[string]interface{}); ok {
+		for k, v := range existing {
+			chromeOptions[k] = v
+		}
+	}
+	prefs := map[string]interface{}{}
+	if existing, ok := chromeOptions["prefs"].(map[string]interface{}); ok {
+		for k, v := range existing {
+			prefs[k] = v
+		}
+	}
+	prefs["intl.accept_languages"] = lang
+	chromeOptions["prefs"] = prefs
+	args, _ := chromeOptions["args"].([]interface{})
+	chromeOptions["args"] = append(append([]interface{}{}, args...), "--lang="+lang)
+	capabilities["goog:chromeOptions"] = chromeOptions
+
+	if err := th.wd.Quit(); err != nil {
+		return err
+	}
+	wd, err := selenium.NewRemote(capabilities, th.seleniumURL)
+	if err != nil {
+		return err
+	}
+	th.wd = wd
+	return th.setsDefaultViewport()
+}
+
+// seesWidgetInLanguage checks the login page configured the widget for lang
+// and that the widget's form title is shown translated.
+func (th *TestHarness) seesWidgetInLanguage(lang, title string) error {
+	text, err := th.widgetConfigJSON()
+	if err != nil {
+		return err
+	}
+	var widgetConfig struct {
+		Language string `json:"language"`
+	}
+	if err = json.Unmarshal([]byte(text), &widgetConfig); err != nil {
+		return fmt.Errorf("widget config is not JSON %q: %v", text, err)
+	}
+	if widgetConfig.Language != lang {
+		return fmt.Errorf("expected widget language %q, got %q", lang, widgetConfig.Language)
+	}
+	return th.seesElementWithText(`.okta-form-title`, title)
+}
+
+// seesWidgetConfiguredForApp reads the widget config embedded in the login
+// page and checks it targets the issuer and client the server is set up with.
+func (th *TestHarness) seesWidgetConfiguredForApp() error {
+	text, err := th.widgetConfigJSON()
+	if err != nil {
+		return err
+	}
+
+	var widgetConfig struct {
+		ClientID   string `json:"clientId"`
+		AuthParams struct {
+			Issuer string `json:"issuer"`
+		} `json:"authParams"`
+	}
+	if err = json.Unmarshal([]byte(text), &widgetConfig); err != nil {
+		return fmt.Errorf("widget config is not JSON %q: %v", text, err)
+	}
+
+	idxConfig := th.server.IdxConfig().Okta.IDX
+	if widgetConfig.AuthParams.Issuer != idxConfig.Issuer {
+		return fmt.Errorf("expected widget issuer %q, got %q", idxConfig.Issuer, widgetConfig.AuthParams.Issuer)
+	}
+	if widgetConfig.ClientID != idxConfig.ClientID {
+		return fmt.Errorf("expected widget client id %q, got %q", idxConfig.ClientID, widgetConfig.ClientID)
+	}
+	return nil
+}
+
+var idxRequestPath = regexp.MustCompile(`/idp/idx/`)
+
+// checkFirstIDXRequest checks the first Identity Engine request in urls, in
+// the order the browser made them, introspects the interaction on the
+// issuer's host. A widget with a wrong base URL sends it elsewhere.
+func checkFirstIDXRequest(urls []string, issuer string) error {
+	for _, rawURL := range urls {
+		u, err := url.Parse(rawURL)
+		if err != nil || !idxRequestPath.MatchString(u.Path) {
+			continue
+		}
+		if !strings.HasSuffix(u.Path, "/idp/idx/introspect") {
+			return fmt.Errorf("expected the widget to introspect the interaction first, its first request was %s", rawURL)
+		}
+		if !isOktaHostedURL(rawURL, issuer, nil) {
+			return fmt.Errorf("expected the widget to call the host of %s, it called %s", issuer, rawURL)
+		}
+		return nil
+	}
+	return errors.New("the widget made no Identity Engine requests")
+}
+
+// widgetCallsInteractionEndpoint checks the widget starts from the interaction
+// handle the login page injected, on the configured issuer. The browser's
+// resource timing entries list the widget's requests without a proxy.
+func (th *TestHarness) widgetCallsInteractionEndpoint() error {
+	text, err := th.widgetConfigJSON()
+	if err != nil {
+		return err
+	}
+	var widgetConfig struct {
+		InteractionHandle string `json:"interactionHandle"`
+	}
+	if err = json.Unmarshal([]byte(text), &widgetConfig); err != nil {
+		return fmt.Errorf("widget config is not JSON %q: %v", text, err)
+	}
+	if widgetConfig.InteractionHandle == "" {
+		return errors.New("expected the login page to inject an interaction handle")
+	}
+
+	issuer := th.server.IdxConfig().Okta.IDX.Issuer
+	var urls []string
+	err = th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		entries, err := th.wd.ExecuteScript(`return performance.getEntriesByType("resource").map(function (e) { return e.name; });`, nil)
+		if err != nil {
+			return false, nil
+		}
+		list, _ := entries.([]interface{})
+		urls = urls[:0]
+		for _, entry := range list {
+			if name, ok := entry.(string); ok {
+				urls = append(urls, name)
+			}
+		}
+		return checkFirstIDXRequest(urls, issuer) == nil, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return checkFirstIDXRequest(urls, issuer)
+	}
+	return nil
+}
+
+func (th *TestHarness) misconfiguresRedirectURI() error {
+	// restored in AfterScenario
+	th.server.IdxConfig().Okta.IDX.RedirectURI = fmt.Sprintf("http://%s/not/a/registered/callback", th.server.Address())
+	return nil
+}
+
+func (th *TestHarness) seesRedirectUriMismatchError() error {
+	return th.seesErrorPage(th.server.IdxConfig().Okta.IDX.RedirectURI, "is not one of the app's sign-in redirect URIs")
+}
+
+// seesErrorPage waits for the sample's sign in error page with a message
+// containing each of texts.
+func (th *TestHarness) seesErrorPage(texts ...string) error {
+	var text string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elem, err := th.wd.FindElement(selenium.ByID, "auth-error")
+		if err != nil {
+			return false, nil
+		}
+		if text, err = elem.Text(); err != nil {
+			return false, nil
+		}
+		for _, want := range texts {
+			if !strings.Contains(text, want) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected an error page saying %q but saw %q", texts, text)
+	}
+	return nil
+}
+
+// replaysLoginCallback sends the browser to the callback it signed in with,
+// whose interaction code has been used already.
+func (th *TestHarness) replaysLoginCallback() error {
+	callback := th.server.LastCallbackURI()
+	if callback == "" {
+		return fmt.Errorf("expected the sample to have seen a sign in callback")
+	}
+	if err := th.wd.Get(fmt.Sprintf("http://%s%s", th.server.Address(), callback)); err != nil {
+		return err
+	}
+	return th.waitForPageRender()
+}
+
+// maxInteractionRequiredCallbacks bounds how often the harness answers the
+// callback with interaction_required before calling it a redirect loop.
+const maxInteractionRequiredCallbacks = 10
+
+// callbackKeepsGettingInteractionRequired sends the browser to the callback
+// with interaction_required, the way Okta answers when it wants the user to
+// sign in again, until the sample stops re-rendering the widget for it.
+func (th *TestHarness) callbackKeepsGettingInteractionRequired() error {
+	text, err := th.widgetConfigJSON()
+	if err != nil {
+		return err
+	}
+	var widgetConfig struct {
+		State string `json:"state"`
+	}
+	if err = json.Unmarshal([]byte(text), &widgetConfig); err != nil {
+		return fmt.Errorf("widget config is not JSON %q: %v", text, err)
+	}
+	callback := fmt.Sprintf("http://%s%s?%s", th.server.Address(), th.server.Path("/login/callback"),
+		url.Values{"state": {widgetConfig.State}, "error": {"interaction_required"}}.Encode())
+
+	for i := 0; i < maxInteractionRequiredCallbacks; i++ {
+		if err = th.wd.Get(callback); err != nil {
+			return err
+		}
+		if err = th.waitForPageRender(); err != nil {
+			return err
+		}
+		if _, err = th.wd.FindElement(selenium.ByID, "auth-error"); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected the sample to give up, it re-rendered the widget after %d interaction_required callbacks", maxInteractionRequiredCallbacks)
+}
+
+func (th *TestHarness) seesInteractionRequiredLoopError() error {
+	return th.seesErrorPage("kept asking to sign in again", "Please try again")
+}
+
+func (th *TestHarness) seesInteractionCodeRejected() error {
+	return th.seesErrorPage("invalid_grant", "already completed or has expired")
+}
+
+// browserRequest is a request to the sample carrying the browser's cookies,
+// for looking up what the server keeps for the browser's session.
+func (th *TestHarness) browserRequest() (*http.Request, error) {
+	cookies, err := th.wd.GetCookies()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s", th.server.Address(), th.server.Path("/")), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return req, nil
+}
+
+// notesSessionCookie remembers the browser's session cookie and session id
+// before sign in.
+func (th *TestHarness) notesSessionCookie() error {
+	cookie, err := th.wd.GetCookie(server.SESSION_STORE_NAME)
+	if err != nil {
+		return fmt.Errorf("expected a session cookie before sign in: %w", err)
+	}
+	req, err := th.browserRequest()
+	if err != nil {
+		return err
+	}
+	th.preLoginCookie = cookie.Value
+	th.preLoginSID = th.server.SessionID(req)
+	if th.preLoginSID == "" {
+		return errors.New("expected the session to have an id before sign in")
+	}
+	return nil
+}
+
+// sessionCookieIsRotated checks sign in gave the browser a new session, so a
+// session id fixed before sign in isn't the one holding the tokens.
+func (th *TestHarness) sessionCookieIsRotated() error {
+	if th.preLoginCookie == "" {
+		return errors.New("no session cookie was noted before sign in")
+	}
+	cookie, err := th.wd.GetCookie(server.SESSION_STORE_NAME)
+	if err != nil {
+		return err
+	}
+	if cookie.Value == th.preLoginCookie {
+		return errors.New("expected the session cookie to change on sign in")
+	}
+	req, err := th.browserRequest()
+	if err != nil {
+		return err
+	}
+	if id := th.server.SessionID(req); id == "" || id == th.preLoginSID {
+		return fmt.Errorf("expected a new session id after sign in, found %q", id)
+	}
+	return nil
+}
+
+// notesInteractionHandle remembers the interaction handle the login page
+// was given, read from the server's /debug/session with the browser's
+// cookies.
+func (th *TestHarness) notesInteractionHandle() error {
+	req, err := th.browserRequest()
+	if err != nil {
+		return err
+	}
+	req.URL.Path = th.server.Path("/debug/session")
+	resp, err := th.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var debug struct {
+		InteractionHandle string `json:"interactionHandle"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&debug); err != nil {
+		return fmt.Errorf("expected JSON from /debug/session: %w", err)
+	}
+	th.loginHandles = append(th.loginHandles, debug.InteractionHandle)
+	return nil
+}
+
+// checkDistinctHandles checks every login was given an interaction handle of
+// its own.
+func checkDistinctHandles(handles []string) error {
+	if len(handles) < 2 {
+		return fmt.Errorf("expected the handles of at least two logins to be noted, got %d", len(handles))
+	}
+	seen := make(map[string]bool)
+	for i, handle := range handles {
+		if handle == "" {
+			return fmt.Errorf("login %d had no interaction handle", i+1)
+		}
+		if seen[handle] {
+			return fmt.Errorf("login %d reused the interaction handle %s", i+1, handle)
+		}
+		seen[handle] = true
+	}
+	return nil
+}
+
+func (th *TestHarness) eachLoginGotFreshHandle() error {
+	return checkDistinctHandles(th.loginHandles)
+}
+
+// holdsAccessToken remembers the access token the server keeps for the
+// browser's session so it can be introspected once the user has logged out.
+func (th *TestHarness) holdsAccessToken() error {
+	req, err := th.browserRequest()
+	if err != nil {
+		return err
+	}
+
+	token, ok := th.server.SessionAccessToken(req)
+	if !ok {
+		return errors.New("expected the session to hold an access token")
+	}
+	th.accessToken = token
+	th.tokenSID = th.server.SessionID(req)
+	return nil
+}
+
+// tokensAreCleared checks the server dropped the tokens of the session that
+// held them, not just that the browser looks signed out.
+func (th *TestHarness) tokensAreCleared() error {
+	if th.tokenSID == "" {
+		return errors.New("no session holding tokens was noted before logout")
+	}
+	if th.server.HasSessionTokens(th.tokenSID) {
+		return errors.New("expected logout to clear the session's tokens from the server")
+	}
+	return nil
+}
+
+func (th *TestHarness) refreshesPage() error {
+	if err := th.wd.Refresh(); err != nil {
+		return err
+	}
+	return th.waitForPageRender()
+}
+
+// isSignedOut checks the page offers to log in rather than out, i.e. the
+// server no longer knows the browser's session.
+func (th *TestHarness) isSignedOut() error {
+	if err := th.seesElement(fmt.Sprintf(`a[href="%s"]`, th.server.Path("/login"))); err != nil {
+		return fmt.Errorf("expected the login link: %v", err)
+	}
+	if _, err := th.wd.FindElement(selenium.ByCSSSelector, `#logout-button`); err == nil {
+		return errors.New("expected no logout button once signed out")
+	}
+	return nil
+}
+
+func (th *TestHarness) clicksLogoutButton() error {
+	if err := th.clicksButtonWithText(`#logout-button`, "Logout"); err != nil {
+		return err
+	}
+	return th.waitForPageRender()
+}
+
+// sameLocation reports whether two URLs name the same page. Only their ports
+// and localhost against 127.0.0.1, e.g. behind Selenium, may differ.
+func sameLocation(actual, expected string) bool {
+	a, err := url.Parse(actual)
+	if err != nil {
+		return false
+	}
+	e, err := url.Parse(expected)
+	if err != nil {
+		return false
+	}
+	trim := func(p string) string {
+		if p = strings.TrimSuffix(p, "/"); p == "" {
+			return "/"
+		}
+		return p
+	}
+	host := func(u *url.URL) string {
+		if h := u.Hostname(); h != "127.0.0.1" && h != "::1" {
+			return h
+		}
+		return "localhost"
+	}
+	return a.Scheme == e.Scheme && host(a) == host(e) &&
+		trim(a.Path) == trim(e.Path) && a.RawQuery == e.RawQuery
+}
+
+// setsPostLogoutRedirectURI makes logouts for the rest of the scenario end
+// the Okta session and return to uri, a path on the sample or an absolute
+// URL. It has to be one of the app's sign-out redirect URIs in Okta.
+func (th *TestHarness) setsPostLogoutRedirectURI(uri string) error {
+	if strings.HasPrefix(uri, "/") {
+		uri = fmt.Sprintf("http://%s%s", th.server.Address(), th.server.Path(uri))
+	}
+	cfg := th.server.Config()
+	cfg.PostLogoutRedirectURI = uri
+	cfg.AllowedPostLogoutRedirectURIs = append(cfg.AllowedPostLogoutRedirectURIs, uri)
+	return cfg.Validate()
+}
+
+// landsOnPostLogoutRedirectURI checks logging out ended on the configured
+// post logout redirect URI, or on the Root View when none is configured.
+func (th *TestHarness) landsOnPostLogoutRedirectURI() error {
+	expected := th.server.Config().PostLogoutRedirectURI
+	if expected == "" {
+		expected = fmt.Sprintf("http://%s%s", th.server.Address(), th.server.Path("/"))
+	}
+	var currentURL string
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		var err error
+		if currentURL, err = th.wd.CurrentURL(); err != nil {
+			return false, nil
+		}
+		return sameLocation(currentURL, expected), nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return fmt.Errorf("expected logout to land on %s, it is on %s", expected, currentURL)
+	}
+	return nil
+}
+
+// accessTokenIsRevoked introspects the access token held before logout and
+// expects it to be inactive. Orgs that don't let the client introspect its
+// tokens skip the check rather than fail it.
+func (th *TestHarness) accessTokenIsRevoked() error {
+	if th.accessToken == "" {
+		return errors.New("no access token was held before logout")
+	}
+
+	var active bool
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		var err error
+		active, err = th.server.IntrospectToken(context.Background(), th.accessToken, "access_token")
+		if err != nil {
+			return false, err
+		}
+		return !active, nil
+	}, defaultTimeout(), defaultInterval())
+	if errors.Is(err, server.ErrIntrospectionNotPermitted) {
+		fmt.Printf("WARNING: skipping revocation check, %v\n", err)
+		return nil
+	}
+	if active {
+		return errors.New("expected the access token to be revoked after logout but it is still active")
+	}
+	return err
+}
+
+func (th *TestHarness) seesWidgetLoadError() error {
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elem, err := th.wd.FindElement(selenium.ByID, "okta-signin-widget-fallback")
+		if err != nil {
+			return false, nil
+		}
+
+		displayed, err := elem.IsDisplayed()
+		if err != nil {
+			return false, nil
+		}
+
+		return displayed, nil
+	}, defaultTimeout(), defaultInterval())
+
+	return err
+}
+
+func (th *TestHarness) seesWidgetRetryLink() error {
+	return th.seesElementWithText(fmt.Sprintf(`#okta-signin-widget-fallback a[href=%q]`, th.server.Path("/login")), "Retry")
+}
+
 type waitFor func() error
 
 func (th *TestHarness) fillsInFormValue(selector, value string, waitForForm waitFor) error {
@@ -295,17 +1247,19 @@ func (th *TestHarness) existingUser() error {
 }
 
 func (th *TestHarness) fillsInUsername() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="identifier"]`, th.currentProfile.EmailAddress, th.waitForLoginForm)
+	return th.fillsInFormValue(`input[name="identifier"]`, profile.EmailAddress, th.waitForLoginForm)
 }
 
 func (th *TestHarness) fillsInPassword() error {
-	if th.currentProfile == nil {
-		return errors.New("test harness doesn't have a current profile")
+	profile, err := th.mustCurrentProfile()
+	if err != nil {
+		return err
 	}
-	return th.fillsInFormValue(`input[name="credentials.passcode"]`, th.currentProfile.Password, th.waitForLoginForm)
+	return th.fillsInFormValue(`input[name="credentials.passcode"]`, profile.Password, th.waitForLoginForm)
 }
 
 func (th *TestHarness) seesElement(selector string) error {
@@ -385,6 +1339,31 @@ func (th *TestHarness) entersText(selector, text string) error {
 	return err
 }
 
+// entersTextSlowly types text one key at a time, perCharDelay apart, for
+// widget inputs whose debounced validation, e.g. the password strength check,
+// misbehaves when every key arrives at once.
+func (th *TestHarness) entersTextSlowly(selector, text string, perCharDelay time.Duration) error {
+	var elem selenium.WebElement
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		var err error
+		if elem, err = th.wd.FindElement(selenium.ByCSSSelector, selector); err != nil {
+			return false, nil
+		}
+		return true, elem.Clear()
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return err
+	}
+
+	for _, key := range text {
+		if err = elem.SendKeys(string(key)); err != nil {
+			return err
+		}
+		time.Sleep(perCharDelay)
+	}
+	return nil
+}
+
 func (th *TestHarness) clicksInputWithValue(selector, value string) error {
 	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
 		elem, err := th.wd.FindElement(selenium.ByCSSSelector, selector)
@@ -466,16 +1445,51 @@ func (th *TestHarness) destroyCurrentProfile() error {
 	return err
 }
 
+// mustCurrentProfile returns the profile established by a Given step, or an
+// error if the scenario hasn't set one up.
+func (th *TestHarness) mustCurrentProfile() (*A18NProfile, error) {
+	if th.currentProfile == nil {
+		return nil, errors.New("test harness doesn't have a current profile")
+	}
+	return th.currentProfile, nil
+}
+
+// debugSleep pauses a scenario, e.g. "And sleep 60s", for local debugging.
+// It only sleeps when HARNESS_ALLOW_SLEEP=true so a step left in a scenario
+// doesn't slow down CI.
 func (th *TestHarness) debugSleep(amount string) error {
-	// And sleep 60s
 	d, err := time.ParseDuration(amount)
 	if err != nil {
 		return err
 	}
+	if os.Getenv("HARNESS_ALLOW_SLEEP") != "true" {
+		fmt.Printf("WARNING: skipping sleep %s, set HARNESS_ALLOW_SLEEP=true to allow it\n", d)
+		return nil
+	}
 	time.Sleep(d)
 	return nil
 }
 
+func (th *TestHarness) setsDefaultViewport() error {
+	viewport := defaultViewport()
+	if viewport == "maximize" {
+		return th.maximizesViewport()
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(viewport, "%dx%d", &width, &height); err != nil {
+		return fmt.Errorf("unable to parse viewport %q: %v", viewport, err)
+	}
+	return th.setsViewport(width, height)
+}
+
+func (th *TestHarness) setsViewport(width, height int) error {
+	return th.wd.ResizeWindow("", width, height)
+}
+
+func (th *TestHarness) maximizesViewport() error {
+	return th.wd.MaximizeWindow("")
+}
+
 func (th *TestHarness) deleteProfile(profile *A18NProfile) error {
 	if profile.URL == "" {
 		return nil
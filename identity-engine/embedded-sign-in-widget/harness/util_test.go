@@ -0,0 +1,141 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestIsOktaHostedURL(t *testing.T) {
+	const issuer = "https://example.okta.com/oauth2/default"
+	authorize := regexp.MustCompile(`^/oauth2/[^/]+/v1/authorize`)
+
+	tests := []struct {
+		url     string
+		pattern *regexp.Regexp
+		hosted  bool
+	}{
+		{"https://example.okta.com/signin/verify", nil, true},
+		{"https://EXAMPLE.okta.com:443/sso/idps/0oa1", nil, true},
+		{"https://example.okta.com/oauth2/aus1/v1/authorize?idp=0oa1", authorize, true},
+		{"https://example.okta.com/sso/idps/0oa1", authorize, false},
+		{"http://localhost:8000/login", nil, false},
+		{"https://accounts.google.com/o/oauth2/auth", nil, false},
+		{"https://example.okta.com.evil.test/signin", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isOktaHostedURL(tt.url, issuer, tt.pattern); got != tt.hosted {
+			t.Errorf("isOktaHostedURL(%q) = %t, expected %t", tt.url, got, tt.hosted)
+		}
+	}
+}
+
+func TestCheckFirstIDXRequest(t *testing.T) {
+	const issuer = "https://example.okta.com/oauth2/default"
+	assets := "https://global.oktacdn.com/okta-signin-widget/5.5.2/js/okta-sign-in.min.js"
+
+	tests := []struct {
+		urls []string
+		ok   bool
+	}{
+		{[]string{assets, "https://example.okta.com/idp/idx/introspect", "https://example.okta.com/idp/idx/identify"}, true},
+		{[]string{assets, "https://wrong.okta.com/idp/idx/introspect"}, false},
+		{[]string{"https://example.okta.com/idp/idx/identify", "https://example.okta.com/idp/idx/introspect"}, false},
+		{[]string{assets}, false},
+	}
+	for _, tt := range tests {
+		if err := checkFirstIDXRequest(tt.urls, issuer); (err == nil) != tt.ok {
+			t.Errorf("checkFirstIDXRequest(%v) = %v, expected ok %t", tt.urls, err, tt.ok)
+		}
+	}
+}
+
+func TestKeyDelay(t *testing.T) {
+	defer os.Setenv("HARNESS_KEY_DELAY", os.Getenv("HARNESS_KEY_DELAY"))
+	for value, expected := range map[string]time.Duration{
+		"":      25 * time.Millisecond,
+		"100ms": 100 * time.Millisecond,
+		"0s":    0,
+		"-1s":   25 * time.Millisecond,
+		"slow":  25 * time.Millisecond,
+	} {
+		os.Setenv("HARNESS_KEY_DELAY", value)
+		if got := keyDelay(); got != expected {
+			t.Errorf("HARNESS_KEY_DELAY=%q: expected %s, got %s", value, expected, got)
+		}
+	}
+}
+
+func TestCheckTokenExpiryCountdown(t *testing.T) {
+	for _, text := range []string{"59m 7s", "0m 1s", "1440m 0s"} {
+		if err := checkTokenExpiryCountdown(text); err != nil {
+			t.Errorf("expected %q to be plausible, got %v", text, err)
+		}
+	}
+	for _, text := range []string{"", "59 minutes", "0m 0s", "5m 60s", "1441m 0s", "-1m 5s"} {
+		if err := checkTokenExpiryCountdown(text); err == nil {
+			t.Errorf("expected %q not to be plausible", text)
+		}
+	}
+}
+
+func TestCheckDistinctHandles(t *testing.T) {
+	if err := checkDistinctHandles([]string{"handle-1", "handle-2"}); err != nil {
+		t.Errorf("expected distinct handles to pass, got %v", err)
+	}
+	for _, handles := range [][]string{nil, {"handle-1"}, {"handle-1", "handle-1"}, {"handle-1", ""}} {
+		if err := checkDistinctHandles(handles); err == nil {
+			t.Errorf("expected %q to fail", handles)
+		}
+	}
+}
+
+func TestCheckWidgetFeature(t *testing.T) {
+	config := `{"clientId":"abc","features":{"registration":true,"rememberMe":false}}`
+	if err := checkWidgetFeature(config, "registration"); err != nil {
+		t.Error(err)
+	}
+	for _, name := range []string{"rememberMe", "selfServiceUnlock"} {
+		if err := checkWidgetFeature(config, name); err == nil {
+			t.Errorf("expected %q not to be on", name)
+		}
+	}
+}
+
+func TestSameLocation(t *testing.T) {
+	for _, tt := range []struct {
+		actual, expected string
+		same             bool
+	}{
+		{"http://127.0.0.1:8000/", "http://localhost:8000/", true},
+		{"http://localhost:8000", "http://localhost:8000/", true},
+		{"http://127.0.0.1:41234/signed-out/", "http://localhost:8000/signed-out", true},
+		{"https://app.example.com/signed-out", "https://app.example.com:443/signed-out", true},
+		{"https://app.example.com/signed-out/", "http://localhost:8000/signed-out", false},
+		{"https://evil.example.com/signed-out", "https://app.example.com/signed-out", false},
+		{"https://localhost:8000/", "http://localhost:8000/", false},
+		{"http://localhost:8000/profile", "http://localhost:8000/", false},
+		{"http://localhost:8000/?error=x", "http://localhost:8000/", false},
+	} {
+		if got := sameLocation(tt.actual, tt.expected); got != tt.same {
+			t.Errorf("sameLocation(%q, %q) = %t, expected %t", tt.actual, tt.expected, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/log"
+)
+
+const (
+	invalidInteractionHandle = "invalid-interaction-handle"
+	// widgetErrorLogPrefix starts the console error the login page logs when
+	// the widget fails to start
+	widgetErrorLogPrefix = "Sign-in widget error"
+)
+
+// withBrowserLogging asks the browser to keep its console messages so steps
+// can read them, unless the capabilities already set a logging preference.
+func withBrowserLogging(capabilities selenium.Capabilities) {
+	if _, ok := capabilities["goog:loggingPrefs"]; !ok {
+		capabilities["goog:loggingPrefs"] = map[string]string{string(log.Browser): string(log.All)}
+	}
+}
+
+// browserLog reads the console messages logged since it was last read.
+func (th *TestHarness) browserLog() ([]string, error) {
+	messages, err := th.wd.Log(log.Browser)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(messages))
+	for _, m := range messages {
+		lines = append(lines, fmt.Sprintf("%s %s", m.Level, m.Message))
+	}
+	return lines, nil
+}
+
+// firstLineWith returns the first line that contains text.
+func firstLineWith(lines []string, text string) (string, bool) {
+	for _, line := range lines {
+		if strings.Contains(line, text) {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+// opensLoginWithInvalidInteractionHandle hands the login page an interaction
+// handle Okta never issued through the DEBUG only injection. DEBUG is only
+// turned on while the page is requested.
+func (th *TestHarness) opensLoginWithInvalidInteractionHandle() error {
+	debug, set := os.LookupEnv("DEBUG")
+	defer func() {
+		if set {
+			os.Setenv("DEBUG", debug)
+		} else {
+			os.Unsetenv("DEBUG")
+		}
+	}()
+	if err := os.Setenv("DEBUG", "true"); err != nil {
+		return err
+	}
+
+	q := url.Values{"interaction_handle": {invalidInteractionHandle}}
+	loginURL := fmt.Sprintf("http://%s%s?%s", th.server.Address(), th.server.Path("/login"), q.Encode())
+	if err := th.wd.Get(loginURL); err != nil {
+		return err
+	}
+	return th.waitForPageRender()
+}
+
+// seesWidgetError checks the login page shows its error state instead of a
+// blank or hanging widget.
+func (th *TestHarness) seesWidgetError() error {
+	err := th.wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
+		elem, err := th.wd.FindElement(selenium.ByID, "okta-signin-widget-error")
+		if err != nil {
+			return false, nil
+		}
+		displayed, err := elem.IsDisplayed()
+		if err != nil {
+			return false, nil
+		}
+		return displayed, nil
+	}, defaultTimeout(), defaultInterval())
+	if err != nil {
+		return errors.New("expected the login page to show that signing in could not be started")
+	}
+	return th.seesElementWithText(fmt.Sprintf(`#okta-signin-widget-error a[href=%q]`, th.server.Path("/login")), "Try again")
+}
+
+// browserLogShowsWidgetError checks the console says why the widget failed.
+func (th *TestHarness) browserLogShowsWidgetError() error {
+	lines, err := th.browserLog()
+	if err != nil {
+		return fmt.Errorf("could not read the browser log: %w", err)
+	}
+	if _, ok := firstLineWith(lines, widgetErrorLogPrefix); !ok {
+		return fmt.Errorf("expected the browser log to say why the widget failed, got %q", lines)
+	}
+	return nil
+}
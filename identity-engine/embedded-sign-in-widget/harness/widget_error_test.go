@@ -0,0 +1,53 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package harness
+
+import (
+	"testing"
+
+	"github.com/tebeka/selenium"
+)
+
+func TestWithBrowserLogging(t *testing.T) {
+	capabilities := selenium.Capabilities{"browserName": "chrome"}
+	withBrowserLogging(capabilities)
+	prefs, ok := capabilities["goog:loggingPrefs"].(map[string]string)
+	if !ok || prefs["browser"] != "ALL" {
+		t.Errorf("expected the browser log to be kept, got %v", capabilities["goog:loggingPrefs"])
+	}
+
+	custom := map[string]string{"browser": "SEVERE"}
+	capabilities = selenium.Capabilities{"goog:loggingPrefs": custom}
+	withBrowserLogging(capabilities)
+	if prefs := capabilities["goog:loggingPrefs"].(map[string]string); prefs["browser"] != "SEVERE" {
+		t.Errorf("expected the configured logging preference to be kept, got %v", prefs)
+	}
+}
+
+func TestFirstLineWith(t *testing.T) {
+	lines := []string{
+		"INFO widget loaded",
+		`SEVERE console-api 1:2 "Sign-in widget error: The interaction handle is invalid"`,
+	}
+	line, ok := firstLineWith(lines, widgetErrorLogPrefix)
+	if !ok || line != lines[1] {
+		t.Errorf("expected the widget error line, got %q", line)
+	}
+	if _, ok := firstLineWith(lines, "nothing like this"); ok {
+		t.Error("expected no line to match")
+	}
+}
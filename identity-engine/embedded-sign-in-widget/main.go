@@ -17,6 +17,14 @@
 package main
 
 import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/config"
 	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/server"
 )
@@ -30,7 +38,60 @@ var App *application
 
 func main() {
 	App = &application{}
-	cfg := &config.Config{}
+	interactParams, err := url.ParseQuery(os.Getenv("INTERACT_PARAMS"))
+	if err != nil {
+		log.Fatalf("INTERACT_PARAMS error: %+v", err)
+	}
+	widgetFeatures, err := config.ParseWidgetFeatures(os.Getenv("WIDGET_FEATURES"))
+	if err != nil {
+		log.Fatalf("WIDGET_FEATURES error: %+v", err)
+	}
+	shutdownTracing, err := server.SetupTracing(context.Background())
+	if err != nil {
+		log.Fatalf("tracing setup error: %+v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	cfg := &config.Config{
+		WidgetAssetBase:       os.Getenv("WIDGET_ASSET_BASE"),
+		AccessTokenAudience:   os.Getenv("OKTA_OAUTH2_AUDIENCE"),
+		BasePath:              os.Getenv("BASE_PATH"),
+		CookieDomain:          os.Getenv("COOKIE_DOMAIN"),
+		CookiePath:            os.Getenv("COOKIE_PATH"),
+		InteractParams:        interactParams,
+		PostLogoutRedirectURI: os.Getenv("POST_LOGOUT_REDIRECT_URI"),
+		ResponseType:          os.Getenv("RESPONSE_TYPE"),
+		ResponseMode:          os.Getenv("RESPONSE_MODE"),
+		Prompt:                os.Getenv("PROMPT"),
+		SessionDir:            os.Getenv("SESSION_DIR"),
+		SessionKeys:           config.ParseSessionKeys(os.Getenv("SESSION_KEYS")),
+		WidgetFeatures:        widgetFeatures,
+	}
+	if uris := os.Getenv("ALLOWED_POST_LOGOUT_REDIRECT_URIS"); uris != "" {
+		cfg.AllowedPostLogoutRedirectURIs = strings.Split(uris, ",")
+	}
+	if issuers := os.Getenv("ALLOWED_ISSUERS"); issuers != "" {
+		cfg.AllowedIssuers = strings.Split(issuers, ",")
+	}
+	cfg.AutoRefresh = true
+	if autoRefresh := os.Getenv("AUTO_REFRESH"); autoRefresh != "" {
+		cfg.AutoRefresh, err = strconv.ParseBool(autoRefresh)
+		if err != nil {
+			log.Fatalf("AUTO_REFRESH must be true or false, got %q", autoRefresh)
+		}
+	}
+	if timeout := os.Getenv("IDX_TIMEOUT"); timeout != "" {
+		cfg.IDXTimeout, err = time.ParseDuration(timeout)
+		if err != nil || cfg.IDXTimeout <= 0 {
+			log.Fatalf("IDX_TIMEOUT must be a positive duration, e.g. 10s, got %q", timeout)
+		}
+	}
+	if retries := os.Getenv("IDX_RETRIES"); retries != "" {
+		cfg.IDXRetries, err = strconv.Atoi(retries)
+		if err != nil || cfg.IDXRetries < 0 {
+			log.Fatalf("IDX_RETRIES must be zero or a positive number, got %q", retries)
+		}
+	}
 	server := server.NewServer(cfg)
 
 	server.Run()
@@ -0,0 +1,116 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutesHonorBasePath(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.config.BasePath = "/auth/"
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+	router := s.router()
+
+	tests := []struct {
+		method   string
+		path     string
+		bearer   string
+		status   int
+		location string
+	}{
+		{http.MethodGet, "/auth", "", http.StatusMovedPermanently, "/auth/"},
+		{http.MethodGet, "/auth/login", "valid-token", http.StatusFound, "/auth/"},
+		{http.MethodPost, "/auth/logout", "", http.StatusFound, "/auth/"},
+		{http.MethodGet, "/login", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.bearer)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+		}
+		if location := rec.Header().Get("Location"); location != tt.location {
+			t.Errorf("%s %s: expected location %q, got %q", tt.method, tt.path, tt.location, location)
+		}
+	}
+}
+
+func TestLoginCompletesUnderBasePath(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/default/v1/interact":
+			fmt.Fprint(w, `{"interaction_handle":"handle"}`)
+		case "/oauth2/default/v1/token":
+			fmt.Fprint(w, `{"access_token":"valid-token","id_token":"valid-id-token"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.config.BasePath = "/auth/"
+	s.state = "state"
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+	router := s.router()
+
+	// the widget page starts the interaction and the session under the base path
+	req := httptest.NewRequest(http.MethodGet, "/auth/login", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the sign in page, got %d: %s", rec.Code, rec.Body.String())
+	}
+	cookies := rec.Result().Cookies()
+
+	// Okta sends the user back to the callback under the base path
+	req = httptest.NewRequest(http.MethodGet, "/auth/login/callback?state=state&interaction_code=code", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect after sign in, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if location := rec.Header().Get("Location"); location != "/auth/" {
+		t.Errorf("expected the redirect to land on /auth/, got %q", location)
+	}
+
+	// the signed in session is recognized under the base path
+	req = httptest.NewRequest(http.MethodGet, "/auth/login", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	if !s.isAuthenticated(req) {
+		t.Fatal("expected the session to be signed in after the callback")
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/auth/" {
+		t.Errorf("expected the signed in user to be sent to /auth/, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,56 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// interactionHandleKey keeps the interaction handle a session's login page
+// was given, so concurrent and repeated logins don't share one.
+const interactionHandleKey = "interaction_handle"
+
+func sessionInteractionHandle(session *sessions.Session) string {
+	if session == nil {
+		return ""
+	}
+	handle, _ := session.Values[interactionHandleKey].(string)
+	return handle
+}
+
+// DebugSessionHandler shows the browser session's id and interaction handle
+// while testing, e.g. so the harness can check every login gets a fresh
+// handle. Tokens are never shown.
+func (s *Server) DebugSessionHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "session_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(struct {
+		SessionID         string `json:"sessionId"`
+		InteractionHandle string `json:"interactionHandle"`
+	}{
+		SessionID:         sessionID(session),
+		InteractionHandle: sessionInteractionHandle(session),
+	})
+}
@@ -0,0 +1,75 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEachLoginGetsItsOwnInteractionHandle(t *testing.T) {
+	interactions := 0
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		interactions++
+		fmt.Fprintf(w, `{"interaction_handle":"handle-%d"}`, interactions)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.config.Testing = true
+	router := s.router()
+
+	login := func() string {
+		rec := httptest.NewRecorder()
+		if _, err := s.prepareLogin(rec, httptest.NewRequest(http.MethodGet, "/login", nil), nil); err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/debug/session", nil)
+		for _, c := range rec.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var debug struct {
+			SessionID         string `json:"sessionId"`
+			InteractionHandle string `json:"interactionHandle"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &debug); err != nil {
+			t.Fatalf("expected JSON from /debug/session, got %s", rec.Body.String())
+		}
+		return debug.InteractionHandle
+	}
+
+	first, second := login(), login()
+	if first != "handle-1" || second != "handle-2" {
+		t.Errorf("expected a fresh handle per login, got %q and %q", first, second)
+	}
+}
+
+func TestDebugSessionOnlyWhileTesting(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/debug/session", nil)
+	req.Header.Set("Accept", "application/json")
+	s.router().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected /debug/session to be missing outside of testing, got %d", rec.Code)
+	}
+}
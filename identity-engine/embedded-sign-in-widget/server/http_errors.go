@@ -0,0 +1,70 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// renderHTTPError answers a request the router has no route for with the
+// styled error page, or a JSON error for API clients.
+func (s *Server) renderHTTPError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if wantsJSON(r) {
+		writeJSONError(w, status, code, message)
+		return
+	}
+
+	type customData struct {
+		IsAuthenticated bool
+		Title           string
+		Message         string
+	}
+
+	s.render(w, status, "httpError.gohtml", customData{
+		IsAuthenticated: s.isAuthenticated(r),
+		Title:           http.StatusText(status),
+		Message:         message,
+	})
+}
+
+// notFoundHandler redirects a GET with a trailing slash to the route without
+// it, e.g. /login/ to /login, and renders a 404 for anything else.
+func (s *Server) notFoundHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if trimmed := strings.TrimRight(r.URL.Path, "/"); (r.Method == http.MethodGet || r.Method == http.MethodHead) && trimmed != r.URL.Path && trimmed != "" {
+			alt := r.Clone(r.Context())
+			alt.URL.Path = trimmed
+			var match mux.RouteMatch
+			if router.Match(alt, &match) && match.MatchErr == nil {
+				target := *r.URL
+				target.Path = trimmed
+				target.RawPath = ""
+				http.Redirect(w, r, target.RequestURI(), http.StatusMovedPermanently)
+				return
+			}
+		}
+		s.renderHTTPError(w, r, http.StatusNotFound, "not_found", "There is no page at this address.")
+	})
+}
+
+func (s *Server) methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	s.renderHTTPError(w, r, http.StatusMethodNotAllowed, "method_not_allowed", fmt.Sprintf("This page does not accept %s requests.", r.Method))
+}
@@ -0,0 +1,96 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUnroutedRequestsRenderErrorPage(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+	router := s.router()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		title  string
+	}{
+		{http.MethodGet, "/missing", http.StatusNotFound, "Not Found"},
+		{http.MethodPost, "/login/", http.StatusNotFound, "Not Found"},
+		{http.MethodGet, "/logout", http.StatusMethodNotAllowed, "Method Not Allowed"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, `id="http-error"`) || !strings.Contains(body, "<h1>"+tt.title+"</h1>") {
+			t.Errorf("%s %s: expected the styled error page, got %s", tt.method, tt.path, body)
+		}
+	}
+}
+
+func TestUnroutedRequestsAnswerAPIClientsWithJSON(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	router := s.router()
+
+	tests := map[string]struct {
+		method string
+		status int
+		code   string
+	}{
+		"/missing": {http.MethodGet, http.StatusNotFound, "not_found"},
+		"/logout":  {http.MethodGet, http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+	for path, tt := range tests {
+		req := httptest.NewRequest(tt.method, path, nil)
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", path, tt.status, rec.Code)
+		}
+		if body := decodeJSONError(t, rec); body.Error != tt.code {
+			t.Errorf("%s: unexpected error %+v", path, body)
+		}
+	}
+}
+
+func TestTrailingSlashRedirects(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.config.BasePath = "/auth"
+	router := s.router()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/profile/?tab=claims", nil))
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected a redirect, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/auth/profile?tab=claims" {
+		t.Errorf("expected the path without the trailing slash, got %q", location)
+	}
+}
@@ -0,0 +1,37 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/config"
+	"github.com/okta/samples-golang/identity-engine/internal/idxhttp"
+)
+
+// newIDXHTTPClient is the client for calls to Okta, with the configured
+// timeout and retries.
+func newIDXHTTPClient(c *config.Config) *http.Client {
+	timeout := idxhttp.DefaultTimeout
+	if c.IDXTimeout > 0 {
+		timeout = c.IDXTimeout
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &UserAgentTransport{Base: &idxhttp.RetryTransport{Base: &TracingTransport{}, Retries: c.IDXRetries}},
+	}
+}
@@ -0,0 +1,258 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetInteractionHandleSendsExtraParams(t *testing.T) {
+	var form url.Values
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		form = r.PostForm
+		fmt.Fprint(w, `{"interaction_handle":"handle"}`)
+	}))
+	defer okta.Close()
+
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.state = "state"
+	s.config.InteractParams = url.Values{"custom": {"configured"}, "state": {"forged"}}
+
+	handle, err := s.getInteractionHandle(context.Background(), "challenge", "", url.Values{"activation_token": {"token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handle != "handle" {
+		t.Errorf("unexpected interaction handle %q", handle)
+	}
+
+	if got := form.Get("custom"); got != "configured" {
+		t.Errorf("expected the configured param, got %q", got)
+	}
+	if got := form.Get("activation_token"); got != "token" {
+		t.Errorf("expected the extra param, got %q", got)
+	}
+	if got := form.Get("state"); got != "state" {
+		t.Errorf("expected extra params not to replace state, got %q", got)
+	}
+	if got := form.Get("code_challenge"); got != "challenge" {
+		t.Errorf("unexpected code_challenge %q", got)
+	}
+}
+
+func TestResponseTypeAndModeAreSent(t *testing.T) {
+	var form url.Values
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		form = r.PostForm
+		fmt.Fprint(w, `{"interaction_handle":"handle"}`)
+	}))
+	defer okta.Close()
+
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.config.ResponseType = "code id_token"
+	s.config.ResponseMode = "form_post"
+
+	if _, err := s.getInteractionHandle(context.Background(), "challenge", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := form.Get("response_type"); got != "code id_token" {
+		t.Errorf("unexpected response_type %q", got)
+	}
+	if got := form.Get("response_mode"); got != "form_post" {
+		t.Errorf("unexpected response_mode %q", got)
+	}
+
+	tpl := template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+	var page bytes.Buffer
+	if err := tpl.ExecuteTemplate(&page, "login.gohtml", &loginData{Pkce: &PKCE{}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"responseType": "code id_token"`, `"responseMode": "form_post"`} {
+		if !strings.Contains(page.String(), expected) {
+			t.Errorf("expected the widget config to contain %s", expected)
+		}
+	}
+}
+
+func TestResponseTypeAndModeDefaultToOkta(t *testing.T) {
+	var form url.Values
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		fmt.Fprint(w, `{"interaction_handle":"handle"}`)
+	}))
+	defer okta.Close()
+
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	if _, err := s.getInteractionHandle(context.Background(), "challenge", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := form["response_type"]; ok {
+		t.Error("expected no response_type by default")
+	}
+	if _, ok := form["response_mode"]; ok {
+		t.Error("expected no response_mode by default")
+	}
+}
+
+func TestLoginUsesInjectedInteractionHandleInDebug(t *testing.T) {
+	defer os.Setenv("DEBUG", os.Getenv("DEBUG"))
+	os.Setenv("DEBUG", "true")
+	interacted := false
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		interacted = true
+		fmt.Fprint(w, `{"interaction_handle":"fresh-handle"}`)
+	}))
+	defer okta.Close()
+
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.state = "state"
+	req := httptest.NewRequest(http.MethodGet, "/login?interaction_handle=injected-handle&state=injected-state&code_verifier=verifier", nil)
+	data, err := s.prepareLogin(httptest.NewRecorder(), req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _ := s.sessionStore.Get(req, SESSION_STORE_NAME)
+
+	if interacted {
+		t.Error("expected interact to be skipped")
+	}
+	if data.InteractionHandle != "injected-handle" || sessionInteractionHandle(session) != "injected-handle" {
+		t.Errorf("expected the injected interaction handle, got %q", data.InteractionHandle)
+	}
+	if data.State != "injected-state" || s.state != "injected-state" {
+		t.Errorf("expected the injected state, got %q", data.State)
+	}
+	if want := pkceFromVerifier("verifier"); data.Pkce.CodeVerifier != "verifier" || data.Pkce.CodeChallenge != want.CodeChallenge {
+		t.Errorf("expected PKCE data for the injected verifier, got %+v", data.Pkce)
+	}
+}
+
+func TestLoginIgnoresInjectedInteractionHandleWithoutDebug(t *testing.T) {
+	defer os.Setenv("DEBUG", os.Getenv("DEBUG"))
+	os.Setenv("DEBUG", "")
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"interaction_handle":"fresh-handle"}`)
+	}))
+	defer okta.Close()
+
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	req := httptest.NewRequest(http.MethodGet, "/login?interaction_handle=injected-handle", nil)
+	data, err := s.prepareLogin(httptest.NewRecorder(), req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.InteractionHandle != "fresh-handle" {
+		t.Errorf("expected interact's handle, got %q", data.InteractionHandle)
+	}
+}
+
+func TestLoginSendsConfiguredPrompt(t *testing.T) {
+	var form url.Values
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		fmt.Fprint(w, `{"interaction_handle":"handle"}`)
+	}))
+	defer okta.Close()
+
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	tpl := template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+	login := func() string {
+		data, err := s.prepareLogin(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/login", nil), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page bytes.Buffer
+		if err := tpl.ExecuteTemplate(&page, "login.gohtml", data); err != nil {
+			t.Fatal(err)
+		}
+		return page.String()
+	}
+
+	if page := login(); form.Get("prompt") != "" || strings.Contains(page, `"prompt"`) {
+		t.Errorf("expected no prompt by default, got %q", form.Get("prompt"))
+	}
+
+	s.config.Prompt = "select_account"
+	page := login()
+	if got := form.Get("prompt"); got != "select_account" {
+		t.Errorf("expected prompt select_account on interact, got %q", got)
+	}
+	if !strings.Contains(page, `"prompt": "select_account"`) {
+		t.Error("expected the widget config to contain the prompt")
+	}
+}
+
+func TestLoginSendsWidgetFeatures(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"interaction_handle":"handle"}`)
+	}))
+	defer okta.Close()
+
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	tpl := template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+	login := func() string {
+		data, err := s.prepareLogin(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/login", nil), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page bytes.Buffer
+		if err := tpl.ExecuteTemplate(&page, "login.gohtml", data); err != nil {
+			t.Fatal(err)
+		}
+		return page.String()
+	}
+
+	if page := login(); strings.Contains(page, `"features"`) {
+		t.Error("expected no features by default")
+	}
+
+	s.config.WidgetFeatures = map[string]bool{"registration": true, "rememberMe": false}
+	page := login()
+	start := strings.Index(page, `<script type="application/json" id="okta-signin-widget-config">`)
+	end := strings.Index(page[start:], "</script>")
+	if start < 0 || end < 0 {
+		t.Fatalf("expected the widget config in %s", page)
+	}
+	configJSON := page[start+len(`<script type="application/json" id="okta-signin-widget-config">`) : start+end]
+	var config struct {
+		Features map[string]bool `json:"features"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		t.Fatalf("expected the widget config to be JSON: %v\n%s", err, configJSON)
+	}
+	if !config.Features["registration"] || config.Features["rememberMe"] || len(config.Features) != 2 {
+		t.Errorf("expected the configured features, got %v", config.Features)
+	}
+}
@@ -0,0 +1,54 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"net/http"
+)
+
+// rejectedInteractionCodeMessage explains an invalid_grant from the token
+// endpoint on the callback: the interaction code is single-use and short
+// lived, so the callback was replayed, e.g. from the browser's history, or
+// came too late.
+const rejectedInteractionCodeMessage = "This sign in was already completed or has expired. Sign in again."
+
+// isInteractionCodeRejected reports whether the token endpoint refused the
+// interaction code itself rather than the app's configuration.
+func isInteractionCodeRejected(err error) bool {
+	var oauthErr *OAuthError
+	return errors.As(err, &oauthErr) && oauthErr.Code == "invalid_grant" && !isRedirectURIMismatch(err)
+}
+
+// recordCallback keeps the callback URL while testing so the harness can
+// replay it.
+func (s *Server) recordCallback(r *http.Request) {
+	if !s.config.Testing || r.Method != http.MethodGet {
+		return
+	}
+	s.callbackMu.Lock()
+	defer s.callbackMu.Unlock()
+	s.lastCallbackURI = r.URL.RequestURI()
+}
+
+// LastCallbackURI is the path and query of the last sign in callback while
+// testing, e.g. so a test can check replaying it fails cleanly.
+func (s *Server) LastCallbackURI() string {
+	s.callbackMu.Lock()
+	defer s.callbackMu.Unlock()
+	return s.lastCallbackURI
+}
@@ -0,0 +1,114 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoginCallbackRejectsReplayedCode(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid_grant","error_description":"The interaction code is invalid or has expired."}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.config.Testing = true
+	s.state = "state"
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	session, err := s.sessionStore.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["pkce_code_verifier"] = "verifier"
+	session.Values["pkce_code_challenge"] = "challenge"
+	session.Values["pkce_code_challenge_method"] = "S256"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	callback := "/login/callback?state=state&interaction_code=used-code"
+	req = httptest.NewRequest(http.MethodGet, callback, nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	s.LoginCallbackHandler(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `id="auth-error"`) || !strings.Contains(body, "already completed or has expired") {
+		t.Errorf("expected the replayed code error page, got %s", body)
+	}
+	if got := s.LastCallbackURI(); got != callback {
+		t.Errorf("expected the callback %q to be recorded, got %q", callback, got)
+	}
+}
+
+func TestLoginCallbackHidesUnverifiedIDToken(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"valid-token","id_token":"forged-id-token","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.config.Testing = true
+	s.state = "state"
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	session, err := s.sessionStore.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["pkce_code_verifier"] = "verifier"
+	session.Values["pkce_code_challenge"] = "challenge"
+	session.Values["pkce_code_challenge_method"] = "S256"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/login/callback?state=state&interaction_code=code", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	s.LoginCallbackHandler(rec, req)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "the sign in could not be verified") {
+		t.Errorf("expected the verification error page, got %s", body)
+	}
+	if strings.Contains(body, "forged-id-token") {
+		t.Errorf("expected the ID token to stay off the page, got %s", body)
+	}
+}
@@ -0,0 +1,50 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/gorilla/sessions"
+)
+
+const interactionRequiredKey = "interaction_required"
+
+// maxInteractionRequired is how many times in a row the callback re-renders
+// the widget for interaction_required before giving up. Okta answering every
+// attempt that way would otherwise bounce the browser between the widget and
+// the callback forever.
+const maxInteractionRequired = 3
+
+const interactionRequiredLoopMessage = "Signing in could not be completed because Okta kept asking to sign in again. Please try again."
+
+// noteInteractionRequired counts an interaction_required callback in the
+// session and reports whether the widget may be shown again.
+func noteInteractionRequired(session *sessions.Session) bool {
+	count, _ := session.Values[interactionRequiredKey].(int)
+	count++
+	if count > maxInteractionRequired {
+		delete(session.Values, interactionRequiredKey)
+		return false
+	}
+	session.Values[interactionRequiredKey] = count
+	return true
+}
+
+// resetInteractionRequired forgets the interaction_required callbacks once a
+// sign in starts over or completes.
+func resetInteractionRequired(session *sessions.Session) {
+	delete(session.Values, interactionRequiredKey)
+}
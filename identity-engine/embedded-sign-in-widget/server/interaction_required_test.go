@@ -0,0 +1,69 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoginCallbackStopsInteractionRequiredLoop(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.state = "state"
+	s.pkce = &PKCE{CodeChallenge: "challenge", CodeChallengeMethod: "S256"}
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+
+	var cookies []*http.Cookie
+	callback := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/login/callback?state=state&error=interaction_required", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		s.LoginCallbackHandler(rec, req)
+		if got := rec.Result().Cookies(); len(got) > 0 {
+			cookies = got
+		}
+		return rec
+	}
+
+	for i := 1; i <= maxInteractionRequired; i++ {
+		if rec := callback(); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "okta-signin-widget-container") {
+			t.Fatalf("expected the widget on attempt %d, got %d: %s", i, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := callback()
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 once the retries ran out, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `id="auth-error"`) || !strings.Contains(body, "Please try again") {
+		t.Errorf("expected the try again error, got %s", body)
+	}
+	if strings.Contains(body, "okta-signin-widget-container") {
+		t.Error("expected the widget not to be rendered again")
+	}
+
+	// giving up starts the count over, e.g. for the user's next try
+	if rec := callback(); rec.Code != http.StatusOK {
+		t.Errorf("expected the widget after starting over, got %d", rec.Code)
+	}
+}
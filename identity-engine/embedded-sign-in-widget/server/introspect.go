@@ -0,0 +1,80 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrIntrospectionNotPermitted is returned by IntrospectToken when the
+// authorization server refuses to introspect tokens for this client.
+var ErrIntrospectionNotPermitted = errors.New("token introspection is not permitted for this client")
+
+// IntrospectToken asks the authorization server whether token, e.g. one that
+// logout should have revoked, is still active.
+func (s *Server) IntrospectToken(ctx context.Context, token, tokenTypeHint string) (bool, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", tokenTypeHint)
+	form.Set("client_id", s.idxConfigFor(ctx).Okta.IDX.ClientID)
+	form.Set("client_secret", s.idxConfigFor(ctx).Okta.IDX.ClientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.oAuthEndPoint(ctx, "introspect"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("introspect request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, ErrIntrospectionNotPermitted
+	default:
+		return false, fmt.Errorf("introspect returned %s", resp.Status)
+	}
+
+	var result struct {
+		Active bool `json:"active"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("introspect response is not valid: %w", err)
+	}
+	return result.Active, nil
+}
+
+// SessionAccessToken returns the access token held for the browser session
+// of r, e.g. so a test can check logout revoked it.
+func (s *Server) SessionAccessToken(r *http.Request) (string, bool) {
+	session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
+	if err != nil {
+		return "", false
+	}
+	return s.tokens().get(sessionID(session), accessTokenKind)
+}
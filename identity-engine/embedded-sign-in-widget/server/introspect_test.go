@@ -0,0 +1,60 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIntrospectToken(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/default/v1/introspect" || r.FormValue("client_id") != "client-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"active":%t}`, r.FormValue("token") == "active-token")
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+
+	active, err := s.IntrospectToken(context.Background(), "active-token", "access_token")
+	if err != nil || !active {
+		t.Errorf("expected an active token, got %t, %v", active, err)
+	}
+	active, err = s.IntrospectToken(context.Background(), "revoked-token", "access_token")
+	if err != nil || active {
+		t.Errorf("expected an inactive token, got %t, %v", active, err)
+	}
+}
+
+func TestIntrospectTokenNotPermitted(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"invalid_client"}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+
+	if _, err := s.IntrospectToken(context.Background(), "token", "access_token"); !errors.Is(err, ErrIntrospectionNotPermitted) {
+		t.Errorf("expected ErrIntrospectionNotPermitted, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	idx "github.com/okta/okta-idx-golang"
+)
+
+const issuerKey = "issuer"
+
+var errIssuerNotAllowed = errors.New("issuer is not allowed")
+
+type issuerContextKey struct{}
+
+// issuerResolver hands out an IDX client per issuer, the configured one or one
+// of the allowed ones, creating each the first time it is asked for. Created
+// clients share httpClient, if set.
+type issuerResolver struct {
+	defaultClient *idx.Client
+	httpClient    *http.Client
+	allowed       map[string]bool
+
+	mu      sync.Mutex
+	clients map[string]*idx.Client
+}
+
+func newIssuerResolver(defaultClient *idx.Client, httpClient *http.Client, allowed []string) *issuerResolver {
+	ir := &issuerResolver{
+		defaultClient: defaultClient,
+		httpClient:    httpClient,
+		allowed:       map[string]bool{},
+		clients:       map[string]*idx.Client{},
+	}
+	for _, issuer := range allowed {
+		ir.allowed[strings.TrimSuffix(issuer, "/")] = true
+	}
+	return ir
+}
+
+// clientFor returns the client for issuer. An empty issuer, or the configured
+// one, is the default client; any other must be allowed.
+func (ir *issuerResolver) clientFor(issuer string) (*idx.Client, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	defaultConfig := ir.defaultClient.Config().Okta.IDX
+	if issuer == "" || issuer == strings.TrimSuffix(defaultConfig.Issuer, "/") {
+		return ir.defaultClient, nil
+	}
+	if !ir.allowed[issuer] {
+		return nil, errIssuerNotAllowed
+	}
+
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	if client, ok := ir.clients[issuer]; ok {
+		return client, nil
+	}
+	client, err := idx.NewClientWithSettings(
+		idx.WithIssuer(issuer),
+		idx.WithClientID(defaultConfig.ClientID),
+		idx.WithClientSecret(defaultConfig.ClientSecret),
+		idx.WithScopes(defaultConfig.Scopes),
+		idx.WithRedirectURI(defaultConfig.RedirectURI),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if ir.httpClient != nil {
+		client = client.WithHTTPClient(ir.httpClient)
+	}
+	ir.clients[issuer] = client
+	return client, nil
+}
+
+// issuerMiddleware picks the issuer a request signs in against: the one in
+// ?issuer=, which is remembered in the session for the callback and later
+// requests, or else the session's. Switching issuers drops the tokens of the
+// previous one.
+func (s *Server) issuerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		current, _ := session.Values[issuerKey].(string)
+		issuer := current
+		if requested, ok := r.URL.Query()[issuerKey]; ok {
+			issuer = requested[0]
+		}
+
+		client, err := s.issuers.clientFor(issuer)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// the session only holds an issuer other than the configured one
+		var selected string
+		if client != s.idxClient {
+			selected = client.Config().Okta.IDX.Issuer
+		}
+		if selected != current {
+			s.tokens().delete(sessionID(session))
+			if selected == "" {
+				delete(session.Values, issuerKey)
+			} else {
+				session.Values[issuerKey] = selected
+			}
+			session.Save(r, w)
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), issuerContextKey{}, client)))
+	})
+}
+
+// idxConfigFor is the IDX config of the issuer the request was resolved to,
+// or the configured one.
+func (s *Server) idxConfigFor(ctx context.Context) *idx.Config {
+	if client, ok := ctx.Value(issuerContextKey{}).(*idx.Client); ok {
+		return client.Config()
+	}
+	return s.idxClient.Config()
+}
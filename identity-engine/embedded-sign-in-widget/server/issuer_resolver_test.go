@@ -0,0 +1,139 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIssuerResolverClientFor(t *testing.T) {
+	s := newBearerTestServer(t, "https://default.okta.com/oauth2/default")
+	ir := newIssuerResolver(s.idxClient, nil, []string{"https://acme.okta.com/oauth2/default", "https://globex.okta.com/oauth2/default/"})
+
+	for _, issuer := range []string{"", "https://default.okta.com/oauth2/default", "https://default.okta.com/oauth2/default/"} {
+		if client, err := ir.clientFor(issuer); err != nil || client != s.idxClient {
+			t.Errorf("%q: expected the default client, got %v", issuer, err)
+		}
+	}
+
+	acme, err := ir.clientFor("https://acme.okta.com/oauth2/default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	globex, err := ir.clientFor("https://globex.okta.com/oauth2/default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acme == globex || acme.Config().Okta.IDX.Issuer != "https://acme.okta.com/oauth2/default" || globex.Config().Okta.IDX.Issuer != "https://globex.okta.com/oauth2/default" {
+		t.Errorf("expected a client per issuer, got %q and %q", acme.Config().Okta.IDX.Issuer, globex.Config().Okta.IDX.Issuer)
+	}
+	if acme.Config().Okta.IDX.ClientID != "client-id" || acme.Config().Okta.IDX.RedirectURI != "http://localhost:8000/login/callback" {
+		t.Errorf("expected the app's client settings, got %+v", acme.Config().Okta.IDX)
+	}
+	if again, _ := ir.clientFor("https://acme.okta.com/oauth2/default"); again != acme {
+		t.Error("expected the client to be cached")
+	}
+
+	if _, err := ir.clientFor("https://evil.example.com/oauth2/default"); !errors.Is(err, errIssuerNotAllowed) {
+		t.Errorf("expected an issuer outside the allowlist to be refused, got %v", err)
+	}
+}
+
+func TestLoginUsesTheSelectedIssuer(t *testing.T) {
+	var hits []string
+	org := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits = append(hits, name+" "+r.URL.Path)
+			fmt.Fprintf(w, `{"interaction_handle":"%s-handle"}`, name)
+		}))
+	}
+	acme, globex := org("acme"), org("globex")
+	defer acme.Close()
+	defer globex.Close()
+
+	s := newBearerTestServer(t, acme.URL+"/oauth2/default")
+	s.issuers = newIssuerResolver(s.idxClient, nil, []string{globex.URL + "/oauth2/default"})
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+	router := s.router()
+
+	// selecting globex is remembered for the following requests
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login?issuer="+globex.URL+"/oauth2/default", nil))
+	if !strings.Contains(rec.Body.String(), "globex-handle") {
+		t.Fatalf("expected the globex interaction, got %s", rec.Body.String())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "globex-handle") {
+		t.Errorf("expected the session to stay on globex, got %s", rec.Body.String())
+	}
+
+	// a session without a selection uses the configured issuer
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if !strings.Contains(rec.Body.String(), "acme-handle") {
+		t.Errorf("expected the acme interaction, got %s", rec.Body.String())
+	}
+
+	expected := []string{"globex /oauth2/default/v1/interact", "globex /oauth2/default/v1/interact", "acme /oauth2/default/v1/interact"}
+	if strings.Join(hits, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected interact calls %v, got %v", expected, hits)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login?issuer=https://evil.example.com/oauth2/default", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected an issuer outside the allowlist to be refused, got %d", rec.Code)
+	}
+}
+
+func TestBearerTokenVerifiedAgainstTheRequestIssuer(t *testing.T) {
+	var hits []string
+	globex := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer globex.Close()
+
+	s := newBearerTestServer(t, "https://acme.okta.com/oauth2/default")
+	s.issuers = newIssuerResolver(s.idxClient, nil, []string{globex.URL + "/oauth2/default"})
+	client, err := s.issuers.clientFor(globex.URL + "/oauth2/default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), issuerContextKey{}, client)
+
+	// a well formed token, so the verifier asks the issuer for its keys
+	token := "eyJhbGciOiJSUzI1NiIsImtpZCI6ImtleSJ9.e30.c2lnbmF0dXJl"
+	if err := s.verifyAccessToken(ctx, token); err == nil {
+		t.Fatal("expected the token to be refused")
+	}
+	if len(hits) == 0 || !strings.HasPrefix(hits[0], "/oauth2/default/") {
+		t.Errorf("expected the globex issuer to be asked, got %v", hits)
+	}
+}
@@ -0,0 +1,99 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func decodeJSONError(t *testing.T, rec *httptest.ResponseRecorder) jsonError {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON content type, got %q", ct)
+	}
+	var body jsonError
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("expected a JSON error body: %v", err)
+	}
+	return body
+}
+
+func TestWriteJSONError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeJSONError(rec, http.StatusBadRequest, "invalid_request", "Something is missing.")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if rec.Body.String() != `{"error":"invalid_request","error_description":"Something is missing."}`+"\n" {
+		t.Errorf("unexpected body %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	writeJSONError(rec, http.StatusInternalServerError, "server_error", "")
+	if rec.Body.String() != `{"error":"server_error"}`+"\n" {
+		t.Errorf("expected an empty description to be left out, got %s", rec.Body.String())
+	}
+}
+
+func TestProfileHandlerJSONErrors(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	tests := map[string]string{
+		"":                    "login_required",
+		"Bearer forged-token": "invalid_token",
+	}
+	for auth, code := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+		req.Header.Set("Accept", "application/json")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.ProfileHandler(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected status 401, got %d", auth, rec.Code)
+		}
+		if body := decodeJSONError(t, rec); body.Error != code || body.ErrorDescription == "" {
+			t.Errorf("%q: unexpected error %+v", auth, body)
+		}
+	}
+}
+
+func TestLoginSilentHandlerJSONErrors(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"invalid_client","error_description":"Client authentication failed."}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+
+	rec := httptest.NewRecorder()
+	s.LoginSilentHandler(rec, httptest.NewRequest(http.MethodGet, "/login/silent", nil))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected status 502, got %d", rec.Code)
+	}
+	body := decodeJSONError(t, rec)
+	if body.Error != "invalid_client" || body.ErrorDescription != "Client authentication failed." {
+		t.Errorf("unexpected error %+v", body)
+	}
+}
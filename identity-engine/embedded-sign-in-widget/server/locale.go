@@ -0,0 +1,57 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import "strings"
+
+// widgetLanguages are the languages the sign-in widget ships translations
+// for, as it names them.
+var widgetLanguages = []string{
+	"cs", "da", "de", "el", "en", "es", "eu", "fi", "fr", "ht", "hu", "id",
+	"it", "ja", "ko", "ms", "nb", "nl-NL", "pl", "pt-BR", "ro", "ru", "sv",
+	"th", "tr", "uk", "vi", "zh-CN", "zh-TW",
+}
+
+const defaultWidgetLanguage = "en"
+
+// widgetLanguage picks the widget language for the browser's preferred
+// languages, in the order the Accept-Language header lists them. A tag
+// matches a widget language exactly, e.g. "pt-BR", or by its base language,
+// e.g. "fr-CA" for "fr".
+func widgetLanguage(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag = strings.TrimSpace(strings.SplitN(tag, ";", 2)[0])
+		tag = strings.ReplaceAll(tag, "_", "-")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		base := strings.SplitN(tag, "-", 2)[0]
+		var baseMatch string
+		for _, lang := range widgetLanguages {
+			if strings.EqualFold(lang, tag) {
+				return lang
+			}
+			if baseMatch == "" && strings.EqualFold(strings.SplitN(lang, "-", 2)[0], base) {
+				baseMatch = lang
+			}
+		}
+		if baseMatch != "" {
+			return baseMatch
+		}
+	}
+	return defaultWidgetLanguage
+}
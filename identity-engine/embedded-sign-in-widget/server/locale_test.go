@@ -0,0 +1,39 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import "testing"
+
+func TestWidgetLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                        "en",
+		"de-DE,de;q=0.9,en;q=0.8": "de",
+		"fr-CA":                   "fr",
+		"pt-BR,pt;q=0.9":          "pt-BR",
+		"pt-PT":                   "pt-BR",
+		"zh-tw":                   "zh-TW",
+		"nl":                      "nl-NL",
+		"xx-YY, es;q=0.5":         "es",
+		"*":                       "en",
+		"tlh":                     "en",
+	}
+	for acceptLanguage, want := range tests {
+		if got := widgetLanguage(acceptLanguage); got != want {
+			t.Errorf("widgetLanguage(%q) = %q, expected %q", acceptLanguage, got, want)
+		}
+	}
+}
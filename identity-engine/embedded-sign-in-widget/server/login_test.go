@@ -0,0 +1,111 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoginPageEmbedsWidgetConfig(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	tpl := template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+
+	var page bytes.Buffer
+	err := tpl.ExecuteTemplate(&page, "login.gohtml", &loginData{
+		BaseUrl:           "https://example.okta.com",
+		ClientId:          "client-id",
+		Issuer:            "https://example.okta.com/oauth2/default",
+		State:             `st"ate</script>`,
+		InteractionHandle: "handle",
+		Pkce:              &PKCE{CodeChallenge: "challenge", CodeChallengeMethod: "S256"},
+		Language:          "de",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const open = `<script type="application/json" id="okta-signin-widget-config">`
+	body := page.String()
+	start := strings.Index(body, open)
+	if start < 0 {
+		t.Fatal("expected the widget config in the page")
+	}
+	blob := body[start+len(open):]
+	blob = blob[:strings.Index(blob, "</script>")]
+
+	var widgetConfig struct {
+		ClientID    string `json:"clientId"`
+		RedirectURI string `json:"redirectUri"`
+		State       string `json:"state"`
+		Language    string `json:"language"`
+		AuthParams  struct {
+			Issuer string   `json:"issuer"`
+			Scopes []string `json:"scopes"`
+		} `json:"authParams"`
+	}
+	if err = json.Unmarshal([]byte(blob), &widgetConfig); err != nil {
+		t.Fatalf("widget config is not valid JSON: %v\n%s", err, blob)
+	}
+	if widgetConfig.ClientID != "client-id" || widgetConfig.AuthParams.Issuer != "https://example.okta.com/oauth2/default" {
+		t.Errorf("unexpected widget config %+v", widgetConfig)
+	}
+	if widgetConfig.RedirectURI != "http://localhost:8000/login/callback" {
+		t.Errorf("unexpected redirect uri %q", widgetConfig.RedirectURI)
+	}
+	if widgetConfig.State != `st"ate</script>` {
+		t.Errorf("expected the state to survive escaping, got %q", widgetConfig.State)
+	}
+	if widgetConfig.Language != "de" {
+		t.Errorf("expected the widget language, got %q", widgetConfig.Language)
+	}
+	if !strings.Contains(body, `<div id="okta-signin-widget-error" class="alert alert-danger m-4" role="alert" style="display: none;">`) {
+		t.Error("expected a hidden error state for a widget that fails to start")
+	}
+}
+
+func TestLoginHandlerShowsOktaError(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"interaction_handle":"handle"}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+
+	rec := httptest.NewRecorder()
+	s.LoginHandler(rec, httptest.NewRequest(http.MethodGet, "/login?error=access_denied&error_description=User+is+not+assigned+to+the+client+application.", nil))
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `id="login-error"`) || !strings.Contains(body, "access_denied: User is not assigned to the client application.") {
+		t.Errorf("expected the Okta error on the login page, got %s", body)
+	}
+	if !strings.Contains(body, "okta-signin-widget-container") {
+		t.Error("expected the widget to still be offered")
+	}
+
+	rec = httptest.NewRecorder()
+	s.LoginHandler(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if strings.Contains(rec.Body.String(), `id="login-error"`) {
+		t.Error("expected no error without one from Okta")
+	}
+}
@@ -0,0 +1,96 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogoutURL(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.config.PostLogoutRedirectURI = "http://localhost:8000/?signed out"
+	s.config.AllowedPostLogoutRedirectURIs = []string{"http://localhost:8000/?signed out"}
+
+	logoutURL, err := s.logoutURL(context.Background(), "id.token+value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(logoutURL, "https://example.okta.com/oauth2/default/v1/logout?") {
+		t.Errorf("unexpected logout endpoint in %s", logoutURL)
+	}
+	if !strings.Contains(logoutURL, "id_token_hint=id.token%2Bvalue") {
+		t.Errorf("expected an encoded id_token_hint in %s", logoutURL)
+	}
+	if !strings.Contains(logoutURL, "post_logout_redirect_uri=http%3A%2F%2Flocalhost%3A8000%2F%3Fsigned+out") {
+		t.Errorf("expected an encoded post_logout_redirect_uri in %s", logoutURL)
+	}
+	u, err := url.Parse(logoutURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("post_logout_redirect_uri"); got != s.config.PostLogoutRedirectURI {
+		t.Errorf("expected post_logout_redirect_uri %s, got %s", s.config.PostLogoutRedirectURI, got)
+	}
+}
+
+func TestLogoutURLRejectsUnlistedRedirect(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.config.PostLogoutRedirectURI = "https://evil.example.com/"
+	s.config.AllowedPostLogoutRedirectURIs = []string{"http://localhost:8000/"}
+
+	if _, err := s.logoutURL(context.Background(), "id-token"); err == nil {
+		t.Error("expected an error for a redirect uri missing from the allowlist")
+	}
+}
+
+func TestLogoutHandlerRedirectsToEndSession(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.config.PostLogoutRedirectURI = "http://localhost:8000/"
+	s.config.AllowedPostLogoutRedirectURIs = []string{"http://localhost:8000/"}
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	session, err := s.sessionStore.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid, err := ensureSessionID(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.tokens().set(sid, idTokenKind, "id-token", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.LogoutHandler(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); !strings.Contains(location, "/v1/logout?id_token_hint=id-token") {
+		t.Errorf("expected a redirect to the end-session endpoint, got %s", location)
+	}
+	if s.HasSessionTokens(sid) {
+		t.Error("expected logout to drop the session's tokens")
+	}
+}
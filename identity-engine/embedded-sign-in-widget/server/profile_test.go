@@ -0,0 +1,135 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	idx "github.com/okta/okta-idx-golang"
+	"github.com/patrickmn/go-cache"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/config"
+)
+
+func newBearerTestServer(t *testing.T, issuer string) *Server {
+	t.Helper()
+	idxClient, err := idx.NewClientWithSettings(
+		idx.WithIssuer(issuer),
+		idx.WithClientID("client-id"),
+		idx.WithClientSecret("client-secret"),
+		idx.WithScopes([]string{"openid", "profile"}),
+		idx.WithRedirectURI("http://localhost:8000/login/callback"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{
+		config:       &config.Config{},
+		idxClient:    idxClient,
+		issuers:      newIssuerResolver(idxClient, nil, nil),
+		httpClient:   &http.Client{},
+		sessionStore: sessions.NewCookieStore([]byte("randomKey")),
+		cache:        cache.New(cache.NoExpiration, cache.NoExpiration),
+		accessTokenVerifier: func(ctx context.Context, token string) error {
+			if token != "valid-token" {
+				return errors.New("invalid token")
+			}
+			return nil
+		},
+		idTokenVerifier: func(ctx context.Context, token string) error {
+			if token != "valid-id-token" {
+				return errors.New("invalid id token")
+			}
+			return nil
+		},
+	}
+}
+
+func TestProfileHandlerAcceptsBearerToken(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/default/v1/userinfo" || r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"email": "mary@acme.com"})
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	s.ProfileHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var profile map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile["email"] != "mary@acme.com" {
+		t.Errorf("unexpected profile %v", profile)
+	}
+}
+
+func TestProfileVerifiesBearerTokenOnce(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"email": "mary@acme.com"})
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	verifications := 0
+	s.accessTokenVerifier = func(ctx context.Context, token string) error {
+		verifications++
+		return nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	s.router().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if verifications != 1 {
+		t.Errorf("expected the bearer token to be verified once, got %d", verifications)
+	}
+}
+
+func TestProfileHandlerRejectsInvalidBearerToken(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer forged-token")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	s.ProfileHandler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,86 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsRedirectURIMismatch(t *testing.T) {
+	tests := []struct {
+		err      error
+		mismatch bool
+	}{
+		{&OAuthError{Code: "invalid_request", Description: "The 'redirect_uri' parameter must be a Login redirect URI in the client app settings"}, true},
+		{fmt.Errorf("interact: %w", &OAuthError{Code: "invalid_grant", Description: "The 'redirect_uri' does not match the redirection URI used in the authorization request."}), true},
+		{&OAuthError{Code: "invalid_grant", Description: "The interaction code is invalid or has expired."}, false},
+		{&OAuthError{Code: "access_denied", Description: "redirect_uri"}, false},
+		{errors.New("redirect_uri"), false},
+	}
+	for _, tt := range tests {
+		if got := isRedirectURIMismatch(tt.err); got != tt.mismatch {
+			t.Errorf("%v: expected %t, got %t", tt.err, tt.mismatch, got)
+		}
+	}
+}
+
+func TestLoginHandlerRendersRedirectURIMismatch(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid_request","error_description":"The 'redirect_uri' parameter must be a Login redirect URI in the client app settings"}`)
+	}))
+	defer okta.Close()
+
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+
+	rec := httptest.NewRecorder()
+	s.LoginHandler(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `id="auth-error"`) || !strings.Contains(body, "http://localhost:8000/login/callback") {
+		t.Errorf("expected the redirect uri mismatch error, got %s", body)
+	}
+	if strings.Contains(body, "okta-signin-widget-container") {
+		t.Error("expected the widget not to be rendered")
+	}
+}
+
+func TestLoginCallbackRendersOktaError(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.state = "state"
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+
+	rec := httptest.NewRecorder()
+	s.LoginCallbackHandler(rec, httptest.NewRequest(http.MethodGet, "/login/callback?state=state&error=access_denied&error_description=User+is+not+assigned", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "access_denied: User is not assigned") {
+		t.Errorf("expected Okta's error in the page, got %s", body)
+	}
+}
@@ -0,0 +1,90 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// refreshTokenLifetime is how long a session's refresh token is kept. Okta
+// may expire it sooner, which ends the session on the next refresh.
+const refreshTokenLifetime = 24 * time.Hour
+
+// sessionUserInfo returns the claims of a browser session's access token.
+// When userinfo rejects it, e.g. because it expired, the session's refresh
+// token renews it if AutoRefresh is on; otherwise, or if that fails, the
+// session's tokens are dropped so the user has to sign in again.
+func (s *Server) sessionUserInfo(ctx context.Context, sid, accessToken string) map[string]string {
+	m, status := s.fetchUserInfo(ctx, accessToken)
+	if status != http.StatusUnauthorized {
+		return m
+	}
+
+	if s.config.AutoRefresh {
+		if refreshToken, found := s.tokens().get(sid, refreshTokenKind); found {
+			exchange, err := s.refreshTokens(ctx, sid, refreshToken)
+			if err == nil {
+				return s.userInfo(ctx, exchange.AccessToken)
+			}
+			log.Printf("refresh error: %+v\n", err)
+		}
+	}
+
+	s.tokens().delete(sid)
+	return make(map[string]string)
+}
+
+// refreshTokens exchanges the refresh token for new tokens and keeps them for
+// the session. A rotated refresh token replaces the old one.
+func (s *Server) refreshTokens(ctx context.Context, sid, refreshToken string) (*Exchange, error) {
+	idxConfig := s.idxConfigFor(ctx)
+	// the refresh token and client secret go in the body, where they aren't
+	// logged along with the URL
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", idxConfig.Okta.IDX.ClientID)
+	form.Set("client_secret", idxConfig.Okta.IDX.ClientSecret)
+
+	exchange, _, err := exchangeToken(ctx, s.httpClient, s.oAuthEndPoint(ctx, "token"), form)
+	if err != nil {
+		return nil, err
+	}
+	if exchange.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access token")
+	}
+
+	if err := s.tokens().set(sid, accessTokenKind, exchange.AccessToken, time.Hour); err != nil {
+		return nil, err
+	}
+	if exchange.IdToken != "" {
+		if err := s.tokens().set(sid, idTokenKind, exchange.IdToken, time.Hour); err != nil {
+			return nil, err
+		}
+	}
+	if exchange.RefreshToken != "" {
+		if err := s.tokens().set(sid, refreshTokenKind, exchange.RefreshToken, refreshTokenLifetime); err != nil {
+			return nil, err
+		}
+	}
+	return exchange, nil
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newRefreshTestServer stubs userinfo, which rejects every access token but
+// "new-access-token", and the token endpoint, which counts refreshes.
+func newRefreshTestServer(t *testing.T, autoRefresh bool) (*Server, *int, func()) {
+	t.Helper()
+	refreshes := 0
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/default/v1/userinfo":
+			if r.Header.Get("Authorization") != "Bearer new-access-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"email":"mary@example.com"}`)
+		case "/oauth2/default/v1/token":
+			refreshes++
+			// the refresh token and secret only go in the form body
+			if r.URL.RawQuery != "" || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" ||
+				r.PostFormValue("grant_type") != "refresh_token" || r.PostFormValue("refresh_token") != "refresh-token" ||
+				r.PostFormValue("client_secret") != "client-secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"new-access-token","id_token":"new-id-token","refresh_token":"new-refresh-token"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.config.AutoRefresh = autoRefresh
+	s.tokens().set("sid", idTokenKind, "id-token", 0)
+	s.tokens().set("sid", accessTokenKind, "expired-access-token", 0)
+	s.tokens().set("sid", refreshTokenKind, "refresh-token", 0)
+	return s, &refreshes, okta.Close
+}
+
+func TestSessionUserInfoRefreshesExpiredAccessToken(t *testing.T) {
+	s, refreshes, done := newRefreshTestServer(t, true)
+	defer done()
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	profile := s.sessionUserInfo(req.Context(), "sid", "expired-access-token")
+
+	if profile["email"] != "mary@example.com" {
+		t.Fatalf("expected the claims after a refresh, got %v", profile)
+	}
+	if *refreshes != 1 {
+		t.Errorf("expected one refresh, got %d", *refreshes)
+	}
+	for kind, want := range map[tokenKind]string{
+		accessTokenKind:  "new-access-token",
+		idTokenKind:      "new-id-token",
+		refreshTokenKind: "new-refresh-token",
+	} {
+		if got, _ := s.tokens().get("sid", kind); got != want {
+			t.Errorf("expected %s %q, got %q", kind, want, got)
+		}
+	}
+}
+
+func TestSessionUserInfoRequiresLoginWithoutAutoRefresh(t *testing.T) {
+	s, refreshes, done := newRefreshTestServer(t, false)
+	defer done()
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	profile := s.sessionUserInfo(req.Context(), "sid", "expired-access-token")
+
+	if len(profile) != 0 {
+		t.Fatalf("expected no claims, got %v", profile)
+	}
+	if *refreshes != 0 {
+		t.Errorf("expected no refresh, got %d", *refreshes)
+	}
+	if s.HasSessionTokens("sid") {
+		t.Error("expected the session's tokens to be dropped so the user signs in again")
+	}
+}
+
+func TestSessionUserInfoRequiresLoginWhenRefreshFails(t *testing.T) {
+	s, _, done := newRefreshTestServer(t, true)
+	defer done()
+	s.tokens().set("sid", refreshTokenKind, "revoked-refresh-token", 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	profile := s.sessionUserInfo(req.Context(), "sid", "expired-access-token")
+
+	if len(profile) != 0 {
+		t.Fatalf("expected no claims, got %v", profile)
+	}
+	if s.HasSessionTokens("sid") {
+		t.Error("expected the session's tokens to be dropped after a failed refresh")
+	}
+}
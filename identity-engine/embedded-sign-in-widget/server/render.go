@@ -0,0 +1,66 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+)
+
+// render executes a template into a buffer before writing any of it, so a
+// template that fails part way through, e.g. on a missing key, doesn't leave
+// a partial page behind a 200. The error page is rendered with a 500
+// instead.
+func (s *Server) render(w http.ResponseWriter, status int, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := s.tpl.ExecuteTemplate(&buf, name, data); err != nil {
+		log.Printf("template %s error: %+v\n", name, err)
+		s.renderTemplateError(w)
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// renderTemplateError answers with the error page after a template failed,
+// or plain text if the error page fails as well.
+func (s *Server) renderTemplateError(w http.ResponseWriter) {
+	type customData struct {
+		IsAuthenticated bool
+		Title           string
+		Message         string
+	}
+
+	status := http.StatusInternalServerError
+	var buf bytes.Buffer
+	err := s.tpl.ExecuteTemplate(&buf, "httpError.gohtml", customData{
+		Title:   http.StatusText(status),
+		Message: "This page could not be shown, please try again.",
+	})
+	if err != nil {
+		log.Printf("template httpError.gohtml error: %+v\n", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,60 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderBrokenTemplate(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+	template.Must(s.tpl.New("home.gohtml").Parse(`<p>partial</p>{{ .Missing.Key }}`))
+
+	rec := httptest.NewRecorder()
+	s.HomeHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected a 500, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "partial") {
+		t.Errorf("expected no partial output, got %s", rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `id="http-error"`) {
+		t.Errorf("expected the error page, got %s", rec.Body.String())
+	}
+}
+
+func TestRenderBrokenErrorPage(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	s.tpl = template.Must(template.New("").Parse(
+		`{{define "home.gohtml"}}partial{{ .Missing }}{{end}}{{define "httpError.gohtml"}}{{ .Missing }}{{end}}`))
+
+	rec := httptest.NewRecorder()
+	s.render(rec, http.StatusOK, "home.gohtml", struct{}{})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected a 500, got %d", rec.Code)
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("expected a plain text error, got %q", body)
+	}
+}
@@ -0,0 +1,51 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/url"
+	"strings"
+)
+
+const returnToKey = "return_to"
+
+// returnTo vets where a sign in should land once it completes, e.g. the
+// deep link that sent the user to log in. Only paths on this app are
+// allowed; anything else, e.g. an absolute or protocol relative URL, is
+// dropped so return_to can't be used as an open redirect.
+func (s *Server) returnTo(raw string) string {
+	if !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") || strings.Contains(raw, `\`) {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.IsAbs() || u.Host != "" {
+		return ""
+	}
+	if !strings.HasPrefix(u.Path, s.Path("/")) {
+		return ""
+	}
+	return u.RequestURI()
+}
+
+// loginURL is the login page that returns to the current request's page
+// once signed in.
+func (s *Server) loginURL(requestURI string) string {
+	if requestURI == "" {
+		return s.Path("/login")
+	}
+	return s.Path("/login") + "?" + url.Values{returnToKey: {requestURI}}.Encode()
+}
@@ -0,0 +1,104 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReturnTo(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+	tests := map[string]string{
+		"/profile":                    "/profile",
+		"/profile?tab=claims":         "/profile?tab=claims",
+		"":                            "",
+		"profile":                     "",
+		"//evil.example.com/profile":  "",
+		`/\evil.example.com`:          "",
+		"https://evil.example.com/":   "",
+		"javascript:alert(1)":         "",
+		"/%2F%2Fevil.example.com/foo": "/%2F%2Fevil.example.com/foo",
+	}
+	for raw, expected := range tests {
+		if got := s.returnTo(raw); got != expected {
+			t.Errorf("returnTo(%q) = %q, expected %q", raw, got, expected)
+		}
+	}
+
+	s.config.BasePath = "/app"
+	if got := s.returnTo("/other"); got != "" {
+		t.Errorf("expected paths outside the base path to be dropped, got %q", got)
+	}
+	if got := s.returnTo("/app/profile"); got != "/app/profile" {
+		t.Errorf("expected paths under the base path to be kept, got %q", got)
+	}
+}
+
+func TestProfileHandlerSendsBrowsersToLogin(t *testing.T) {
+	s := newBearerTestServer(t, "https://example.okta.com/oauth2/default")
+
+	req := httptest.NewRequest(http.MethodGet, "/profile?tab=claims", nil)
+	rec := httptest.NewRecorder()
+	s.ProfileHandler(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect to login, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "/login?return_to=%2Fprofile%3Ftab%3Dclaims" {
+		t.Errorf("unexpected login redirect %q", got)
+	}
+}
+
+func TestLoginCallbackReturnsToDeepLink(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token":"new-access-token","id_token":"valid-id-token"}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.state = "state"
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	session, err := s.sessionStore.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values[returnToKey] = "/profile"
+	session.Values["pkce_code_verifier"] = "verifier"
+	session.Values["pkce_code_challenge"] = "challenge"
+	session.Values["pkce_code_challenge_method"] = "S256"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/login/callback?state=state&interaction_code=code", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	s.LoginCallbackHandler(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect after sign in, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Location"); got != "/profile" {
+		t.Errorf("expected to return to the deep link, got %q", got)
+	}
+}
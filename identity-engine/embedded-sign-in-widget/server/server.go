@@ -18,11 +18,13 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/ioutil"
@@ -31,6 +33,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -40,6 +43,7 @@ import (
 	"github.com/patrickmn/go-cache"
 
 	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/config"
+	"github.com/okta/samples-golang/identity-engine/internal/scopes"
 )
 
 const (
@@ -54,6 +58,33 @@ type Exchange struct {
 	ExpiresIn        int    `json:"expires_in,omitempty"`
 	Scope            string `json:"scope,omitempty"`
 	IdToken          string `json:"id_token,omitempty"`
+	RefreshToken     string `json:"refresh_token,omitempty"`
+}
+
+// OAuthError is an error response from one of the authorization server's
+// endpoints, e.g. login_required from interact when prompt=none.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// isRedirectURIMismatch reports whether Okta rejected the request because the
+// redirect URI isn't one registered on the app, which it reports as either
+// invalid_request or invalid_grant.
+func isRedirectURIMismatch(err error) bool {
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) {
+		return false
+	}
+	return (oauthErr.Code == "invalid_request" || oauthErr.Code == "invalid_grant") &&
+		strings.Contains(oauthErr.Description, "redirect_uri")
 }
 
 type PKCE struct {
@@ -63,36 +94,48 @@ type PKCE struct {
 }
 
 type Server struct {
-	config            *config.Config
-	idxClient         *idx.Client
-	tpl               *template.Template
-	sessionStore      *sessions.CookieStore
-	ViewData          ViewData
-	cache             *cache.Cache
-	svc               *http.Server
-	address           string
-	pkce              *PKCE
-	state             string
-	interactionHandle string
+	config              *config.Config
+	idxClient           *idx.Client
+	issuers             *issuerResolver
+	httpClient          *http.Client
+	accessTokenVerifier func(context.Context, string) error
+	idTokenVerifier     func(context.Context, string) error
+	tpl                 *template.Template
+	sessionStore        sessions.Store
+	ViewData            ViewData
+	cache               *cache.Cache
+	svc                 *http.Server
+	address             string
+	pkce                *PKCE
+	state               string
+	callbackMu          sync.Mutex
+	lastCallbackURI     string
 }
 
 type ViewData map[string]interface{}
 
 func NewServer(c *config.Config) *Server {
+	if err := c.Validate(); err != nil {
+		log.Fatalf("config error: %+v", err)
+	}
+
 	idx, err := idx.NewClient()
 	if err != nil {
 		log.Fatalf("new client error: %+v", err)
 	}
+	scopes.WarnMissing(idx.Config().Okta.IDX.Scopes)
+	httpClient := newIDXHTTPClient(c)
+	idx = idx.WithHTTPClient(httpClient)
 
 	// Generate random byte array for state parameter
 	b := make([]byte, 16)
 	rand.Read(b)
 
-	return &Server{
+	s := &Server{
 		config:       c,
-		tpl:          template.Must(template.ParseGlob("templates/*.gohtml")),
 		idxClient:    idx,
-		sessionStore: sessions.NewCookieStore([]byte("randomKey")),
+		httpClient:   httpClient,
+		sessionStore: newSessionStore(c),
 		cache:        cache.New(5*time.Minute, 10*time.Minute),
 		ViewData: map[string]interface{}{
 			"Authenticated": false,
@@ -100,22 +143,135 @@ func NewServer(c *config.Config) *Server {
 		},
 		state: hex.EncodeToString(b),
 	}
+	s.issuers = newIssuerResolver(idx, httpClient, c.AllowedIssuers)
+	s.accessTokenVerifier = s.verifyAccessToken
+	s.idTokenVerifier = func(ctx context.Context, t string) error {
+		_, err := s.verifyToken(ctx, t)
+		return err
+	}
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("templates/*.gohtml"))
+
+	return s
+}
+
+// defaultSessionKey signs sessions when no session keys are configured.
+var defaultSessionKey = []byte("randomKey")
+
+// newSessionStore scopes the session cookie to the configured domain and path.
+// With a session dir the session is kept on disk and the cookie only holds
+// its id.
+func newSessionStore(c *config.Config) sessions.Store {
+	keyPairs := c.KeyPairs()
+	if len(keyPairs) == 0 {
+		keyPairs = [][]byte{defaultSessionKey}
+	}
+	var options *sessions.Options
+	var store sessions.Store
+	if c.SessionDir != "" {
+		fsStore := sessions.NewFilesystemStore(c.SessionDir, keyPairs...)
+		// the 4KB default would cap file sessions at the cookie's size
+		fsStore.MaxLength(0)
+		options, store = fsStore.Options, fsStore
+	} else {
+		cookieStore := sessions.NewCookieStore(keyPairs...)
+		options, store = cookieStore.Options, cookieStore
+	}
+
+	// Okta returns to the callback with a top level GET, which Lax allows;
+	// set explicitly so browsers with other defaults behave the same. A
+	// form_post callback is a cross-site POST, which only carries a cookie
+	// that is SameSite=None, and browsers require those to be Secure.
+	options.SameSite = http.SameSiteLaxMode
+	if c.ResponseMode == "form_post" {
+		options.SameSite = http.SameSiteNoneMode
+		options.Secure = true
+	}
+	options.Domain = c.CookieDomain
+	if c.CookiePath != "" {
+		options.Path = c.CookiePath
+	}
+	return store
+}
+
+func (s *Server) Config() *config.Config {
+	return s.config
+}
+
+// IdxConfig is the Okta app configuration the server signs users in with.
+func (s *Server) IdxConfig() *idx.Config {
+	return s.idxClient.Config()
+}
+
+func (s *Server) widgetAssetBase() string {
+	if s.config.WidgetAssetBase == "" {
+		return config.DefaultWidgetAssetBase
+	}
+	return strings.TrimSuffix(s.config.WidgetAssetBase, "/")
+}
+
+// Path returns the app path p, e.g. "/login", under the configured BasePath.
+func (s *Server) Path(p string) string {
+	base := strings.TrimSuffix(s.config.BasePath, "/")
+	if base != "" && !strings.HasPrefix(base, "/") {
+		base = "/" + base
+	}
+	return base + p
+}
+
+func (s *Server) tokens() tokenStore {
+	return tokenStore{cache: s.cache}
+}
+
+func (s *Server) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"widgetAssetBase": s.widgetAssetBase,
+		"path":            s.Path,
+		"redirectURI":     s.redirectURI,
+		"responseType":    func() string { return s.config.ResponseType },
+		"responseMode":    func() string { return s.config.ResponseMode },
+		"prompt":          func() string { return s.config.Prompt },
+		"widgetFeatures":  func() map[string]bool { return s.config.WidgetFeatures },
+	}
+}
+
+func (s *Server) redirectURI() string {
+	return s.idxClient.Config().Okta.IDX.RedirectURI
 }
 
 func (s *Server) Address() string {
 	return s.address
 }
 
-func (s *Server) Run() {
+func (s *Server) router() *mux.Router {
 	r := mux.NewRouter()
 	r.Use(s.loggingMiddleware)
+	r.Use(s.tracingMiddleware)
+	r.Use(s.issuerMiddleware)
+	r.Use(s.bearerMiddleware)
+
+	if root := s.Path(""); root != "" {
+		r.Handle(root, http.RedirectHandler(s.Path("/"), http.StatusMovedPermanently))
+	}
+	r.HandleFunc(s.Path("/"), s.HomeHandler).Methods("GET")
+
+	r.HandleFunc(s.Path("/login"), s.LoginHandler).Methods("GET")
+	r.HandleFunc(s.Path("/login/callback"), s.LoginCallbackHandler).Methods("GET", "POST")
+	r.HandleFunc(s.Path("/login/silent"), s.LoginSilentHandler).Methods("GET")
+	r.HandleFunc(s.Path("/activate"), s.ActivateHandler).Methods("GET")
+	r.HandleFunc(s.Path("/profile"), s.ProfileHandler).Methods("GET")
+	r.HandleFunc(s.Path("/logout"), s.LogoutHandler).Methods("POST")
+	if s.config.Testing {
+		r.HandleFunc(s.Path("/debug/session"), s.DebugSessionHandler).Methods("GET")
+	}
 
-	r.HandleFunc("/", s.HomeHandler).Methods("GET")
+	r.NotFoundHandler = s.notFoundHandler(r)
+	r.MethodNotAllowedHandler = http.HandlerFunc(s.methodNotAllowedHandler)
 
-	r.HandleFunc("/login", s.LoginHandler).Methods("GET")
-	r.HandleFunc("/login/callback", s.LoginCallbackHandler).Methods("GET")
-	r.HandleFunc("/profile", s.ProfileHandler).Methods("GET")
-	r.HandleFunc("/logout", s.LogoutHandler).Methods("POST")
+	return r
+}
+
+func (s *Server) Run() {
+	r := s.router()
 
 	addr := "localhost:8000"
 	logger := log.New(os.Stderr, "http: ", log.LstdFlags)
@@ -145,19 +301,127 @@ func (s *Server) HomeHandler(w http.ResponseWriter, r *http.Request) {
 	type customData struct {
 		Profile         map[string]string
 		IsAuthenticated bool
+		TokenExpiry     tokenExpiryData
 	}
 
 	data := customData{
 		Profile:         s.getProfileData(r),
 		IsAuthenticated: s.isAuthenticated(r),
+		TokenExpiry:     s.tokenExpiryData(r),
 	}
 
-	s.tpl.ExecuteTemplate(w, "home.gohtml", data)
+	s.render(w, http.StatusOK, "home.gohtml", data)
 }
 
 func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Cache-Control", "no-cache") // See https://github.com/okta/samples-golang/issues/20
 
+	returnTo := s.returnTo(r.URL.Query().Get(returnToKey))
+
+	// Going back to the login page after signing in must not offer the
+	// widget again
+	if s.isAuthenticated(r) {
+		if returnTo == "" {
+			returnTo = s.Path("/")
+		}
+		http.Redirect(w, r, returnTo, http.StatusFound)
+		return
+	}
+
+	// remembered for the callback; prepareLogin saves the session
+	if session, err := s.sessionStore.Get(r, SESSION_STORE_NAME); err == nil {
+		if returnTo != "" {
+			session.Values[returnToKey] = returnTo
+		} else {
+			delete(session.Values, returnToKey)
+		}
+	}
+
+	data, err := s.prepareLogin(w, r, nil)
+	if err != nil {
+		fmt.Printf("could not get interactionHandle: %s\n", err.Error())
+		if isRedirectURIMismatch(err) {
+			s.renderRedirectURIMismatch(w, err)
+			return
+		}
+	}
+
+	// Okta can send the user back here with an error rather than through the
+	// callback; show it above the widget instead of dropping it
+	if code := r.URL.Query().Get("error"); code != "" {
+		data.Error = (&OAuthError{Code: code, Description: r.URL.Query().Get("error_description")}).Error()
+	}
+
+	s.render(w, http.StatusOK, "login.gohtml", data)
+}
+
+// ActivateHandler lets an invited user finish activating their account. The
+// activation token from their invite is sent along with the interact request,
+// so the widget opens on setting up their password and authenticators.
+func (s *Server) ActivateHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Cache-Control", "no-cache")
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		s.renderActivationError(w, "The activation link is missing its token.")
+		return
+	}
+
+	data, err := s.prepareLogin(w, r, url.Values{"activation_token": {token}})
+	if err != nil {
+		fmt.Printf("could not get interactionHandle: %s\n", err.Error())
+		var oauthErr *OAuthError
+		if !errors.As(err, &oauthErr) {
+			http.Error(w, "The activation could not be started, please try again.", http.StatusInternalServerError)
+			return
+		}
+		s.renderActivationError(w, "The activation link is invalid or has expired. Ask your administrator to send a new invite.")
+		return
+	}
+	s.render(w, http.StatusOK, "login.gohtml", data)
+}
+
+func (s *Server) renderActivationError(w http.ResponseWriter, message string) {
+	type customData struct {
+		IsAuthenticated bool
+		Message         string
+	}
+
+	s.render(w, http.StatusBadRequest, "activationError.gohtml", customData{Message: message})
+}
+
+// renderAuthError shows why signing in failed instead of a blank page.
+func (s *Server) renderAuthError(w http.ResponseWriter, status int, message string) {
+	type customData struct {
+		IsAuthenticated bool
+		Message         string
+	}
+
+	s.render(w, status, "authError.gohtml", customData{Message: message})
+}
+
+func (s *Server) renderRedirectURIMismatch(w http.ResponseWriter, err error) {
+	s.renderAuthError(w, http.StatusInternalServerError, fmt.Sprintf(
+		"The redirect URI %s is not one of the app's sign-in redirect URIs in Okta. Add it to the app or fix OKTA_IDX_REDIRECTURI. (%s)",
+		s.redirectURI(), err.Error()))
+}
+
+type loginData struct {
+	IsAuthenticated   bool
+	BaseUrl           string
+	ClientId          string
+	Issuer            string
+	State             string
+	Nonce             string
+	InteractionHandle string
+	Pkce              *PKCE
+	Language          string
+	Error             string
+}
+
+// prepareLogin sets up the PKCE data and interaction handle the widget needs,
+// sending extra along with the interact request.
+func (s *Server) prepareLogin(w http.ResponseWriter, r *http.Request, extra url.Values) (*loginData, error) {
 	session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -171,7 +435,9 @@ func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		session.Values["pkce_code_verifier"] = s.pkce.CodeVerifier
 		session.Values["pkce_code_challenge"] = s.pkce.CodeChallenge
 		session.Values["pkce_code_challenge_method"] = s.pkce.CodeChallengeMethod
-		session.Save(r, w)
+		if _, err := ensureSessionID(session); err != nil {
+			fmt.Printf("could not create session id: %s\n", err.Error())
+		}
 	} else {
 		s.pkce.CodeVerifier = session.Values["pkce_code_verifier"].(string)
 		s.pkce.CodeChallenge = session.Values["pkce_code_challenge"].(string)
@@ -182,24 +448,32 @@ func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("error: %s\n", err.Error())
 		os.Exit(1)
 	}
-	type customData struct {
-		IsAuthenticated   bool
-		BaseUrl           string
-		ClientId          string
-		Issuer            string
-		State             string
-		Nonce             string
-		InteractionHandle string
-		Pkce              *PKCE
-	}
-
-	interactionHandle, err := s.getInteractionHandle(s.pkce.CodeChallenge)
-	s.interactionHandle = interactionHandle
-	if err != nil {
-		fmt.Printf("could not get interactionHandle: %s\n", err.Error())
+
+	var interactionHandle string
+	var interactErr error
+	if injected, ok := injectedInteraction(r); ok {
+		// dev-only: reuse an interaction started elsewhere so the callback and
+		// token exchange can be debugged without calling interact again
+		log.Printf("DEBUG: using injected interaction handle, skipping interact\n")
+		interactionHandle = injected.handle
+		if injected.state != "" {
+			s.state = injected.state
+		}
+		if injected.codeVerifier != "" {
+			s.pkce = pkceFromVerifier(injected.codeVerifier)
+			session.Values["pkce_code_verifier"] = s.pkce.CodeVerifier
+			session.Values["pkce_code_challenge"] = s.pkce.CodeChallenge
+			session.Values["pkce_code_challenge_method"] = s.pkce.CodeChallengeMethod
+		}
+	} else {
+		interactionHandle, interactErr = s.getInteractionHandle(r.Context(), s.pkce.CodeChallenge, s.config.Prompt, extra)
 	}
+	session.Values[interactionHandleKey] = interactionHandle
+	resetInteractionRequired(session)
+	session.Save(r, w)
 
-	issuerURL := s.idxClient.Config().Okta.IDX.Issuer
+	idxConfig := s.idxConfigFor(r.Context())
+	issuerURL := idxConfig.Okta.IDX.Issuer
 	issuerParts, err := url.Parse(issuerURL)
 	if err != nil {
 		fmt.Printf("error: %s\n", err.Error())
@@ -207,45 +481,110 @@ func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	baseUrl := issuerParts.Scheme + "://" + issuerParts.Hostname()
 
-	data := customData{
+	data := &loginData{
 		IsAuthenticated:   s.isAuthenticated(r),
 		BaseUrl:           baseUrl,
-		ClientId:          s.idxClient.Config().Okta.IDX.ClientID,
-		Issuer:            s.idxClient.Config().Okta.IDX.Issuer,
+		ClientId:          idxConfig.Okta.IDX.ClientID,
+		Issuer:            idxConfig.Okta.IDX.Issuer,
 		State:             s.state,
 		Nonce:             nonce,
 		Pkce:              s.pkce,
 		InteractionHandle: interactionHandle,
+		Language:          widgetLanguage(r.Header.Get("Accept-Language")),
+	}
+	return data, interactErr
+}
+
+type injectedInteractionData struct {
+	handle       string
+	state        string
+	codeVerifier string
+}
+
+// injectedInteraction reads a pre-obtained interaction handle, and optionally
+// the state and PKCE code verifier it was started with, from the login query
+// string. It is only honored when DEBUG is on.
+func injectedInteraction(r *http.Request) (injectedInteractionData, bool) {
+	if os.Getenv("DEBUG") != "true" {
+		return injectedInteractionData{}, false
+	}
+	q := r.URL.Query()
+	handle := q.Get("interaction_handle")
+	if handle == "" {
+		return injectedInteractionData{}, false
+	}
+	return injectedInteractionData{
+		handle:       handle,
+		state:        q.Get("state"),
+		codeVerifier: q.Get("code_verifier"),
+	}, true
+}
+
+// LoginSilentHandler answers whether the browser already has a session
+// without rendering any UI, the way an SPA's hidden iframe check would.
+func (s *Server) LoginSilentHandler(w http.ResponseWriter, r *http.Request) {
+	type silentResult struct {
+		Authenticated bool   `json:"authenticated"`
+		Error         string `json:"error,omitempty"`
+	}
+
+	w.Header().Add("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.isAuthenticated(r) {
+		json.NewEncoder(w).Encode(silentResult{Authenticated: true})
+		return
 	}
-	err = s.tpl.ExecuteTemplate(w, "login.gohtml", data)
+
+	pkce, err := createPKCEData()
 	if err != nil {
-		fmt.Printf("error: %s\n", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	_, err = s.getInteractionHandle(r.Context(), pkce.CodeChallenge, "none", nil)
+	var oauthErr *OAuthError
+	switch {
+	case errors.As(err, &oauthErr) && oauthErr.Code == "login_required":
+		json.NewEncoder(w).Encode(silentResult{Error: oauthErr.Code})
+	case errors.As(err, &oauthErr):
+		writeJSONError(w, http.StatusBadGateway, oauthErr.Code, oauthErr.Description)
+	case err != nil:
+		writeJSONError(w, http.StatusInternalServerError, "server_error", err.Error())
+	default:
+		// Okta has a session but this app holds no tokens for it yet; the
+		// widget has to run to finish the interaction.
+		json.NewEncoder(w).Encode(silentResult{Error: "interaction_required"})
 	}
 }
 
 func (s *Server) LoginCallbackHandler(w http.ResponseWriter, r *http.Request) {
-	// Check the state that was returned in the query string is the same as the above state
-	if r.URL.Query().Get("state") != s.state {
+	s.recordCallback(r)
+
+	// Check the state that was returned in the query string, or the form with
+	// response_mode=form_post, is the same as the above state
+	if r.FormValue("state") != s.state {
 		fmt.Fprintln(w, "The state was not as expected")
 		return
 	}
 
 	// Check if interaction_required error is returned
-	if r.URL.Query().Get("error") == "interaction_required" {
+	if r.FormValue("error") == "interaction_required" {
 		w.Header().Add("Cache-Control", "no-cache")
 
-		// render the widget with the saved interaction handle
-		type customData struct {
-			IsAuthenticated   bool
-			BaseUrl           string
-			ClientId          string
-			Issuer            string
-			State             string
-			InteractionHandle string
-			Pkce              *PKCE
+		// render the widget with the session's interaction handle
+		session, _ := s.sessionStore.Get(r, SESSION_STORE_NAME)
+		again := noteInteractionRequired(session)
+		if err := session.Save(r, w); err != nil {
+			log.Printf("session error: %+v\n", err)
 		}
-
-		issuerURL := s.idxClient.Config().Okta.IDX.Issuer
+		if !again {
+			log.Printf("interaction_required %d times in a row, giving up\n", maxInteractionRequired+1)
+			s.renderAuthError(w, http.StatusBadRequest, interactionRequiredLoopMessage)
+			return
+		}
+		idxConfig := s.idxConfigFor(r.Context())
+		issuerURL := idxConfig.Okta.IDX.Issuer
 		issuerParts, err := url.Parse(issuerURL)
 		if err != nil {
 			fmt.Printf("error: %s\n", err.Error())
@@ -253,24 +592,33 @@ func (s *Server) LoginCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		baseUrl := issuerParts.Scheme + "://" + issuerParts.Hostname()
 
-		data := customData{
+		data := loginData{
 			IsAuthenticated:   s.isAuthenticated(r),
 			BaseUrl:           baseUrl,
-			ClientId:          s.idxClient.Config().Okta.IDX.ClientID,
-			Issuer:            s.idxClient.Config().Okta.IDX.Issuer,
+			ClientId:          idxConfig.Okta.IDX.ClientID,
+			Issuer:            idxConfig.Okta.IDX.Issuer,
 			State:             s.state,
 			Pkce:              s.pkce,
-			InteractionHandle: s.interactionHandle,
+			InteractionHandle: sessionInteractionHandle(session),
+			Language:          widgetLanguage(r.Header.Get("Accept-Language")),
 		}
-		err = s.tpl.ExecuteTemplate(w, "login.gohtml", data)
-		if err != nil {
-			fmt.Printf("error: %s\n", err.Error())
+		s.render(w, http.StatusOK, "login.gohtml", data)
+		return
+	}
+
+	// Okta redirects back with an error instead of a code, e.g. access_denied
+	if code := r.FormValue("error"); code != "" {
+		err := &OAuthError{Code: code, Description: r.FormValue("error_description")}
+		if isRedirectURIMismatch(err) {
+			s.renderRedirectURIMismatch(w, err)
+			return
 		}
+		s.renderAuthError(w, http.StatusBadRequest, fmt.Sprintf("Signing in failed: %s", err.Error()))
 		return
 	}
 
 	// Make sure the interaction_code was provided
-	if r.URL.Query().Get("interaction_code") == "" {
+	if r.FormValue("interaction_code") == "" {
 		fmt.Fprintln(w, "The interaction_code was not returned or is not accessible")
 		return
 	}
@@ -293,84 +641,171 @@ func (s *Server) LoginCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	q.Del("state")
 
 	q.Add("grant_type", "interaction_code")
-	q.Set("interaction_code", r.URL.Query().Get("interaction_code"))
-	q.Add("client_id", s.idxClient.Config().Okta.IDX.ClientID)
-	q.Add("client_secret", s.idxClient.Config().Okta.IDX.ClientSecret)
+	q.Set("interaction_code", r.FormValue("interaction_code"))
+	q.Add("client_id", s.idxConfigFor(r.Context()).Okta.IDX.ClientID)
+	q.Add("client_secret", s.idxConfigFor(r.Context()).Okta.IDX.ClientSecret)
 	q.Add("code_verifier", session.Values["pkce_code_verifier"].(string))
 
-	url := s.oAuthEndPoint(fmt.Sprintf("token?%s", q.Encode()))
-	req, _ := http.NewRequest("POST", url, bytes.NewReader([]byte("")))
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{Timeout: time.Second * 30}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalf("RESP ERROR: %+v\n", err.Error())
-	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatalf("READ ERROR: %+v\n", err.Error())
+	url := s.oAuthEndPoint(r.Context(), fmt.Sprintf("token?%s", q.Encode()))
+	exchange, body, err := exchangeToken(r.Context(), s.httpClient, url, nil)
+	if body != nil {
+		debugTokenResponse(body)
 	}
-	defer resp.Body.Close()
-
-	var exchange Exchange
-	err = json.Unmarshal(body, &exchange)
 	if err != nil {
-		log.Fatalf("UNMARSHAL ERROR: %+v\n", err.Error())
+		log.Printf("TOKEN EXCHANGE ERROR: %+v\n", err.Error())
+		if isRedirectURIMismatch(err) {
+			s.renderRedirectURIMismatch(w, err)
+			return
+		}
+		if isInteractionCodeRejected(err) {
+			s.renderAuthError(w, http.StatusBadRequest, fmt.Sprintf("%s (%s)", rejectedInteractionCodeMessage, err.Error()))
+			return
+		}
+		s.renderAuthError(w, http.StatusBadGateway, fmt.Sprintf("Signing in failed: %s", err.Error()))
+		return
 	}
 
-	_, verificationError := s.verifyToken(exchange.IdToken)
+	verificationError := s.idTokenVerifier(r.Context(), exchange.IdToken)
 
 	if verificationError != nil {
-		log.Fatalf("Verification Error: %+v\n", verificationError)
+		// the details stay in the log, the page only says the sign in failed
+		log.Printf("Verification Error: %+v\n", verificationError)
+		s.renderAuthError(w, http.StatusBadGateway, unverifiedSignInMessage)
+		return
 	}
 
-	s.cache.Add(fmt.Sprintf("%s-id_token", session.ID), exchange.IdToken, time.Hour)
-	s.cache.Add(fmt.Sprintf("%s-access_token", session.ID), exchange.AccessToken, time.Hour)
+	// land on the deep link the sign in started from, if any
+	rawReturnTo, _ := session.Values[returnToKey].(string)
+	delete(session.Values, returnToKey)
+	resetInteractionRequired(session)
+	returnTo := s.returnTo(rawReturnTo)
+	if returnTo == "" {
+		returnTo = s.Path("/")
+	}
 
-	http.Redirect(w, r, "/", http.StatusFound)
+	// a new id on sign in prevents session fixation; tokens held under the
+	// old id, e.g. from signing in again, are dropped with it
+	s.tokens().delete(sessionID(session))
+	sid, err := rotateSessionID(session)
+	if err == nil {
+		err = s.tokens().set(sid, idTokenKind, exchange.IdToken, time.Hour)
+	}
+	if err == nil {
+		err = s.tokens().set(sid, accessTokenKind, exchange.AccessToken, time.Hour)
+	}
+	if err == nil && exchange.RefreshToken != "" {
+		err = s.tokens().set(sid, refreshTokenKind, exchange.RefreshToken, refreshTokenLifetime)
+	}
+	if err == nil {
+		err = session.Save(r, w)
+	}
+	if err != nil {
+		log.Printf("session error: %+v\n", err)
+		s.renderAuthError(w, http.StatusInternalServerError, fmt.Sprintf("Signing in failed: %s", err.Error()))
+		return
+	}
+
+	http.Redirect(w, r, returnTo, http.StatusFound)
 }
 
 func (s *Server) ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	type customData struct {
 		Profile         map[string]string
 		IsAuthenticated bool
+		TokenExpiry     tokenExpiryData
 	}
 
 	data := customData{
 		Profile:         s.getProfileData(r),
 		IsAuthenticated: s.isAuthenticated(r),
+		TokenExpiry:     s.tokenExpiryData(r),
 	}
-	s.tpl.ExecuteTemplate(w, "profile.gohtml", data)
+
+	// API clients, e.g. ones sending a bearer token, get the claims as JSON
+	if wantsJSON(r) {
+		if !data.IsAuthenticated {
+			if r.Header.Get("Authorization") != "" {
+				writeJSONError(w, http.StatusUnauthorized, "invalid_token", "The access token is invalid or expired.")
+			} else {
+				writeJSONError(w, http.StatusUnauthorized, "login_required", "Sign in or send an access token to read the profile.")
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data.Profile)
+		return
+	}
+
+	// browsers sign in first and come back here
+	if !data.IsAuthenticated {
+		http.Redirect(w, r, s.loginURL(r.URL.RequestURI()), http.StatusFound)
+		return
+	}
+
+	s.render(w, http.StatusOK, "profile.gohtml", data)
 }
 
 func (s *Server) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	// revoke the oauth2 access token it exists in the session API side before flushing cache
-	if session, err := s.sessionStore.Get(r, SESSION_STORE_NAME); err != nil {
-		if accessToken, found := s.cache.Get(fmt.Sprintf("%s-access_token", session.ID)); found {
-			revokeTokenUrl := s.oAuthEndPoint("revoke")
-			form := url.Values{}
-			form.Set("token", accessToken.(string))
-			form.Set("token_type_hint", "access_token")
-			form.Add("client_id", s.idxClient.Config().Okta.IDX.ClientID)
-			form.Add("client_secret", s.idxClient.Config().Okta.IDX.ClientSecret)
-			req, _ := http.NewRequest("POST", revokeTokenUrl, strings.NewReader(form.Encode()))
-			h := req.Header
-			h.Add("Accept", "application/json")
-			h.Add("Content-Type", "application/x-www-form-urlencoded")
-
-			client := &http.Client{Timeout: time.Second * 30}
-			resp, err := client.Do(req)
-			if err != nil {
+	var sid string
+	if session, err := s.sessionStore.Get(r, SESSION_STORE_NAME); err == nil {
+		sid = sessionID(session)
+	}
+	idToken, _ := s.tokens().get(sid, idTokenKind)
+
+	// revoke the oauth2 access token it exists in the session API side before dropping the session's tokens
+	if accessToken, found := s.tokens().get(sid, accessTokenKind); found {
+		revokeTokenUrl := s.oAuthEndPoint(r.Context(), "revoke")
+		form := url.Values{}
+		form.Set("token", accessToken)
+		form.Set("token_type_hint", "access_token")
+		form.Add("client_id", s.idxConfigFor(r.Context()).Okta.IDX.ClientID)
+		form.Add("client_secret", s.idxConfigFor(r.Context()).Okta.IDX.ClientSecret)
+		req, _ := http.NewRequest("POST", revokeTokenUrl, strings.NewReader(form.Encode()))
+		h := req.Header
+		h.Add("Accept", "application/json")
+		h.Add("Content-Type", "application/x-www-form-urlencoded")
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			fmt.Printf("revoke error: %s\n", err.Error())
+		} else {
+			if resp.StatusCode != http.StatusOK {
 				body, _ := ioutil.ReadAll(resp.Body)
 				fmt.Printf("revoke error; status: %s, body: %s\n", resp.Status, string(body))
 			}
-			defer resp.Body.Close()
+			resp.Body.Close()
 		}
 	}
 
-	s.cache.Flush()
-	http.Redirect(w, r, "/", http.StatusFound)
+	s.tokens().delete(sid)
+
+	// end the Okta session too when a sign-out redirect is configured
+	if s.config.PostLogoutRedirectURI != "" && idToken != "" {
+		logoutURL, err := s.logoutURL(r.Context(), idToken)
+		if err != nil {
+			log.Printf("logout error: %+v\n", err)
+			http.Error(w, fmt.Sprintf("logout is misconfigured: %v", err), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, logoutURL, http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, s.Path("/"), http.StatusFound)
+}
+
+// logoutURL builds the end-session request that signs the user out of the
+// Okta org and sends them back to the configured post logout redirect URI.
+func (s *Server) logoutURL(ctx context.Context, idToken string) (string, error) {
+	redirectURI := s.config.PostLogoutRedirectURI
+	if !s.config.IsAllowedPostLogoutRedirectURI(redirectURI) {
+		return "", fmt.Errorf("post logout redirect uri %q is not an allowed sign-out redirect URI", redirectURI)
+	}
+
+	q := url.Values{}
+	q.Set("id_token_hint", idToken)
+	q.Set("post_logout_redirect_uri", redirectURI)
+	return s.oAuthEndPoint(ctx, "logout") + "?" + q.Encode(), nil
 }
 
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
@@ -382,17 +817,54 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func (s *Server) verifyToken(t string) (*verifier.Jwt, error) {
+// debugTokenResponse logs the raw /token response when DEBUG is on so fields
+// Exchange doesn't model, e.g. device_secret, are visible. Token and secret
+// values are redacted unless DANGEROUS_LOG_TOKENS is also set.
+func debugTokenResponse(body []byte) {
+	if os.Getenv("DEBUG") != "true" {
+		return
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		log.Printf("token response is not JSON: %v\n", err)
+		return
+	}
+	if os.Getenv("DANGEROUS_LOG_TOKENS") != "true" {
+		for k := range fields {
+			if strings.HasSuffix(k, "_token") || strings.HasSuffix(k, "_secret") {
+				fields[k] = "[REDACTED]"
+			}
+		}
+	}
+
+	pretty, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		log.Printf("could not format token response: %v\n", err)
+		return
+	}
+	log.Printf("token response:\n%s\n", pretty)
+}
+
+// unverifiedSignInMessage is shown when the ID token of a sign in doesn't
+// verify.
+const unverifiedSignInMessage = "Signing in failed: the sign in could not be verified."
+
+func (s *Server) verifyToken(ctx context.Context, t string) (_ *verifier.Jwt, err error) {
+	_, span := startSpan(ctx, "verifyToken")
+	defer func() { endSpan(span, err) }()
+
+	idxConfig := s.idxConfigFor(ctx)
 	tv := map[string]string{}
-	tv["aud"] = s.idxClient.Config().Okta.IDX.ClientID
+	tv["aud"] = idxConfig.Okta.IDX.ClientID
 	jv := verifier.JwtVerifier{
-		Issuer:           s.idxClient.Config().Okta.IDX.Issuer,
+		Issuer:           idxConfig.Okta.IDX.Issuer,
 		ClaimsToValidate: tv,
 	}
 
 	result, err := jv.New().VerifyIdToken(t)
 	if err != nil {
-		return nil, fmt.Errorf("%s; token: %s", err, t)
+		return nil, fmt.Errorf("id token could not be verified: %w", err)
 	}
 
 	if result != nil {
@@ -402,45 +874,142 @@ func (s *Server) verifyToken(t string) (*verifier.Jwt, error) {
 	return nil, fmt.Errorf("token could not be verified: %s", "")
 }
 
+// verifyAccessToken checks a bearer token sent by an API client was issued
+// to this app by the authorization server of the request's issuer.
+func (s *Server) verifyAccessToken(ctx context.Context, t string) error {
+	audience := s.config.AccessTokenAudience
+	if audience == "" {
+		audience = config.DefaultAccessTokenAudience
+	}
+	idxConfig := s.idxConfigFor(ctx)
+	tv := map[string]string{}
+	tv["aud"] = audience
+	tv["cid"] = idxConfig.Okta.IDX.ClientID
+	jv := verifier.JwtVerifier{
+		Issuer:           idxConfig.Okta.IDX.Issuer,
+		ClaimsToValidate: tv,
+	}
+
+	_, err := jv.New().VerifyAccessToken(t)
+	return err
+}
+
+// bearerContextKey holds the bearerResult of a request's Authorization
+// header, verified once by bearerMiddleware.
+type bearerContextKey struct{}
+
+type bearerResult struct {
+	token string
+	ok    bool
+}
+
+// bearerMiddleware verifies a request's bearer token once, so the handler
+// can ask both whether the request is authenticated and for its profile
+// without verifying the token again.
+func (s *Server) bearerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := s.verifyBearerToken(r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), bearerContextKey{}, bearerResult{token, ok})))
+	})
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header if the
+// request has one and it verifies.
+func (s *Server) bearerToken(r *http.Request) (string, bool) {
+	if result, found := r.Context().Value(bearerContextKey{}).(bearerResult); found {
+		return result.token, result.ok
+	}
+	return s.verifyBearerToken(r)
+}
+
+func (s *Server) verifyBearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len("Bearer ") || !strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[len("Bearer "):])
+	if err := s.accessTokenVerifier(r.Context(), token); err != nil {
+		if os.Getenv("DEBUG") == "true" {
+			log.Printf("bearer token rejected: %v\n", err)
+		}
+		return "", false
+	}
+	return token, true
+}
+
 func (s *Server) getProfileData(r *http.Request) map[string]string {
-	m := make(map[string]string)
+	if token, ok := s.bearerToken(r); ok {
+		return s.userInfo(r.Context(), token)
+	}
 
 	session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
-
-	accessToken := session.Values["access_token"]
-	if accessToken == nil || accessToken == "" {
-		accessToken, _ = s.cache.Get(fmt.Sprintf("%s-access_token", session.ID))
+	if err != nil {
+		return make(map[string]string)
 	}
-	if err != nil || accessToken == nil || accessToken == "" {
-		return m
+
+	accessToken, _ := session.Values["access_token"].(string)
+	if accessToken == "" {
+		if token, found := s.tokens().get(sessionID(session), accessTokenKind); found {
+			return s.sessionUserInfo(r.Context(), sessionID(session), token)
+		}
+		return make(map[string]string)
 	}
 
-	reqUrl := s.oAuthEndPoint("userinfo")
-	req, _ := http.NewRequest("GET", reqUrl, bytes.NewReader([]byte("")))
+	return s.userInfo(r.Context(), accessToken)
+}
+
+func (s *Server) userInfo(ctx context.Context, accessToken string) map[string]string {
+	m, _ := s.fetchUserInfo(ctx, accessToken)
+	return m
+}
+
+// fetchUserInfo returns the claims from userinfo along with its status, or 0
+// if the call failed.
+func (s *Server) fetchUserInfo(ctx context.Context, accessToken string) (_ map[string]string, status int) {
+	var err error
+	ctx, span := startSpan(ctx, "userinfo")
+	defer func() {
+		if err == nil && status != http.StatusOK {
+			err = fmt.Errorf("userinfo returned %d", status)
+		}
+		endSpan(span, err)
+	}()
+
+	m := make(map[string]string)
+
+	reqUrl := s.oAuthEndPoint(ctx, "userinfo")
+	req, _ := http.NewRequestWithContext(ctx, "GET", reqUrl, bytes.NewReader([]byte("")))
 	h := req.Header
 	h.Add("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 	h.Add("Accept", "application/json")
 
-	client := &http.Client{Timeout: time.Second * 30}
-	resp, _ := client.Do(req)
-	body, _ := ioutil.ReadAll(resp.Body)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return m, 0
+	}
 	defer resp.Body.Close()
-	json.Unmarshal(body, &m)
+	if resp.StatusCode == http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		json.Unmarshal(body, &m)
+	}
 
-	return m
+	return m, resp.StatusCode
 }
 
 func (s *Server) isAuthenticated(r *http.Request) bool {
-	session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
-	idToken := session.Values["id_token"]
-	if idToken == nil || idToken == "" {
-		idToken, _ = s.cache.Get(fmt.Sprintf("%s-id_token", session.ID))
+	if _, ok := s.bearerToken(r); ok {
+		return true
 	}
-	if err != nil || idToken == nil || idToken == "" {
+
+	session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
+	if err != nil {
 		return false
 	}
-
-	return true
+	if idToken := session.Values["id_token"]; idToken != nil && idToken != "" {
+		return true
+	}
+	_, found := s.tokens().get(sessionID(session), idTokenKind)
+	return found
 }
 
 // Creates a codeVerifier that is used for PKCE
@@ -480,6 +1049,16 @@ func createPKCEData() (*PKCE, error) {
 	}, nil
 }
 
+// pkceFromVerifier rebuilds the PKCE data for a known code verifier.
+func pkceFromVerifier(codeVerifier string) *PKCE {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return &PKCE{
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeVerifier:        codeVerifier,
+		CodeChallengeMethod: "S256",
+	}
+}
+
 // Generate a Nonce to be used during the initialization of the SIW
 func generateNonce() (string, error) {
 	nonceBytes := make([]byte, 32)
@@ -492,29 +1071,50 @@ func generateNonce() (string, error) {
 }
 
 // Get the interaction handle to begin the flow. Use this
-// value when initializing the Okta sign in widget.
-func (s *Server) getInteractionHandle(codeChallenge string) (string, error) {
+// value when initializing the Okta sign in widget. A non-empty
+// prompt is forwarded to the interact endpoint, e.g. "none" for
+// a silent authentication check. The configured InteractParams
+// and then extra are added to the request, but neither can
+// replace the values set here.
+func (s *Server) getInteractionHandle(ctx context.Context, codeChallenge, prompt string, extra url.Values) (_ string, err error) {
+	ctx, span := startSpan(ctx, "getInteractionHandle")
+	defer func() { endSpan(span, err) }()
 
 	data := url.Values{}
-	data.Set("scope", strings.Join(s.idxClient.Config().Okta.IDX.Scopes, " "))
+	for _, params := range []url.Values{s.config.InteractParams, extra} {
+		for k, v := range params {
+			data[k] = v
+		}
+	}
+	data.Set("scope", strings.Join(s.idxConfigFor(ctx).Okta.IDX.Scopes, " "))
 	data.Set("code_challenge", codeChallenge)
 	data.Set("code_challenge_method", "S256")
-	data.Set("redirect_uri", s.idxClient.Config().Okta.IDX.RedirectURI)
+	data.Set("redirect_uri", s.idxConfigFor(ctx).Okta.IDX.RedirectURI)
 	data.Set("state", s.state)
+	if s.config.ResponseType != "" {
+		data.Set("response_type", s.config.ResponseType)
+	}
+	if s.config.ResponseMode != "" {
+		data.Set("response_mode", s.config.ResponseMode)
+	}
+	if prompt != "" {
+		data.Set("prompt", prompt)
+	}
 
-	endpoint := s.oAuthEndPoint("interact")
-	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(data.Encode()))
+	endpoint := s.oAuthEndPoint(ctx, "interact")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("failed to create interact http request: %w", err)
 	}
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	client := &http.Client{Timeout: time.Second * 30}
-	resp, err := client.Do(req)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("http call has failed: %w", err)
 	}
 	type interactionHandleResponse struct {
 		InteractionHandle string `json:"interaction_handle"`
+		Error             string `json:"error,omitempty"`
+		ErrorDescription  string `json:"error_description,omitempty"`
 	}
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -526,13 +1126,19 @@ func (s *Server) getInteractionHandle(codeChallenge string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if interactionHandle.Error != "" {
+		return "", &OAuthError{
+			Code:        interactionHandle.Error,
+			Description: interactionHandle.ErrorDescription,
+		}
+	}
 
 	return interactionHandle.InteractionHandle, nil
 }
 
-func (s *Server) oAuthEndPoint(operation string) string {
+func (s *Server) oAuthEndPoint(ctx context.Context, operation string) string {
 	var endPoint string
-	issuer := s.idxClient.Config().Okta.IDX.Issuer
+	issuer := s.idxConfigFor(ctx).Okta.IDX.Issuer
 	if strings.Contains(issuer, "oauth2") {
 		endPoint = fmt.Sprintf("%s/v1/%s", issuer, operation)
 	} else {
@@ -0,0 +1,79 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoginSilentHandler(t *testing.T) {
+	interactions := 0
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/default/v1/interact" || r.FormValue("prompt") != "none" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		interactions++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"login_required","error_description":"The client specified not to prompt, but the user is not logged in."}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	router := s.router()
+
+	// a session this app signed in answers without asking Okta
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	session, err := s.sessionStore.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["id_token"] = "valid-id-token"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/login/silent", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"authenticated":true}`+"\n" {
+		t.Errorf("expected a signed in result, got %d %s", rec.Code, rec.Body.String())
+	}
+	if interactions != 0 {
+		t.Errorf("expected no interaction for a signed in session, got %d", interactions)
+	}
+
+	// without a session Okta is asked not to prompt, and says to log in
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login/silent", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"authenticated":false,"error":"login_required"}`+"\n" {
+		t.Errorf("expected a login_required result, got %d %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON content type, got %q", ct)
+	}
+	if interactions != 1 {
+		t.Errorf("expected one interaction without a session, got %d", interactions)
+	}
+}
@@ -0,0 +1,209 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/config"
+)
+
+func TestSessionCookieHonorsDomainAndPath(t *testing.T) {
+	store := newSessionStore(&config.Config{CookieDomain: "example.com", CookiePath: "/auth"})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/login", nil)
+	rec := httptest.NewRecorder()
+	session, err := store.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["pkce_code_verifier"] = "verifier"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	setCookie := rec.Header().Get("Set-Cookie")
+	if !strings.Contains(setCookie, "Domain=example.com") {
+		t.Errorf("expected the cookie domain in %q", setCookie)
+	}
+	if !strings.Contains(setCookie, "Path=/auth") {
+		t.Errorf("expected the cookie path in %q", setCookie)
+	}
+	if !strings.Contains(setCookie, "SameSite=Lax") {
+		t.Errorf("expected SameSite=Lax in %q", setCookie)
+	}
+}
+
+func TestFormPostSessionCookieIsSameSiteNone(t *testing.T) {
+	store := newSessionStore(&config.Config{ResponseMode: "form_post"})
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	session, err := store.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["pkce_code_verifier"] = "verifier"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	setCookie := rec.Header().Get("Set-Cookie")
+	if !strings.Contains(setCookie, "SameSite=None") || !strings.Contains(setCookie, "Secure") {
+		t.Errorf("expected SameSite=None; Secure in %q", setCookie)
+	}
+}
+
+func TestSessionSurvivesKeyRotation(t *testing.T) {
+	oldKey := config.SessionKey{HashKey: []byte("old-hash-key"), BlockKey: []byte("0123456789abcdef")}
+	newKey := config.SessionKey{HashKey: []byte("new-hash-key"), BlockKey: []byte("fedcba9876543210")}
+
+	// a session made before the rotation
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	session, err := newSessionStore(&config.Config{SessionKeys: []config.SessionKey{oldKey}}).Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values[sessionIDKey] = "before-rotation"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	oldCookie := rec.Result().Cookies()[0]
+
+	rotated := newSessionStore(&config.Config{SessionKeys: []config.SessionKey{newKey, oldKey}})
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(oldCookie)
+	session, err = rotated.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatalf("expected the old session to be read after the rotation: %v", err)
+	}
+	if session.Values[sessionIDKey] != "before-rotation" {
+		t.Fatalf("expected the old session's values, got %v", session.Values)
+	}
+
+	// saved again it's under the new key, which the old key can't read
+	rec = httptest.NewRecorder()
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	if _, err := newSessionStore(&config.Config{SessionKeys: []config.SessionKey{oldKey}}).Get(req, SESSION_STORE_NAME); err == nil {
+		t.Error("expected the resaved session to use the new key")
+	}
+}
+
+func TestFilesystemSessionKeepsLargeValues(t *testing.T) {
+	large := strings.Repeat("x", 16*1024)
+
+	// the cookie store can't hold the value at all
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	session, err := newSessionStore(&config.Config{}).Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["id_token"] = large
+	if err := session.Save(req, httptest.NewRecorder()); err == nil {
+		t.Error("expected the cookie store to reject a 16KB session")
+	}
+
+	store := newSessionStore(&config.Config{SessionDir: t.TempDir(), CookiePath: "/auth"})
+	req = httptest.NewRequest(http.MethodGet, "/auth/", nil)
+	rec := httptest.NewRecorder()
+	session, err = store.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["id_token"] = large
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || len(cookies[0].Value) > 4096 || cookies[0].Path != "/auth" {
+		t.Fatalf("expected one small session id cookie scoped to /auth, got %+v", cookies)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/auth/", nil)
+	req.AddCookie(cookies[0])
+	session, err = store.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := session.Values["id_token"].(string); got != large {
+		t.Errorf("expected the large value back intact, got %d bytes", len(got))
+	}
+}
+
+func TestLoginCallbackRotatesSessionID(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token":"new-access-token","id_token":"valid-id-token"}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.state = "state"
+
+	// a session with an id planted before sign in
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	session, err := s.sessionStore.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values[sessionIDKey] = "planted"
+	session.Values["pkce_code_verifier"] = "verifier"
+	session.Values["pkce_code_challenge"] = "challenge"
+	session.Values["pkce_code_challenge_method"] = "S256"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	s.tokens().set("planted", accessTokenKind, "old-access-token", time.Minute)
+
+	req = httptest.NewRequest(http.MethodGet, "/login/callback?state=state&interaction_code=code", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	if s.SessionID(req) != "planted" {
+		t.Fatal("expected the planted session id before the callback")
+	}
+	rec = httptest.NewRecorder()
+	s.LoginCallbackHandler(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect after sign in, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	id := s.SessionID(req)
+	if id == "" || id == "planted" {
+		t.Fatalf("expected a new session id after sign in, got %q", id)
+	}
+	if token, _ := s.SessionAccessToken(req); token != "new-access-token" {
+		t.Errorf("expected the new access token under the new id, got %q", token)
+	}
+	if _, ok := s.tokens().get("planted", accessTokenKind); ok {
+		t.Error("expected no tokens left under the planted id")
+	}
+}
@@ -0,0 +1,106 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The interaction code is single use, so the token exchange is only retried
+// a few times and only for failures where the server can't have consumed it.
+var (
+	tokenExchangeAttempts = 3
+	tokenExchangeBackoff  = 500 * time.Millisecond
+)
+
+// tokenExchangeError is a failure of the /token call that is worth retrying:
+// a transport error such as a timeout, or a 5xx from the server.
+type tokenExchangeError struct {
+	err error
+}
+
+func (e *tokenExchangeError) Error() string {
+	return e.err.Error()
+}
+
+// exchangeToken POSTs form to the token endpoint, retrying with backoff on
+// timeouts and 5xx responses. Definitive 4xx responses, e.g. invalid_grant,
+// are returned straight away as an *OAuthError. The raw body of the final
+// response is returned alongside the decoded exchange.
+func exchangeToken(ctx context.Context, client *http.Client, tokenURL string, form url.Values) (_ *Exchange, _ []byte, err error) {
+	ctx, span := startSpan(ctx, "token exchange")
+	defer func() { endSpan(span, err) }()
+
+	backoff := tokenExchangeBackoff
+	for attempt := 1; attempt <= tokenExchangeAttempts; attempt++ {
+		var exchange *Exchange
+		var body []byte
+		exchange, body, err = doExchangeToken(ctx, client, tokenURL, form)
+		if _, retryable := err.(*tokenExchangeError); !retryable {
+			return exchange, body, err
+		}
+		if attempt < tokenExchangeAttempts {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, nil, fmt.Errorf("token exchange abandoned after %d attempts: %w", attempt, ctx.Err())
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+	}
+	return nil, nil, fmt.Errorf("token exchange failed after %d attempts: %w", tokenExchangeAttempts, err)
+}
+
+func doExchangeToken(ctx context.Context, client *http.Client, tokenURL string, form url.Values) (*Exchange, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, &tokenExchangeError{err}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, &tokenExchangeError{err}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, body, &tokenExchangeError{fmt.Errorf("token endpoint returned %s", resp.Status)}
+	}
+
+	var exchange Exchange
+	if err := json.Unmarshal(body, &exchange); err != nil {
+		return nil, body, err
+	}
+	if exchange.Error != "" {
+		return nil, body, &OAuthError{Code: exchange.Error, Description: exchange.ErrorDescription}
+	}
+	return &exchange, body, nil
+}
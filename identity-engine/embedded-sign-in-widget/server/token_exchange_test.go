@@ -0,0 +1,143 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/config"
+)
+
+func TestExchangeTokenRetriesAfterTimeout(t *testing.T) {
+	defer func(backoff time.Duration) { tokenExchangeBackoff = backoff }(tokenExchangeBackoff)
+	tokenExchangeBackoff = time.Millisecond
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, `{"access_token":"at","id_token":"it"}`)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	exchange, _, err := exchangeToken(context.Background(), client, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exchange.AccessToken != "at" || exchange.IdToken != "it" {
+		t.Errorf("unexpected exchange %+v", exchange)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestExchangeTokenStopsOnInvalidGrant(t *testing.T) {
+	defer func(backoff time.Duration) { tokenExchangeBackoff = backoff }(tokenExchangeBackoff)
+	tokenExchangeBackoff = time.Millisecond
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid_grant","error_description":"The interaction code is invalid."}`)
+	}))
+	defer ts.Close()
+
+	_, _, err := exchangeToken(context.Background(), ts.Client(), ts.URL, nil)
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_grant" {
+		t.Fatalf("expected invalid_grant, got %v", err)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("expected 1 attempt, got %d", n)
+	}
+}
+
+func TestExchangeTokenGivesUpOnRepeated5xx(t *testing.T) {
+	defer func(backoff time.Duration) { tokenExchangeBackoff = backoff }(tokenExchangeBackoff)
+	tokenExchangeBackoff = time.Millisecond
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	if _, _, err := exchangeToken(context.Background(), ts.Client(), ts.URL, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := atomic.LoadInt32(&attempts); int(n) != tokenExchangeAttempts {
+		t.Errorf("expected %d attempts, got %d", tokenExchangeAttempts, n)
+	}
+}
+
+func TestExchangeTokenStopsWaitingWhenCanceled(t *testing.T) {
+	defer func(backoff time.Duration) { tokenExchangeBackoff = backoff }(tokenExchangeBackoff)
+	tokenExchangeBackoff = time.Hour
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := exchangeToken(ctx, ts.Client(), ts.URL, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context's error, got %v", err)
+	}
+	if waited := time.Since(start); waited > 5*time.Second {
+		t.Errorf("expected the backoff to end with the context, waited %s", waited)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("expected 1 attempt before giving up, got %d", n)
+	}
+}
+
+func TestExchangeTokenIsOnlyRetriedByItself(t *testing.T) {
+	defer func(backoff time.Duration) { tokenExchangeBackoff = backoff }(tokenExchangeBackoff)
+	tokenExchangeBackoff = time.Millisecond
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := newIDXHTTPClient(&config.Config{IDXRetries: 2})
+	if _, _, err := exchangeToken(context.Background(), client, ts.URL, nil); err == nil {
+		t.Fatal("expected the exchange to fail")
+	}
+	if n := atomic.LoadInt32(&attempts); n != int32(tokenExchangeAttempts) {
+		t.Errorf("expected %d attempts, got %d", tokenExchangeAttempts, n)
+	}
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// tokenExpiry reads the exp claim of a JWT. The token was verified when it
+// was issued, so the signature isn't checked again here.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// sessionTokenExpiry returns when the browser session's access token, or
+// its id token if the access token has no exp, expires.
+func (s *Server) sessionTokenExpiry(r *http.Request) (time.Time, bool) {
+	session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, kind := range []tokenKind{accessTokenKind, idTokenKind} {
+		if token, found := s.tokens().get(sessionID(session), kind); found {
+			if exp, ok := tokenExpiry(token); ok {
+				return exp, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatExpiresIn renders the time left on a token, e.g. "59m 7s".
+func formatExpiresIn(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%dm %ds", int(d/time.Minute), int(d%time.Minute/time.Second))
+}
+
+// tokenExpiryData is what the pages need to show the countdown to the
+// session's token expiring. The zero value hides it.
+type tokenExpiryData struct {
+	ExpiresAt int64
+	ExpiresIn string
+}
+
+func (s *Server) tokenExpiryData(r *http.Request) tokenExpiryData {
+	exp, ok := s.sessionTokenExpiry(r)
+	if !ok {
+		return tokenExpiryData{}
+	}
+	left := time.Until(exp)
+	if left <= 0 {
+		return tokenExpiryData{}
+	}
+	return tokenExpiryData{ExpiresAt: exp.Unix(), ExpiresIn: formatExpiresIn(left)}
+}
@@ -0,0 +1,99 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testJWT(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"mary","exp":%d}`, exp.Unix())))
+	return "header." + payload + ".signature"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	want := time.Now().Add(time.Hour).Truncate(time.Second)
+	exp, ok := tokenExpiry(testJWT(want))
+	if !ok || !exp.Equal(want) {
+		t.Errorf("expected %v, got %v %v", want, exp, ok)
+	}
+	for _, token := range []string{"", "opaque-token", "a.!!!.c", "a." + base64.RawURLEncoding.EncodeToString([]byte(`{}`)) + ".c"} {
+		if _, ok := tokenExpiry(token); ok {
+			t.Errorf("expected no expiry for %q", token)
+		}
+	}
+}
+
+func TestFormatExpiresIn(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		59*time.Minute + 7*time.Second + 300*time.Millisecond: "59m 7s",
+		45 * time.Second: "0m 45s",
+		2 * time.Hour:    "120m 0s",
+	} {
+		if got := formatExpiresIn(d); got != want {
+			t.Errorf("expected %q for %v, got %q", want, d, got)
+		}
+	}
+}
+
+func TestHomeShowsTokenExpiry(t *testing.T) {
+	okta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"Mary"}`)
+	}))
+	defer okta.Close()
+	s := newBearerTestServer(t, okta.URL+"/oauth2/default")
+	s.tpl = template.Must(template.New("").Funcs(s.templateFuncs()).ParseGlob("../templates/*.gohtml"))
+
+	render := func(req *http.Request) string {
+		rec := httptest.NewRecorder()
+		s.HomeHandler(rec, req)
+		return rec.Body.String()
+	}
+
+	if body := render(httptest.NewRequest(http.MethodGet, "/", nil)); strings.Contains(body, `id="token-expiry"`) {
+		t.Error("expected no token expiry without a token")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	session, err := s.sessionStore.Get(req, SESSION_STORE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values[sessionIDKey] = "sid"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	s.tokens().set("sid", idTokenKind, "valid-id-token", time.Minute)
+	s.tokens().set("sid", accessTokenKind, testJWT(time.Now().Add(10*time.Minute+30*time.Second)), time.Minute)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	body := render(req)
+	if !strings.Contains(body, `id="token-expiry"`) || !strings.Contains(body, "10m 2") {
+		t.Errorf("expected the token to expire in about 10m 30s, got %s", body)
+	}
+}
@@ -0,0 +1,149 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/patrickmn/go-cache"
+)
+
+// tokenKind is a kind of token kept server side for a browser session.
+type tokenKind string
+
+const (
+	idTokenKind      tokenKind = "id_token"
+	accessTokenKind  tokenKind = "access_token"
+	refreshTokenKind tokenKind = "refresh_token"
+
+	sessionIDKey = "session_id"
+)
+
+var errNoSessionID = errors.New("session has no id")
+
+// tokenKey is the cache key of a session's token. Sessions without an id,
+// e.g. from a failed session lookup, have no key so they can't share one.
+func tokenKey(sessionID string, kind tokenKind) (string, error) {
+	if sessionID == "" {
+		return "", errNoSessionID
+	}
+	return fmt.Sprintf("%s-%s", sessionID, kind), nil
+}
+
+// sessionID returns the id kept in the session cookie. Cookie sessions have
+// no gorilla session.ID of their own.
+func sessionID(session *sessions.Session) string {
+	if session == nil {
+		return ""
+	}
+	id, _ := session.Values[sessionIDKey].(string)
+	return id
+}
+
+// ensureSessionID gives the session an id, if it has none, that the caller
+// must save with the session.
+func ensureSessionID(session *sessions.Session) (string, error) {
+	if id := sessionID(session); id != "" {
+		return id, nil
+	}
+	if session == nil {
+		return "", errNoSessionID
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	session.Values[sessionIDKey] = id
+	return id, nil
+}
+
+// rotateSessionID gives a session that has just signed in a new id. An id
+// planted in the browser before sign in, i.e. session fixation, then doesn't
+// lead to the user's tokens.
+func rotateSessionID(session *sessions.Session) (string, error) {
+	if session == nil {
+		return "", errNoSessionID
+	}
+	delete(session.Values, sessionIDKey)
+	// the filesystem store saves the session under a new id as well
+	session.ID = ""
+	return ensureSessionID(session)
+}
+
+// SessionID returns the id of the browser session of r, e.g. so a test can
+// check it changes on sign in.
+func (s *Server) SessionID(r *http.Request) string {
+	session, err := s.sessionStore.Get(r, SESSION_STORE_NAME)
+	if err != nil {
+		return ""
+	}
+	return sessionID(session)
+}
+
+// HasSessionTokens reports whether the server still holds any token for the
+// session id, e.g. so a test can check logout drops them.
+func (s *Server) HasSessionTokens(sessionID string) bool {
+	for _, kind := range []tokenKind{idTokenKind, accessTokenKind, refreshTokenKind} {
+		if _, found := s.tokens().get(sessionID, kind); found {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenStore keeps the tokens of browser sessions in the server's cache.
+type tokenStore struct {
+	cache *cache.Cache
+}
+
+func (t tokenStore) set(sessionID string, kind tokenKind, token string, d time.Duration) error {
+	key, err := tokenKey(sessionID, kind)
+	if err != nil {
+		return err
+	}
+	t.cache.Set(key, token, d)
+	return nil
+}
+
+func (t tokenStore) get(sessionID string, kind tokenKind) (string, bool) {
+	key, err := tokenKey(sessionID, kind)
+	if err != nil {
+		return "", false
+	}
+	v, found := t.cache.Get(key)
+	if !found {
+		return "", false
+	}
+	token, ok := v.(string)
+	return token, ok && token != ""
+}
+
+// delete removes all of a session's tokens.
+func (t tokenStore) delete(sessionID string) {
+	for _, kind := range []tokenKind{idTokenKind, accessTokenKind, refreshTokenKind} {
+		if key, err := tokenKey(sessionID, kind); err == nil {
+			t.cache.Delete(key)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/patrickmn/go-cache"
+)
+
+func TestTokenKeyRequiresSessionID(t *testing.T) {
+	if _, err := tokenKey("", idTokenKind); err != errNoSessionID {
+		t.Errorf("expected errNoSessionID, got %v", err)
+	}
+	key, err := tokenKey("abc", accessTokenKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "abc-access_token" {
+		t.Errorf("unexpected key %q", key)
+	}
+}
+
+func TestTokenStoreIgnoresEmptySessionID(t *testing.T) {
+	tokens := tokenStore{cache: cache.New(cache.NoExpiration, cache.NoExpiration)}
+	// a key an unguarded "%s-id_token" lookup would have hit
+	tokens.cache.SetDefault("-id_token", "someone-elses-token")
+
+	if err := tokens.set("", idTokenKind, "token", time.Minute); err == nil {
+		t.Error("expected an error storing a token without a session id")
+	}
+	if token, found := tokens.get("", idTokenKind); found {
+		t.Errorf("expected no token without a session id, got %q", token)
+	}
+	tokens.delete("")
+	if _, found := tokens.cache.Get("-id_token"); !found {
+		t.Error("expected delete without a session id to leave other keys alone")
+	}
+}
+
+func TestTokenStoreKeepsSessionsApart(t *testing.T) {
+	tokens := tokenStore{cache: cache.New(cache.NoExpiration, cache.NoExpiration)}
+	if err := tokens.set("one", accessTokenKind, "token-one", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.set("two", accessTokenKind, "token-two", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens.delete("one")
+	if _, found := tokens.get("one", accessTokenKind); found {
+		t.Error("expected session one's token to be deleted")
+	}
+	if token, _ := tokens.get("two", accessTokenKind); token != "token-two" {
+		t.Errorf("expected session two's token to remain, got %q", token)
+	}
+}
+
+func TestEnsureSessionID(t *testing.T) {
+	if _, err := ensureSessionID(nil); err != errNoSessionID {
+		t.Errorf("expected errNoSessionID for a nil session, got %v", err)
+	}
+	if id := sessionID(nil); id != "" {
+		t.Errorf("expected no id for a nil session, got %q", id)
+	}
+
+	session := sessions.NewSession(sessions.NewCookieStore([]byte("key")), SESSION_STORE_NAME)
+	id, err := ensureSessionID(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "" || sessionID(session) != id {
+		t.Errorf("expected the session to keep id %q, got %q", id, sessionID(session))
+	}
+	if again, _ := ensureSessionID(session); again != id {
+		t.Errorf("expected the id to be stable, got %q then %q", id, again)
+	}
+}
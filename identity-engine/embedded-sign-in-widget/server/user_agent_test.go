@@ -0,0 +1,93 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/okta/samples-golang/identity-engine/embedded-sign-in-widget/config"
+)
+
+func TestUserAgentTransportSetsHeader(t *testing.T) {
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &UserAgentTransport{}}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if !strings.HasPrefix(userAgent, config.UserAgent()) {
+		t.Fatalf("expected User-Agent to start with %q, got %q", config.UserAgent(), userAgent)
+	}
+}
+
+func TestUserAgentTransportKeepsExistingHeader(t *testing.T) {
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "okta-sdk-golang/2.3.0")
+
+	client := &http.Client{Transport: &UserAgentTransport{}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	expected := config.UserAgent() + " okta-sdk-golang/2.3.0"
+	if userAgent != expected {
+		t.Fatalf("expected User-Agent %q, got %q", expected, userAgent)
+	}
+}
+
+func TestIssuerClientsSendUserAgent(t *testing.T) {
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	s := newBearerTestServer(t, "https://acme.okta.com/oauth2/default")
+	ir := newIssuerResolver(s.idxClient, newIDXHTTPClient(&config.Config{}), []string{ts.URL + "/oauth2/default"})
+	client, err := ir.clientFor(ts.URL + "/oauth2/default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.InitLogin(context.Background())
+
+	if !strings.HasPrefix(userAgent, config.UserAgent()) {
+		t.Fatalf("expected User-Agent to start with %q, got %q", config.UserAgent(), userAgent)
+	}
+}
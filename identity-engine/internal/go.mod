@@ -0,0 +1,3 @@
+module github.com/okta/samples-golang/identity-engine/internal
+
+go 1.14
@@ -0,0 +1,107 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package idxhttp holds the HTTP plumbing for calls to Okta that both
+// identity engine samples share.
+package idxhttp
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout bounds calls to Okta when the sample doesn't configure a
+// timeout.
+const DefaultTimeout = 30 * time.Second
+
+// retryBackoff is the wait before the first retry of a call; it doubles for
+// each retry after that.
+var retryBackoff = 250 * time.Millisecond
+
+// RetryTransport retries idempotent calls to Okta that failed before Okta
+// could answer them: transport errors, e.g. a reset connection, and 502, 503
+// and 504 responses. POSTs, e.g. IDX remediations and the /token exchange,
+// are never retried here since Okta may already have acted on them; callers
+// that know a POST is safe to send again retry it themselves. It gives up
+// early once the request's context, e.g. the client's timeout, is done.
+type RetryTransport struct {
+	Base    http.RoundTripper
+	Retries int
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	retries := t.Retries
+	if !idempotent(req) {
+		retries = 0
+	}
+
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := base.RoundTrip(attemptReq)
+		if attempt >= retries || req.Context().Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		backoff *= 2
+	}
+}
+
+// idempotent reports whether req can be sent again without changing what
+// Okta does: an idempotent method whose body, if any, can be replayed.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
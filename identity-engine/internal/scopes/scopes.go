@@ -0,0 +1,59 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package scopes checks the OAuth scopes both identity engine samples are
+// configured with against the ones their pages rely on.
+package scopes
+
+import (
+	"fmt"
+	"log"
+)
+
+// required are the scopes the samples' pages rely on, with what degrades when
+// one isn't requested.
+var required = []struct {
+	scope    string
+	degrades string
+}{
+	{"openid", "no id token is issued, so users never show as signed in and logout can't end the Okta session"},
+	{"profile", "the Profile page won't show the user's name"},
+	{"email", "the Profile page won't show the user's email"},
+}
+
+// MissingWarnings explains what degrades for each required scope missing from
+// scopes.
+func MissingWarnings(scopes []string) []string {
+	configured := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		configured[scope] = true
+	}
+	var warnings []string
+	for _, r := range required {
+		if !configured[r.scope] {
+			warnings = append(warnings, fmt.Sprintf("scope %q is not in okta.idx.scopes: %s", r.scope, r.degrades))
+		}
+	}
+	return warnings
+}
+
+// WarnMissing logs MissingWarnings at startup. The samples still run, so a
+// narrower set of scopes can be tried on purpose.
+func WarnMissing(scopes []string) {
+	for _, warning := range MissingWarnings(scopes) {
+		log.Printf("warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,55 @@
+/**
+ * Copyright 2021 - Present Okta, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scopes
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestWarnMissingForMinimalScopes(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	WarnMissing([]string{"openid"})
+
+	logged := buf.String()
+	for _, scope := range []string{`"profile"`, `"email"`} {
+		if !strings.Contains(logged, "warning: scope "+scope) {
+			t.Errorf("expected a warning for the %s scope, got %q", scope, logged)
+		}
+	}
+	if strings.Contains(logged, `"openid"`) {
+		t.Errorf("expected no warning for the configured openid scope, got %q", logged)
+	}
+}
+
+func TestMissingWarningsForFullScopes(t *testing.T) {
+	if warnings := MissingWarnings([]string{"openid", "profile", "email", "offline_access"}); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
+
+func TestMissingOpenIDWarnsAboutLogout(t *testing.T) {
+	warnings := MissingWarnings([]string{"profile", "email"})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "logout") {
+		t.Errorf("expected the openid warning to mention logout, got %v", warnings)
+	}
+}